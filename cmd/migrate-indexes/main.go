@@ -0,0 +1,48 @@
+// Command migrate-indexes builds the secondary indexes for data stored before
+// they existed: users by email and recordings by room. It is safe to run
+// again, and while the server is up.
+//
+//	go run ./cmd/migrate-indexes
+package main
+
+import (
+	"bincang-visual/internal/config"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+
+	redisRepo "bincang-visual/internal/repository/redis"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+
+	ctx := context.Background()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	users, err := redisRepo.NewUserRepository(redisClient).RebuildEmailIndex(ctx)
+	if err != nil {
+		log.Fatalf("Failed to index users by email after %d users: %v", users, err)
+	}
+	log.Printf("Indexed %d users by email", users)
+
+	recordings, err := redisRepo.NewRecordingRepository(redisClient).RebuildRoomIndex(ctx)
+	if err != nil {
+		log.Fatalf("Failed to index recordings by room after %d recordings: %v", recordings, err)
+	}
+	log.Printf("Indexed %d recordings by room", recordings)
+}
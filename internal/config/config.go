@@ -5,17 +5,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Google   GoogleConfig
-	TurnStun TurnStunConfig
-	Storage  StorageConfig
+	Server    ServerConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Google    GoogleConfig
+	TurnStun  TurnStunConfig
+	Storage   StorageConfig
+	Signaling SignalingConfig
+	Rooms     RoomsConfig
 }
 
 type ServerConfig struct {
@@ -23,6 +26,14 @@ type ServerConfig struct {
 	Host        string
 	BaseURL     string
 	Environment string
+	// ProxyHeader carries the client IP when the request came through one
+	// of TrustedProxies (IPs or CIDRs), e.g. the load balancer. The client IP
+	// is the rightmost address in it that is not a trusted proxy.
+	ProxyHeader    string
+	TrustedProxies []string
+	// MetricsAddr is where /metrics is served, apart from the public API so
+	// it can be kept to the internal network. Empty turns it off.
+	MetricsAddr string
 }
 
 type RedisConfig struct {
@@ -56,6 +67,22 @@ type StorageConfig struct {
 	S3Region  string
 }
 
+type SignalingConfig struct {
+	NodeID            string // unique per replica, used for presence and pub/sub routing
+	HeartbeatInterval int    // in seconds
+	NodeTTL           int    // in seconds, node is considered dead after this
+	ResumeGracePeriod int    // in seconds, how long a dropped client may resume its session
+	Shards            int    // room workers per node, rooms are hashed onto them
+	SendQueueSize     int    // messages queued per connection and priority class
+	SlowClientTimeout int    // in seconds, how long a connection may stay saturated before it is closed
+}
+
+type RoomsConfig struct {
+	KeepEmpty       bool // keep a room open after its last participant leaves
+	CleanupInterval int  // in seconds, how often one replica looks for rooms to clean up
+	IdleTimeout     int  // in seconds, rooms without activity for this long are checked
+}
+
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -70,6 +97,11 @@ func LoadConfig() (*Config, error) {
 			Host:        getEnv("SERVER_HOST", "0.0.0.0"),
 			BaseURL:     getEnv("BASE_URL", "http://localhost:8080"),
 			Environment: getEnv("ENV", "development"),
+
+			ProxyHeader:    getEnv("SERVER_PROXY_HEADER", "X-Forwarded-For"),
+			TrustedProxies: getEnvAsList("SERVER_TRUSTED_PROXIES"),
+
+			MetricsAddr: getEnv("SERVER_METRICS_ADDR", "127.0.0.1:9100"),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -97,6 +129,20 @@ func LoadConfig() (*Config, error) {
 			S3Bucket:  getEnv("STORAGE_S3_BUCKET", ""),
 			S3Region:  getEnv("STORAGE_S3_REGION", "us-east-1"),
 		},
+		Signaling: SignalingConfig{
+			NodeID:            getEnv("NODE_ID", defaultNodeID()),
+			HeartbeatInterval: getEnvAsInt("SIGNALING_HEARTBEAT_INTERVAL", 10),
+			NodeTTL:           getEnvAsInt("SIGNALING_NODE_TTL", 30),
+			ResumeGracePeriod: getEnvAsInt("SIGNALING_RESUME_GRACE_PERIOD", 20),
+			Shards:            getEnvAsInt("SIGNALING_SHARDS", 64),
+			SendQueueSize:     getEnvAsInt("SIGNALING_SEND_QUEUE_SIZE", 256),
+			SlowClientTimeout: getEnvAsInt("SIGNALING_SLOW_CLIENT_TIMEOUT", 5),
+		},
+		Rooms: RoomsConfig{
+			KeepEmpty:       getEnvAsBool("ROOMS_KEEP_EMPTY", true),
+			CleanupInterval: getEnvAsInt("ROOMS_CLEANUP_INTERVAL", 300),
+			IdleTimeout:     getEnvAsInt("ROOMS_IDLE_TIMEOUT", 600),
+		},
 	}
 
 	return config, nil
@@ -111,6 +157,35 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT secret must be changed in production")
 	}
 
+	if c.Signaling.NodeTTL <= c.Signaling.HeartbeatInterval {
+		return fmt.Errorf("signaling node TTL (%ds) must be greater than heartbeat interval (%ds)",
+			c.Signaling.NodeTTL, c.Signaling.HeartbeatInterval)
+	}
+
+	if c.Signaling.Shards <= 0 {
+		return fmt.Errorf("invalid signaling shard count: %d", c.Signaling.Shards)
+	}
+
+	if c.Signaling.ResumeGracePeriod < 0 {
+		return fmt.Errorf("invalid signaling resume grace period: %d", c.Signaling.ResumeGracePeriod)
+	}
+
+	if c.Signaling.SendQueueSize <= 0 {
+		return fmt.Errorf("invalid signaling send queue size: %d", c.Signaling.SendQueueSize)
+	}
+
+	if c.Signaling.SlowClientTimeout <= 0 {
+		return fmt.Errorf("invalid signaling slow client timeout: %d", c.Signaling.SlowClientTimeout)
+	}
+
+	if c.Rooms.CleanupInterval <= 0 {
+		return fmt.Errorf("invalid room cleanup interval: %d", c.Rooms.CleanupInterval)
+	}
+
+	if c.Rooms.IdleTimeout <= 0 {
+		return fmt.Errorf("invalid room idle timeout: %d", c.Rooms.IdleTimeout)
+	}
+
 	return nil
 }
 
@@ -129,6 +204,33 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsList reads a comma separated list, skipping empty items.
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func defaultNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "node"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
 func (c *Config) IsDevelopment() bool {
 	return c.Server.Environment == "development"
 }
@@ -18,6 +18,8 @@ import (
 	"golang.org/x/oauth2"
 )
 
+const wsTicketTTL = 30 * time.Second
+
 type GoogleUserInfo struct {
 	ID            string `json:"sub"`
 	Email         string `json:"email"`
@@ -263,6 +265,29 @@ func (h *AuthHandler) GetCurrentUser(c *fiber.Ctx) error {
 	return c.JSON(user)
 }
 
+// POST /api/auth/ws-ticket
+// Issues a short-lived, single-use ticket for clients that can't send the JWT
+// on the WebSocket upgrade (query string tokens end up in access logs).
+func (h *AuthHandler) IssueWebSocketTicket(c *fiber.Ctx) error {
+	claims := &middleware.JWTClaims{
+		UserID:      c.Locals("userID").(string),
+		Email:       c.Locals("email").(string),
+		DisplayName: c.Locals("displayName").(string),
+	}
+
+	ticket, err := middleware.IssueWebSocketTicket(c.Context(), h.redisClient, claims, wsTicketTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue ticket",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"ticket":    ticket,
+		"expiresIn": int(wsTicketTTL.Seconds()),
+	})
+}
+
 func (h *AuthHandler) storeOAuthToken(userID string, token *oauth2.Token) error {
 	key := fmt.Sprintf("oauth_token:%s", userID)
 	tokenJSON, _ := json.Marshal(token)
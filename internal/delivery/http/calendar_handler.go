@@ -4,6 +4,8 @@ import (
 	"bincang-visual/internal/domain/entity"
 	"bincang-visual/internal/domain/repository"
 	"bincang-visual/internal/domain/usecase"
+	"errors"
+	"log"
 
 	"fmt"
 	"time"
@@ -13,17 +15,20 @@ import (
 )
 
 type CalendarHandler struct {
-	calendarRepo repository.CalendarRepository
-	roomUseCase  *usecase.RoomUseCase
+	calendarRepo    repository.CalendarRepository
+	roomUseCase     *usecase.RoomUseCase
+	templateUseCase *usecase.TemplateUseCase
 }
 
 func NewCalendarHandler(
 	calendarRepo repository.CalendarRepository,
 	roomUseCase *usecase.RoomUseCase,
+	templateUseCase *usecase.TemplateUseCase,
 ) *CalendarHandler {
 	return &CalendarHandler{
-		calendarRepo: calendarRepo,
-		roomUseCase:  roomUseCase,
+		calendarRepo:    calendarRepo,
+		roomUseCase:     roomUseCase,
+		templateUseCase: templateUseCase,
 	}
 }
 
@@ -33,6 +38,7 @@ type CreateScheduledMeetingRequest struct {
 	StartTime   time.Time `json:"startTime"`
 	EndTime     time.Time `json:"endTime"`
 	Attendees   []string  `json:"attendees"`
+	TemplateID  string    `json:"templateId,omitempty"` // the creator's room defaults when left out
 }
 
 func (h *CalendarHandler) CreateScheduledMeeting(c *fiber.Ctx) error {
@@ -58,16 +64,30 @@ func (h *CalendarHandler) CreateScheduledMeeting(c *fiber.Ctx) error {
 		})
 	}
 
+	defaults, err := h.templateUseCase.RoomDefaults(c.Context(), userID, req.TemplateID)
+	switch {
+	case errors.Is(err, repository.ErrTemplateNotFound):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Room template not found",
+		})
+	case err != nil:
+		log.Printf("[Handler] Failed to get room defaults: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create room",
+		})
+	}
+
 	room, err := h.roomUseCase.CreateRoom(c.Context(), usecase.CreateRoomInput{
 		Name:            req.Title,
 		HostID:          userID,
-		MaxParticipants: 100,
-		Settings: entity.RoomSettings{
-			AllowScreenShare: true,
-			AllowChat:        true,
-			RecordingEnabled: true,
-		},
+		MaxParticipants: defaults.MaxParticipants,
+		Settings:        defaults.Settings,
 	})
+	if errors.Is(err, usecase.ErrInvalidRoom) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create room",
@@ -3,34 +3,42 @@ package http
 import (
 	"bincang-visual/internal/config"
 	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
 	"bincang-visual/internal/domain/usecase"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type RoomHandler struct {
-	roomUseCase *usecase.RoomUseCase
-	baseURL     string
+	roomUseCase     *usecase.RoomUseCase
+	templateUseCase *usecase.TemplateUseCase
+	baseURL         string
 }
 
-func NewRoomHandler(roomUseCase *usecase.RoomUseCase, baseURL string) *RoomHandler {
+func NewRoomHandler(roomUseCase *usecase.RoomUseCase, templateUseCase *usecase.TemplateUseCase, baseURL string) *RoomHandler {
 	return &RoomHandler{
-		roomUseCase: roomUseCase,
-		baseURL:     baseURL,
+		roomUseCase:     roomUseCase,
+		templateUseCase: templateUseCase,
+		baseURL:         baseURL,
 	}
 }
 
 type CreateRoomRequest struct {
-	Name            string              `json:"name"`
-	MaxParticipants int                 `json:"maxParticipants"`
-	Settings        entity.RoomSettings `json:"settings"`
+	Name            string               `json:"name"`
+	TemplateID      string               `json:"templateId,omitempty"` // the creator's room defaults when left out
+	MaxParticipants int                  `json:"maxParticipants"`      // overrides the template's
+	Settings        *entity.RoomSettings `json:"settings"`             // overrides the template's
+	Passcode        string               `json:"passcode,omitempty"`
 }
 
 type CreateRoomResponse struct {
 	RoomID    string `json:"roomId"`
+	Code      string `json:"code"` // works wherever the room ID does
 	RoomURL   string `json:"roomUrl"`
 	JoinURL   string `json:"joinUrl"`
 	HostID    string `json:"hostId"`
@@ -39,7 +47,7 @@ type CreateRoomResponse struct {
 
 func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 	// Get user from context (set by auth middleware)
-	userID := "anonymous"
+	userID := usecase.AnonymousHostID
 	if uid := c.Locals("userID"); uid != nil {
 		userID = uid.(string)
 	}
@@ -51,12 +59,38 @@ func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 		})
 	}
 
+	defaults, err := h.templateUseCase.RoomDefaults(c.Context(), userID, req.TemplateID)
+	switch {
+	case errors.Is(err, repository.ErrTemplateNotFound):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Room template not found",
+		})
+	case err != nil:
+		log.Printf("[Handler] Failed to get room defaults: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create room",
+		})
+	}
+
+	if req.MaxParticipants != 0 {
+		defaults.MaxParticipants = req.MaxParticipants
+	}
+	if req.Settings != nil {
+		defaults.Settings = *req.Settings
+	}
+
 	room, err := h.roomUseCase.CreateRoom(c.Context(), usecase.CreateRoomInput{
 		Name:            req.Name,
 		HostID:          userID,
-		MaxParticipants: req.MaxParticipants,
-		Settings:        req.Settings,
+		MaxParticipants: defaults.MaxParticipants,
+		Settings:        defaults.Settings,
+		Passcode:        req.Passcode,
 	})
+	if errors.Is(err, usecase.ErrInvalidPasscode) || errors.Is(err, usecase.ErrInvalidRoom) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		log.Printf("[Handler] Failed to create room: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -69,6 +103,7 @@ func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusCreated).JSON(CreateRoomResponse{
 		RoomID:    room.ID,
+		Code:      room.Code,
 		RoomURL:   roomURL,
 		JoinURL:   joinURL,
 		HostID:    room.HostID,
@@ -76,7 +111,7 @@ func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 	})
 }
 
-// GET /api/rooms/:roomId
+// GET /api/rooms/:roomId, by ID or code
 func (h *RoomHandler) GetRoom(c *fiber.Ctx) error {
 	roomID := c.Params("roomId")
 
@@ -91,6 +126,147 @@ func (h *RoomHandler) GetRoom(c *fiber.Ctx) error {
 	return c.JSON(room)
 }
 
+// GET /api/rooms?mine=true&active=true&q=&cursor=&limit=
+func (h *RoomHandler) ListRooms(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	// listing everyone's rooms would give away who meets when
+	if !c.QueryBool("mine", true) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only your own rooms can be listed",
+		})
+	}
+
+	page, err := h.roomUseCase.ListRooms(c.Context(), usecase.ListRoomsInput{
+		UserID:     userID,
+		ActiveOnly: c.QueryBool("active"),
+		Query:      c.Query("q"),
+		Cursor:     c.Query("cursor"),
+		Limit:      c.QueryInt("limit"),
+	})
+	switch {
+	case err == nil:
+		return c.JSON(page)
+	case errors.Is(err, usecase.ErrInvalidCursor):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+		})
+	default:
+		log.Printf("[Handler] Failed to list rooms: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list rooms",
+		})
+	}
+}
+
+type UpdateRoomRequest struct {
+	Version         *int64                 `json:"version"` // version of the room the change is based on
+	Name            *string                `json:"name"`
+	MaxParticipants *int                   `json:"maxParticipants"`
+	Settings        *entity.RoomSettings   `json:"settings"`
+	Metadata        map[string]interface{} `json:"metadata"`
+}
+
+// PATCH /api/rooms/:roomId
+func (h *RoomHandler) UpdateRoom(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	var req UpdateRoomRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Version == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "version is required",
+		})
+	}
+
+	room, err := h.roomUseCase.UpdateRoom(c.Context(), usecase.UpdateRoomInput{
+		RoomID:          roomID,
+		UserID:          userID,
+		Version:         *req.Version,
+		Name:            req.Name,
+		MaxParticipants: req.MaxParticipants,
+		Settings:        req.Settings,
+		Metadata:        req.Metadata,
+	})
+	switch {
+	case err == nil:
+		return c.JSON(room)
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can update the room",
+		})
+	case errors.Is(err, repository.ErrRoomChanged):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Room was changed by someone else, reload it and try again",
+		})
+	case errors.Is(err, usecase.ErrInvalidRoomUpdate),
+		errors.Is(err, usecase.ErrBelowParticipantCount):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to update room: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update room",
+		})
+	}
+}
+
+type ExtendRoomRequest struct {
+	Minutes int `json:"minutes"`
+}
+
+// POST /api/rooms/:roomId/extend
+func (h *RoomHandler) ExtendRoom(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	var req ExtendRoomRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	duration := time.Duration(req.Minutes) * time.Minute
+	room, err := h.roomUseCase.ExtendRoomDuration(c.Context(), roomID, userID, duration)
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{
+			"room":   room,
+			"endsAt": room.EndsAt(),
+		})
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can extend the meeting",
+		})
+	case errors.Is(err, usecase.ErrInvalidExtension),
+		errors.Is(err, usecase.ErrNoTimeLimit),
+		errors.Is(err, usecase.ErrMeetingOver):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to extend room: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to extend meeting",
+		})
+	}
+}
+
 // GET /api/rooms/:roomId/participants
 func (h *RoomHandler) GetParticipants(c *fiber.Ctx) error {
 	roomID := c.Params("roomId")
@@ -139,9 +315,7 @@ func (h *RoomHandler) StartRecording(c *fiber.Ctx) error {
 
 	recording, err := h.roomUseCase.StartRecording(c.Context(), req.RoomID, userID)
 	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return forbidden(c, err)
 	}
 
 	return c.JSON(recording)
@@ -164,11 +338,14 @@ func (h *RoomHandler) StopRecording(c *fiber.Ctx) error {
 	}
 
 	err := h.roomUseCase.StopRecording(c.Context(), req.RoomID, req.RecordingID, userID)
-	if err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+	if errors.Is(err, usecase.ErrNotRoomRecording) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if err != nil {
+		return forbidden(c, err)
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "Recording stopped successfully",
@@ -244,24 +421,26 @@ func (h *RoomHandler) DeleteRoom(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 	roomID := c.Params("roomId")
 
-	room, err := h.roomUseCase.GetRoom(c.Context(), roomID)
-	if err != nil {
-		log.Printf("[Handler] Room not found: %v", err)
+	err := h.roomUseCase.EndMeeting(c.Context(), roomID, userID)
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{
+			"message": "Meeting ended for all participants",
+		})
+	case errors.Is(err, repository.ErrRoomNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Room not found",
 		})
-	}
-
-	if room.HostID != userID {
-		log.Printf("[Handler] Only host can delete the room: %v", err)
+	case errors.Is(err, usecase.ErrNotHost):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only host can delete the room",
 		})
+	default:
+		log.Printf("[Handler] Failed to end meeting: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to end meeting",
+		})
 	}
-
-	return c.JSON(fiber.Map{
-		"message": "Room will be deleted when all participants leave",
-	})
 }
 
 func (h *RoomHandler) GenerateRoomLink(c *fiber.Ctx) error {
@@ -275,10 +454,11 @@ func (h *RoomHandler) GenerateRoomLink(c *fiber.Ctx) error {
 		})
 	}
 
-	link := fmt.Sprintf("%s/join/%s", h.baseURL, roomID)
+	link := fmt.Sprintf("%s/join/%s", h.baseURL, room.ID)
 
 	return c.JSON(fiber.Map{
-		"roomId":   roomID,
+		"roomId":   room.ID,
+		"code":     room.Code,
 		"roomName": room.Name,
 		"link":     link,
 		"qrCode":   generateQRCodeURL(link),
@@ -293,19 +473,285 @@ func (h *RoomHandler) ValidateRoom(c *fiber.Ctx) error {
 		})
 	}
 
-	room, err := h.roomUseCase.GetRoom(c.Context(), roomID)
-	if err != nil {
-		log.Printf("[Handler] Room not found: %v", err)
+	userID, _ := c.Locals("userID").(string)
+	email, _ := c.Locals("email").(string)
+	room, err := h.roomUseCase.ValidateRoomAccess(c.Context(), usecase.RoomAccessInput{
+		RoomID:      roomID,
+		UserID:      userID,
+		Email:       email,
+		Passcode:    c.Query("passcode"),
+		InviteToken: c.Query("invite"),
+		ClientIP:    c.IP(),
+	})
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{
+			"data": room,
+		})
+	case errors.Is(err, repository.ErrRoomNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Room not found",
 		})
+	case errors.Is(err, usecase.ErrPasscodeRequired):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":            err.Error(),
+			"passcodeRequired": true,
+		})
+	case errors.Is(err, usecase.ErrWrongPasscode):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrTooManyAttempts):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrRoomLocked):
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrInviteSignInRequired):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrInvalidInvite),
+		errors.Is(err, usecase.ErrInviteNotForYou):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to validate room: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to validate room",
+		})
+	}
+}
+
+type LockRoomRequest struct {
+	Locked bool `json:"locked"`
+}
+
+// POST /api/rooms/:roomId/lock
+func (h *RoomHandler) LockRoom(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	var req LockRoomRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	room, err := h.roomUseCase.SetRoomLocked(c.Context(), roomID, userID, req.Locked)
+	switch {
+	case err == nil:
+		return c.JSON(room)
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can lock the room",
+		})
+	default:
+		log.Printf("[Handler] Failed to lock room: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to lock room",
+		})
+	}
+}
+
+type CreateInviteRequest struct {
+	Role             entity.Role `json:"role"` // participant, co-host or viewer
+	Email            string      `json:"email,omitempty"`
+	MaxUses          int         `json:"maxUses,omitempty"`
+	ExpiresInMinutes int         `json:"expiresInMinutes,omitempty"` // a day when left out
+}
+
+type CreateInviteResponse struct {
+	Invite *entity.Invite `json:"invite"`
+	Token  string         `json:"token"`
+	URL    string         `json:"url"`
+}
+
+// POST /api/rooms/:roomId/invites
+func (h *RoomHandler) CreateInvite(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	var req CreateInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	invite, token, err := h.roomUseCase.CreateInvite(c.Context(), usecase.CreateInviteInput{
+		RoomID:    roomID,
+		UserID:    userID,
+		Role:      req.Role,
+		Email:     req.Email,
+		MaxUses:   req.MaxUses,
+		ExpiresIn: time.Duration(req.ExpiresInMinutes) * time.Minute,
+	})
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusCreated).JSON(CreateInviteResponse{
+			Invite: invite,
+			Token:  token,
+			URL:    fmt.Sprintf("%s/join/%s?invite=%s", h.baseURL, roomID, url.QueryEscape(token)),
+		})
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can invite to the room",
+		})
+	case errors.Is(err, usecase.ErrInvalidInviteRequest):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to create invite: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create invite",
+		})
+	}
+}
+
+// GET /api/rooms/:roomId/invites
+func (h *RoomHandler) ListInvites(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	invites, err := h.roomUseCase.ListInvites(c.Context(), roomID, userID)
+	switch {
+	case err == nil:
+		return c.JSON(invites)
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can see the room's invites",
+		})
+	default:
+		log.Printf("[Handler] Failed to list invites: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list invites",
+		})
+	}
+}
+
+// DELETE /api/rooms/:roomId/invites/:inviteId
+func (h *RoomHandler) RevokeInvite(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	roomID := c.Params("roomId")
+
+	err := h.roomUseCase.RevokeInvite(c.Context(), roomID, c.Params("inviteId"), userID)
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{
+			"message": "Invite revoked",
+		})
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Room not found",
+		})
+	case errors.Is(err, repository.ErrInviteNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invite not found",
+		})
+	case errors.Is(err, usecase.ErrNotHost):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only host can revoke invites",
+		})
+	default:
+		log.Printf("[Handler] Failed to revoke invite: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke invite",
+		})
+	}
+}
+
+// GET /api/me/room
+func (h *RoomHandler) GetPersonalRoom(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	displayName, _ := c.Locals("displayName").(string)
+
+	room, err := h.roomUseCase.GetPersonalRoom(c.Context(), userID, displayName)
+	if err != nil {
+		log.Printf("[Handler] Failed to get personal room: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get personal room",
+		})
 	}
 
 	return c.JSON(fiber.Map{
-		"data": room,
+		"room": room,
+		"link": fmt.Sprintf("%s/join/%s", h.baseURL, room.Code),
 	})
 }
 
+type SetRoomCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// PUT /api/me/room/code
+func (h *RoomHandler) SetPersonalRoomCode(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	var req SetRoomCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	room, err := h.roomUseCase.SetPersonalRoomCode(c.Context(), userID, req.Code)
+	switch {
+	case err == nil:
+		return c.JSON(room)
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Personal room not found",
+		})
+	case errors.Is(err, usecase.ErrInvalidCode):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrCodeTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to set room code: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set room code",
+		})
+	}
+}
+
+// forbidden refuses a request, naming the room setting that refused it when
+// there is one.
+func forbidden(c *fiber.Ctx, err error) error {
+	body := fiber.Map{
+		"error": err.Error(),
+	}
+
+	var policyErr *usecase.PolicyError
+	if errors.As(err, &policyErr) {
+		body["capability"] = policyErr.Capability
+		body["role"] = policyErr.Role
+	}
+
+	return c.Status(fiber.StatusForbidden).JSON(body)
+}
+
 func generateQRCodeURL(link string) string {
 	return fmt.Sprintf("https://api.qrserver.com/v1/create-qr-code/?size=300x300&data=%s",
 		url.QueryEscape(link))
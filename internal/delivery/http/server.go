@@ -0,0 +1,30 @@
+package http
+
+import (
+	"bincang-visual/internal/config"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AppConfig is the Fiber configuration of the API server. Behind a load
+// balancer c.IP() reads the client IP from the proxy header, but only when
+// the request really came from one of the trusted proxies; otherwise anyone
+// could pick their own IP and every client would share the balancer's.
+// Fiber takes the header's leftmost address, which the client writes itself,
+// so middleware.ClientIPMiddleware has to narrow the header down first.
+func AppConfig(cfg config.ServerConfig) fiber.Config {
+	return fiber.Config{
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		BodyLimit:    50 * 1024 * 1024, // 50MB for recordings
+		ServerHeader: "Bincang-Visual",
+		AppName:      "Bincang Visual v1.0.0",
+
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.TrustedProxies,
+		EnableIPValidation:      true,
+	}
+}
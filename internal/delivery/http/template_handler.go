@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TemplateHandler struct {
+	templateUseCase *usecase.TemplateUseCase
+}
+
+func NewTemplateHandler(templateUseCase *usecase.TemplateUseCase) *TemplateHandler {
+	return &TemplateHandler{
+		templateUseCase: templateUseCase,
+	}
+}
+
+// GET /api/templates, the built-in templates and the user's own
+func (h *TemplateHandler) ListTemplates(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	templates, err := h.templateUseCase.ListTemplates(c.Context(), userID)
+	if err != nil {
+		log.Printf("[Handler] Failed to list templates: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list templates",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"templates": templates,
+	})
+}
+
+type CreateTemplateRequest struct {
+	Name            string              `json:"name"`
+	MaxParticipants int                 `json:"maxParticipants"`
+	Settings        entity.RoomSettings `json:"settings"`
+}
+
+// POST /api/templates
+func (h *TemplateHandler) CreateTemplate(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	var req CreateTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	template, err := h.templateUseCase.CreateTemplate(c.Context(), usecase.CreateTemplateInput{
+		UserID: userID,
+		Name:   req.Name,
+		RoomDefaults: entity.RoomDefaults{
+			MaxParticipants: req.MaxParticipants,
+			Settings:        req.Settings,
+		},
+	})
+	switch {
+	case err == nil:
+		return c.Status(fiber.StatusCreated).JSON(template)
+	case errors.Is(err, usecase.ErrInvalidTemplate):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, usecase.ErrTooManyTemplates):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to create template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create template",
+		})
+	}
+}
+
+// DELETE /api/templates/:templateId
+func (h *TemplateHandler) DeleteTemplate(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	err := h.templateUseCase.DeleteTemplate(c.Context(), userID, c.Params("templateId"))
+	switch {
+	case err == nil:
+		return c.SendStatus(fiber.StatusNoContent)
+	case errors.Is(err, repository.ErrTemplateNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Template not found",
+		})
+	case errors.Is(err, usecase.ErrBuiltInTemplate):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to delete template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete template",
+		})
+	}
+}
+
+// GET /api/me/room-defaults
+func (h *TemplateHandler) GetRoomDefaults(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	defaults, err := h.templateUseCase.GetRoomDefaults(c.Context(), userID)
+	if err != nil {
+		log.Printf("[Handler] Failed to get room defaults: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get room defaults",
+		})
+	}
+
+	return c.JSON(defaults)
+}
+
+// PUT /api/me/room-defaults
+func (h *TemplateHandler) SetRoomDefaults(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	var req entity.RoomDefaults
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	err := h.templateUseCase.SetRoomDefaults(c.Context(), userID, req)
+	switch {
+	case err == nil:
+		return h.GetRoomDefaults(c)
+	case errors.Is(err, usecase.ErrInvalidTemplate):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		log.Printf("[Handler] Failed to set room defaults: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set room defaults",
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"bincang-visual/internal/infrastructure/metrics"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+// priority classes of queued messages, used as metric labels
+const (
+	classCritical = "critical"
+	classBulk     = "bulk"
+)
+
+// enqueue hands a frame to the write pump without blocking. Bulk messages are
+// dropped while their queue is full, and a client whose bulk queue stays full
+// for longer than the slow client timeout is evicted. Signaling is never
+// dropped: a client that can't take it anymore is evicted right away, so it
+// reconnects and resumes instead of silently missing an offer or candidate.
+func (c *Client) enqueue(frame []byte, bulk bool) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	queue, class := c.priority, classCritical
+	if bulk {
+		queue, class = c.Send, classBulk
+	}
+
+	select {
+	case queue <- frame:
+		metrics.ObserveSendQueueDepth(class, len(queue))
+		if bulk {
+			c.saturated = time.Time{}
+		}
+		return true
+	default:
+	}
+
+	metrics.IncMessagesDropped(class)
+
+	if !bulk {
+		log.Printf("[WebSocket] Signaling queue full for %s, evicting", c.UserID)
+		c.evictLocked()
+		return false
+	}
+
+	now := time.Now()
+	if c.saturated.IsZero() {
+		c.saturated = now
+	}
+
+	timeout := time.Duration(c.Hub.config.SlowClientTimeout) * time.Second
+	if now.Sub(c.saturated) >= timeout {
+		log.Printf("[WebSocket] %s saturated for %s, evicting", c.UserID, now.Sub(c.saturated).Round(time.Millisecond))
+		c.evictLocked()
+		return false
+	}
+
+	log.Printf("[WebSocket] Dropped message for %s, queue full", c.UserID)
+	return false
+}
+
+// evictLocked closes a slow consumer. It isn't leaving, so its place in the
+// room is held for the grace period like any dropped connection.
+func (c *Client) evictLocked() {
+	metrics.IncSlowClientsEvicted()
+	c.closeLocked(CloseSlowConsumer, "slow consumer")
+}
+
+func (c *Client) closeSend() {
+	c.closeWithCode(0, "")
+}
+
+// closeWithCode closes the connection with an application close code once
+// the queued messages are flushed.
+func (c *Client) closeWithCode(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.closeLocked(code, reason)
+}
+
+func (c *Client) closeLocked(code int, reason string) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	close(c.Send)
+}
+
+// flushPriority writes the signaling still queued once Send is closed.
+func (c *Client) flushPriority() {
+	for {
+		select {
+		case message := <-c.priority:
+			if err := c.write(message); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *Client) write(message []byte) error {
+	c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+	frameType := websocket.TextMessage
+	if c.codec.Binary() {
+		frameType = websocket.BinaryMessage
+	}
+	return c.Conn.WriteMessage(frameType, message)
+}
@@ -0,0 +1,235 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const roomChannelPrefix = "signaling:room:"
+
+// envelope is what travels over the broker between nodes. Every node
+// subscribed to the room delivers it to its own local clients only.
+type envelope struct {
+	NodeID    string          `json:"nodeId"`
+	RoomID    string          `json:"roomId"`
+	To        string          `json:"to,omitempty"`
+	Exclude   string          `json:"exclude,omitempty"`
+	HostsOnly bool            `json:"hostsOnly,omitempty"`
+	Bulk      bool            `json:"bulk,omitempty"`
+	Command   string          `json:"command,omitempty"` // handled by the node holding To, or every node without one
+	ClientID  string          `json:"clientId,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+func roomChannel(roomID string) string {
+	return roomChannelPrefix + roomID
+}
+
+// publish sends a message to every node serving the room, including this one.
+func (h *SignalingHub) publish(msg *BroadcastMessage) {
+	if msg == nil {
+		return
+	}
+
+	h.publishEnvelope(&envelope{
+		NodeID:    h.nodeID,
+		RoomID:    msg.RoomID,
+		To:        msg.To,
+		Exclude:   msg.Exclude,
+		HostsOnly: msg.HostsOnly,
+		Bulk:      msg.Bulk,
+		Message:   msg.Message,
+	})
+}
+
+// publishCommand asks whichever node holds the participant's connection to
+// act on it.
+func (h *SignalingHub) publishCommand(roomID, participantID, command string) {
+	h.publishEnvelope(&envelope{
+		NodeID:  h.nodeID,
+		RoomID:  roomID,
+		To:      participantID,
+		Command: command,
+	})
+}
+
+func (h *SignalingHub) publishEnvelope(env *envelope) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("[Hub] Failed to marshal envelope: %v", err)
+		return
+	}
+
+	if err := h.broker.Publish(context.Background(), roomChannel(env.RoomID), data); err != nil {
+		log.Printf("[Hub] Failed to publish to room %s: %v", env.RoomID, err)
+	}
+}
+
+// consume delivers messages coming from the broker to local clients.
+func (h *SignalingHub) consume() {
+	for msg := range h.broker.Messages() {
+		if !strings.HasPrefix(msg.Channel, roomChannelPrefix) {
+			continue
+		}
+
+		var env envelope
+		if err := json.Unmarshal(msg.Payload, &env); err != nil {
+			log.Printf("[Hub] Failed to parse envelope on %s: %v", msg.Channel, err)
+			continue
+		}
+
+		var delivered bool
+		if env.Command != "" {
+			delivered = h.dispatch(env.RoomID, func(s *shard) { s.handleCommand(&env) })
+		} else {
+			msg := &BroadcastMessage{
+				RoomID:    env.RoomID,
+				Message:   env.Message,
+				Exclude:   env.Exclude,
+				To:        env.To,
+				HostsOnly: env.HostsOnly,
+				Bulk:      env.Bulk,
+			}
+			delivered = h.dispatch(env.RoomID, func(s *shard) { s.deliver(msg) })
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+func (s *shard) handleCommand(env *envelope) {
+	switch env.Command {
+	case commandAdmit:
+		s.admitClient(env.RoomID, env.To)
+	case commandDeny:
+		s.denyClient(env.RoomID, env.To)
+	case commandRemove:
+		s.kickClient(env.RoomID, env.To, CloseRemovedByHost, "removed")
+	case commandBan:
+		s.kickUser(env.RoomID, env.To, CloseBanned, "banned")
+	case commandDisconnectDevice:
+		s.kickClient(env.RoomID, env.To, CloseDeviceDisconnected, "disconnected")
+	case commandTransferred:
+		s.kickClient(env.RoomID, env.To, CloseCallTransferred, "transferred")
+	case commandResumed:
+		s.resumedElsewhere(env.RoomID, env.To, env.ClientID)
+	case commandEndMeeting:
+		s.endMeeting(env.RoomID, env.Message)
+	default:
+		log.Printf("[Hub] Unknown command '%s'", env.Command)
+	}
+}
+
+func (h *SignalingHub) subscribeRoom(roomID string) {
+	if err := h.broker.Subscribe(context.Background(), roomChannel(roomID)); err != nil {
+		log.Printf("[Hub] Failed to subscribe to room %s: %v", roomID, err)
+	}
+}
+
+func (h *SignalingHub) unsubscribeRoom(roomID string) {
+	if err := h.broker.Unsubscribe(context.Background(), roomChannel(roomID)); err != nil {
+		log.Printf("[Hub] Failed to unsubscribe from room %s: %v", roomID, err)
+	}
+}
+
+// heartbeat keeps this node marked alive and reaps participants whose node
+// died without saying goodbye.
+func (h *SignalingHub) heartbeat() {
+	interval := time.Duration(h.config.HeartbeatInterval) * time.Second
+	ttl := time.Duration(h.config.NodeTTL) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.presenceRepo.NodeHeartbeat(context.Background(), h.nodeID, ttl); err != nil {
+			log.Printf("[Hub] Heartbeat failed for node %s: %v", h.nodeID, err)
+		}
+		h.sweepDeadPresence()
+
+		select {
+		case <-ticker.C:
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// activeRooms returns the rooms with admitted clients on this node.
+func (h *SignalingHub) activeRooms() []string {
+	var mu sync.Mutex
+	var roomIDs []string
+	h.eachShard(func(s *shard) {
+		mu.Lock()
+		defer mu.Unlock()
+		for roomID, r := range s.rooms {
+			if len(r.clients) > 0 {
+				roomIDs = append(roomIDs, roomID)
+			}
+		}
+	})
+	return roomIDs
+}
+
+func (h *SignalingHub) sweepDeadPresence() {
+	ctx := context.Background()
+	alive := map[string]bool{h.nodeID: true}
+
+	for _, roomID := range h.activeRooms() {
+		presence, err := h.presenceRepo.GetPresence(ctx, roomID)
+		if err != nil {
+			log.Printf("[Hub] Failed to get presence for room %s: %v", roomID, err)
+			continue
+		}
+
+		for participantID, p := range presence {
+			// still inside its grace period
+			if p.Disconnected && time.Now().UnixMilli() < p.ResumeBy {
+				continue
+			}
+
+			if p.NodeID == h.nodeID {
+				// our entry but no local socket: the unregister was missed
+				if h.hasLocalClient(roomID, participantID) {
+					continue
+				}
+			} else {
+				isAlive, checked := alive[p.NodeID]
+				if !checked {
+					isAlive, err = h.presenceRepo.IsNodeAlive(ctx, p.NodeID)
+					if err != nil {
+						continue
+					}
+					alive[p.NodeID] = isAlive
+				}
+				if isAlive {
+					continue
+				}
+			}
+
+			// the conditional removal makes sure only one node announces it
+			removed, err := h.presenceRepo.RemovePresence(ctx, roomID, participantID, p)
+			if err != nil || !removed {
+				continue
+			}
+
+			log.Printf("[Hub] Reaping %s from room %s (node %s gone)", participantID, roomID, p.NodeID)
+			h.handleDeparture(roomID, participantID, "")
+		}
+	}
+}
+
+func (h *SignalingHub) hasLocalClient(roomID, participantID string) bool {
+	var exists bool
+	h.query(roomID, func(s *shard) {
+		if r := s.rooms[roomID]; r != nil {
+			_, exists = r.clients[participantID]
+		}
+	})
+	return exists
+}
@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	commandDisconnectDevice = "disconnect-device"
+	commandTransferred      = "transferred"
+
+	maxDeviceLabel = 64
+)
+
+// handleListDevices tells a client which other devices its user is in the
+// room with.
+func (c *Client) handleListDevices() {
+	devices, err := c.Hub.roomUseCase.GetDevices(context.Background(), c.RoomID, c.ParticipantID)
+	if err != nil {
+		log.Printf("[WebSocket] Error getting devices of %s: %v", c.UserID, err)
+		c.sendError(protocol.NewError(errorCode(err), err.Error()))
+		return
+	}
+
+	c.sendDevices(devices)
+}
+
+// handleDisconnectDevice signs one of the user's other devices out of the
+// room.
+func (c *Client) handleDisconnectDevice(req *protocol.Request, payload *protocol.Target) {
+	device, err := c.Hub.roomUseCase.DisconnectDevice(context.Background(), c.RoomID, c.ParticipantID, payload.ParticipantID)
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.ParticipantID, err)
+		c.replyError(req, err)
+		return
+	}
+
+	c.Hub.publishCommand(c.RoomID, device.ID, commandDisconnectDevice)
+}
+
+// handleTransferHere moves the call to this device: the user's other devices
+// are closed and this one stays.
+func (c *Client) handleTransferHere(req *protocol.Request) {
+	moved, err := c.Hub.roomUseCase.TransferToDevice(context.Background(), c.RoomID, c.ParticipantID)
+
+	// close whatever already left even if the transfer stopped early
+	for _, device := range moved {
+		c.Hub.publishCommand(c.RoomID, device.ID, commandTransferred)
+	}
+
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.ParticipantID, err)
+		c.replyError(req, err)
+		return
+	}
+
+	c.handleListDevices()
+}
+
+func (c *Client) sendDevices(devices []*entity.Participant) {
+	list := make([]map[string]interface{}, 0, len(devices))
+	for _, d := range devices {
+		list = append(list, map[string]interface{}{
+			"participantId": d.ID,
+			"device":        d.Device,
+			"joinedAt":      d.JoinedAt,
+			"current":       d.ID == c.ParticipantID,
+		})
+	}
+
+	c.sendMessage(entity.SignalMessage{
+		Type:   "devices",
+		From:   "server",
+		RoomID: c.RoomID,
+		Data: map[string]interface{}{
+			"devices": list,
+		},
+		Timestamp: time.Now(),
+	})
+}
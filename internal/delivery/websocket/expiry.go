@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	commandEndMeeting = "end-meeting" // every node closes its clients in the room
+
+	expiryCheckInterval = 15 * time.Second
+)
+
+// expiryWarnings are how long before a meeting's time limit clients are
+// warned, longest first.
+var expiryWarnings = []time.Duration{5 * time.Minute, time.Minute}
+
+// watchExpiry warns the rooms on this node that are about to run out of
+// time and ends them when they do. Every node holding a room checks it; the
+// notices are claimed in Redis so each goes out once.
+func (h *SignalingHub) watchExpiry() {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, roomID := range h.activeRooms() {
+				h.checkExpiry(roomID)
+			}
+		case <-h.done:
+			return
+		}
+	}
+}
+
+func (h *SignalingHub) checkExpiry(roomID string) {
+	ctx := context.Background()
+
+	room, err := h.roomUseCase.GetRoom(ctx, roomID)
+	if err != nil {
+		return
+	}
+	endsAt := room.EndsAt()
+	if endsAt.IsZero() {
+		return
+	}
+
+	remaining := time.Until(endsAt)
+	if remaining <= 0 {
+		// the room use case announces the end through MeetingEnded
+		if _, err := h.roomUseCase.EndExpiredMeeting(ctx, roomID); err != nil {
+			log.Printf("[Hub] Failed to end expired room %s: %v", roomID, err)
+		}
+		return
+	}
+
+	// only the closest warning, a short meeting may start inside the first
+	var warning time.Duration
+	for _, w := range expiryWarnings {
+		if remaining <= w {
+			warning = w
+		}
+	}
+	if warning == 0 {
+		return
+	}
+
+	notice := fmt.Sprintf("warn-%d", int(warning.Seconds()))
+	claimed, err := h.roomUseCase.ClaimExpiryNotice(ctx, roomID, endsAt, notice)
+	if err != nil {
+		log.Printf("[Hub] Failed to claim expiry warning for room %s: %v", roomID, err)
+		return
+	}
+	if claimed {
+		h.publishRoomExpiring(room, remaining)
+	}
+}
+
+// publishRoomExpiring warns everyone in the room. Hosts can answer with
+// "extend-meeting".
+func (h *SignalingHub) publishRoomExpiring(room *entity.Room, remaining time.Duration) {
+	notification := entity.SignalMessage{
+		Type:   "room-expiring",
+		From:   "server",
+		RoomID: room.ID,
+		Data: map[string]interface{}{
+			"endsAt":      room.EndsAt(),
+			"secondsLeft": int(remaining.Seconds()),
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  room.ID,
+		Message: data,
+	})
+}
+
+// MeetingEnded has every node say goodbye to its clients in the room and
+// close their connections. The room itself is already gone.
+func (h *SignalingHub) MeetingEnded(roomID, reason string) {
+	notification := entity.SignalMessage{
+		Type:   "meeting-ended",
+		From:   "server",
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"reason": reason,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publishEnvelope(&envelope{
+		NodeID:  h.nodeID,
+		RoomID:  roomID,
+		Command: commandEndMeeting,
+		Message: data,
+	})
+}
+
+// endMeeting closes every local connection to the room, including the ones
+// waiting in the lobby, and forgets dropped clients that would otherwise
+// come back. Nobody is announced as leaving, the room went as a whole.
+func (s *shard) endMeeting(roomID string, notification json.RawMessage) {
+	r := s.rooms[roomID]
+	if r == nil {
+		return
+	}
+
+	for _, clients := range []map[string]*Client{r.clients, r.lobby} {
+		for _, client := range clients {
+			client.leaving.Store(true)
+			client.ended.Store(true)
+			client.queue(notification, false)
+			client.closeWithCode(CloseMeetingEnded, "meeting ended")
+		}
+	}
+
+	h := s.hub
+	for _, sc := range r.suspended {
+		r.dropSuspended(sc)
+		s.async(func() { h.endSession(sc.token) })
+	}
+	s.release(r)
+
+	log.Printf("[Hub] Meeting in room %s ended", roomID)
+}
+
+// handleEndMeeting lets the host end the meeting for everyone.
+func (c *Client) handleEndMeeting(req *protocol.Request) {
+	if err := c.Hub.roomUseCase.EndMeeting(context.Background(), c.RoomID, c.UserID); err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
+
+// handleExtendMeeting lets the host give the meeting more time.
+func (c *Client) handleExtendMeeting(req *protocol.Request, payload *protocol.Extension) {
+	duration := time.Duration(payload.Minutes) * time.Minute
+	if _, err := c.Hub.roomUseCase.ExtendRoomDuration(context.Background(), c.RoomID, c.UserID, duration); err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
@@ -0,0 +1,160 @@
+package websocket
+
+import (
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/infrastructure/pubsub"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	benchRooms          = 1000
+	benchClientsPerRoom = 10
+)
+
+var benchMessage = []byte(`{"type":"ice","from":"user-0","roomId":"room-0","data":{"candidate":"candidate:842163049 1 udp 1677729535 203.0.113.7 54321 typ srflx raddr 0.0.0.0 rport 0 generation 0 ufrag sK3k network-cost 999","sdpMid":"0","sdpMLineIndex":0},"timestamp":"2026-01-01T00:00:00Z"}`)
+
+// newBenchHub starts a hub on an in-process broker with benchRooms rooms of
+// benchClientsPerRoom fake clients. onReceive runs for every delivered frame.
+func newBenchHub(b *testing.B, onReceive func()) *SignalingHub {
+	b.Helper()
+
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	broker := pubsub.NewLocalBroker()
+	h := NewSignalingHub(nil, nil, nil, broker, config.SignalingConfig{
+		NodeID:            "bench",
+		Shards:            64,
+		SendQueueSize:     1024,
+		SlowClientTimeout: 5,
+	})
+	h.startShards()
+	go h.consume()
+
+	var receivers sync.WaitGroup
+	for i := 0; i < benchRooms; i++ {
+		roomID := fmt.Sprintf("room-%d", i)
+		h.query(roomID, func(s *shard) {
+			r := s.room(roomID)
+			for j := 0; j < benchClientsPerRoom; j++ {
+				id := fmt.Sprintf("client-%d-%d", i, j)
+				client := &Client{
+					ID:            id,
+					ParticipantID: id,
+					UserID:        fmt.Sprintf("user-%d", j),
+					RoomID:        roomID,
+					Send:          make(chan []byte, 1024),
+					priority:      make(chan []byte, 1024),
+					Hub:           h,
+					codec:         protocol.JSON,
+				}
+				r.clients[client.ParticipantID] = client
+
+				// ice is signaling, so it arrives on the priority queue
+				receivers.Add(1)
+				go func() {
+					defer receivers.Done()
+					for {
+						select {
+						case <-client.priority:
+							onReceive()
+						case _, ok := <-client.Send:
+							if !ok {
+								return
+							}
+							onReceive()
+						}
+					}
+				}()
+			}
+		})
+	}
+
+	// the rooms are subscribed once every shard's I/O worker caught up
+	var subscribed sync.WaitGroup
+	subscribed.Add(len(h.shards))
+	h.eachShard(func(s *shard) { s.async(subscribed.Done) })
+	subscribed.Wait()
+
+	b.Cleanup(func() {
+		close(h.done)
+		h.workers.Wait()
+		broker.Close()
+		for _, s := range h.shards {
+			for _, r := range s.rooms {
+				for _, client := range r.clients {
+					client.closeSend()
+				}
+			}
+		}
+		receivers.Wait()
+	})
+
+	return h
+}
+
+// BenchmarkBroadcastLatency measures the time from publishing a room message
+// to the last of its recipients having it, with 1k rooms x 10 clients.
+func BenchmarkBroadcastLatency(b *testing.B) {
+	var pending sync.WaitGroup
+	h := newBenchHub(b, pending.Done)
+
+	latencies := make([]time.Duration, 0, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pending.Add(benchClientsPerRoom)
+		start := time.Now()
+		h.publish(&BroadcastMessage{
+			RoomID:  fmt.Sprintf("room-%d", i%benchRooms),
+			Message: benchMessage,
+		})
+		pending.Wait()
+		latencies = append(latencies, time.Since(start))
+	}
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)/2].Microseconds()), "p50-µs")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Microseconds()), "p99-µs")
+}
+
+// BenchmarkBroadcastParallel publishes to all rooms at once to show that
+// rooms do not hold each other up.
+func BenchmarkBroadcastParallel(b *testing.B) {
+	var delivered atomic.Int64
+	h := newBenchHub(b, func() { delivered.Add(1) })
+
+	var next atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := next.Add(1)
+			h.publish(&BroadcastMessage{
+				RoomID:  fmt.Sprintf("room-%d", i%benchRooms),
+				Message: benchMessage,
+			})
+		}
+	})
+
+	expected := int64(b.N) * benchClientsPerRoom
+	deadline := time.Now().Add(10 * time.Second)
+	for delivered.Load() < expected && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(delivered.Load())/float64(expected), "delivered")
+}
@@ -0,0 +1,303 @@
+package websocket
+
+import (
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	"bincang-visual/internal/infrastructure/pubsub"
+	redisRepo "bincang-visual/internal/repository/redis"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGracePeriod = 1 // seconds
+
+// testCluster is the Redis the nodes of a test share, with a room in it.
+type testCluster struct {
+	client *redis.Client
+	uc     *usecase.RoomUseCase
+	roomID string
+}
+
+func newTestCluster(t *testing.T) *testCluster {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	// after the hubs, which shut down against it
+	t.Cleanup(func() { client.Close() })
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	room, err := uc.CreateRoom(context.Background(), usecase.CreateRoomInput{Name: "Standup", HostID: "host123", MaxParticipants: 10})
+	require.NoError(t, err)
+
+	return &testCluster{client: client, uc: uc, roomID: room.ID}
+}
+
+// node starts a hub on broker, shut down when the test ends.
+func (tc *testCluster) node(t *testing.T, nodeID string, broker pubsub.Broker, queueSize int) *SignalingHub {
+	t.Helper()
+
+	h := NewSignalingHub(tc.uc, redisRepo.NewPresenceRepository(tc.client), redisRepo.NewSessionRepository(tc.client), broker, config.SignalingConfig{
+		NodeID:            nodeID,
+		Shards:            4,
+		ResumeGracePeriod: testGracePeriod,
+		SendQueueSize:     queueSize,
+		SlowClientTimeout: 5,
+	})
+	h.startShards()
+	go h.consume()
+	t.Cleanup(h.shutdown)
+
+	return h
+}
+
+// redisNode is a node that reaches the others through Redis pub/sub.
+func (tc *testCluster) redisNode(t *testing.T, nodeID string) *SignalingHub {
+	t.Helper()
+
+	broker, err := pubsub.NewRedisBroker(context.Background(), tc.client)
+	require.NoError(t, err)
+	return tc.node(t, nodeID, broker, 64)
+}
+
+// join lets userID into the room on h with a connection that has no socket:
+// what the hub sends it stays in its queues for the test to read.
+func (tc *testCluster) join(t *testing.T, h *SignalingHub, userID string) *Client {
+	t.Helper()
+
+	participant, err := tc.uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+		RoomID:        tc.roomID,
+		ParticipantID: "conn-" + userID,
+		UserID:        userID,
+	})
+	require.NoError(t, err)
+
+	client := &Client{
+		ID:            "client-" + userID,
+		ParticipantID: participant.ID,
+		UserID:        userID,
+		RoomID:        tc.roomID,
+		Send:          make(chan []byte, h.config.SendQueueSize),
+		priority:      make(chan []byte, h.config.SendQueueSize),
+		Hub:           h,
+		codec:         protocol.JSON,
+	}
+	client.ResumeToken = h.createSession(client)
+	return tc.start(t, client)
+}
+
+// start registers the client and waits until it caught up with the room.
+func (tc *testCluster) start(t *testing.T, client *Client) *Client {
+	t.Helper()
+
+	require.True(t, client.Hub.dispatch(client.RoomID, func(s *shard) { s.registerClient(client) }))
+	nextMessage(t, client, "room-state")
+	return client
+}
+
+// nextMessage reads the client's queues until a message of msgType comes.
+func nextMessage(t *testing.T, client *Client, msgType string) entity.SignalMessage {
+	t.Helper()
+
+	timeout := time.After(3 * time.Second)
+	for {
+		var frame []byte
+		select {
+		case frame = <-client.priority:
+		case f, ok := <-client.Send:
+			if !ok {
+				t.Fatalf("%s was closed waiting for %q", client.ParticipantID, msgType)
+			}
+			frame = f
+		case <-timeout:
+			t.Fatalf("%s got no %q", client.ParticipantID, msgType)
+		}
+
+		var msg entity.SignalMessage
+		require.NoError(t, json.Unmarshal(frame, &msg))
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+// noMessage fails when the client gets a message of msgType within d.
+func noMessage(t *testing.T, client *Client, msgType string, d time.Duration) {
+	t.Helper()
+
+	timeout := time.After(d)
+	for {
+		var frame []byte
+		select {
+		case frame = <-client.priority:
+		case f, ok := <-client.Send:
+			if !ok {
+				return
+			}
+			frame = f
+		case <-timeout:
+			return
+		}
+
+		var msg entity.SignalMessage
+		require.NoError(t, json.Unmarshal(frame, &msg))
+		assert.NotEqual(t, msgType, msg.Type, "%s got %q from %s", client.ParticipantID, msgType, msg.From)
+	}
+}
+
+func roomMessage(msgType, from string) []byte {
+	return []byte(fmt.Sprintf(`{"type":%q,"from":%q,"data":{"sdp":"v=0"}}`, msgType, from))
+}
+
+func TestCrossNodeDelivery(t *testing.T) {
+	tc := newTestCluster(t)
+	nodeA := tc.redisNode(t, "node-a")
+	nodeB := tc.redisNode(t, "node-b")
+
+	alice := tc.join(t, nodeA, "alice")
+	bob := tc.join(t, nodeB, "bob")
+
+	// node B announced bob through the broker
+	joined := nextMessage(t, alice, "peer-joined")
+	assert.Equal(t, bob.ParticipantID, joined.From)
+
+	nodeA.publish(&BroadcastMessage{
+		RoomID:  tc.roomID,
+		Message: roomMessage("offer", alice.ParticipantID),
+		To:      bob.ParticipantID,
+	})
+
+	offer := nextMessage(t, bob, "offer")
+	assert.Equal(t, alice.ParticipantID, offer.From)
+}
+
+func TestDeadNodeSweep(t *testing.T) {
+	tc := newTestCluster(t)
+	nodeA := tc.redisNode(t, "node-a")
+	nodeB := tc.redisNode(t, "node-b")
+
+	alice := tc.join(t, nodeA, "alice")
+	bob := tc.join(t, nodeB, "bob")
+	nextMessage(t, alice, "peer-joined")
+
+	// node B never sent a heartbeat, so to node A it is gone
+	nodeA.sweepDeadPresence()
+
+	left := nextMessage(t, alice, "peer-left")
+	assert.Equal(t, bob.ParticipantID, left.From)
+
+	presence, err := nodeA.presenceRepo.GetPresence(context.Background(), tc.roomID)
+	require.NoError(t, err)
+	assert.Contains(t, presence, alice.ParticipantID, "its own clients are left alone")
+	assert.NotContains(t, presence, bob.ParticipantID)
+}
+
+func TestResumeSession(t *testing.T) {
+	t.Run("a client back within the grace period is not announced as gone", func(t *testing.T) {
+		tc := newTestCluster(t)
+		h := tc.node(t, "node-a", pubsub.NewLocalBroker(), 64)
+
+		alice := tc.join(t, h, "alice")
+		bob := tc.join(t, h, "bob")
+		nextMessage(t, alice, "peer-joined")
+
+		h.requestUnregister(bob)
+		nextMessage(t, alice, "peer-reconnecting")
+
+		// what bob misses meanwhile is kept for him
+		h.publish(&BroadcastMessage{
+			RoomID:  tc.roomID,
+			Message: roomMessage("offer", alice.ParticipantID),
+			To:      bob.ParticipantID,
+		})
+		require.Eventually(t, func() bool {
+			buffered, err := tc.client.LLen(context.Background(), fmt.Sprintf("session:%s:buffer", bob.ResumeToken)).Result()
+			return err == nil && buffered > 0
+		}, 3*time.Second, 10*time.Millisecond)
+
+		resumed := h.resumeClient(nil, ConnectParams{
+			RoomID:      tc.roomID,
+			UserID:      bob.UserID,
+			ClientID:    "client-bob-2",
+			ResumeToken: bob.ResumeToken,
+		})
+		require.NotNil(t, resumed)
+		resumed.codec = protocol.JSON
+		require.True(t, h.dispatch(tc.roomID, func(s *shard) { s.registerClient(resumed) }))
+
+		session := nextMessage(t, resumed, "session")
+		assert.Equal(t, true, session.Data["resumed"])
+		replayed := nextMessage(t, resumed, "offer")
+		assert.Equal(t, alice.ParticipantID, replayed.From)
+		nextMessage(t, resumed, "room-state")
+
+		nextMessage(t, alice, "peer-reconnected")
+		noMessage(t, alice, "peer-left", 2*testGracePeriod*time.Second)
+	})
+
+	t.Run("the grace period running out announces the departure", func(t *testing.T) {
+		tc := newTestCluster(t)
+		h := tc.node(t, "node-a", pubsub.NewLocalBroker(), 64)
+
+		alice := tc.join(t, h, "alice")
+		bob := tc.join(t, h, "bob")
+		nextMessage(t, alice, "peer-joined")
+
+		h.requestUnregister(bob)
+		nextMessage(t, alice, "peer-reconnecting")
+
+		left := nextMessage(t, alice, "peer-left")
+		assert.Equal(t, bob.ParticipantID, left.From)
+
+		participants, err := tc.uc.GetParticipants(context.Background(), tc.roomID)
+		require.NoError(t, err)
+		require.Len(t, participants, 1)
+		assert.Equal(t, alice.ParticipantID, participants[0].ID)
+	})
+}
+
+func TestSlowConsumer(t *testing.T) {
+	tc := newTestCluster(t)
+	h := tc.node(t, "node-a", pubsub.NewLocalBroker(), 4)
+
+	alice := tc.join(t, h, "alice")
+	bob := tc.join(t, h, "bob")
+	nextMessage(t, alice, "peer-joined")
+
+	// bob stopped reading, signaling to him piles up
+	for i := 0; i < h.config.SendQueueSize+1; i++ {
+		h.publish(&BroadcastMessage{
+			RoomID:  tc.roomID,
+			Message: roomMessage("ice", alice.ParticipantID),
+			To:      bob.ParticipantID,
+		})
+	}
+
+	require.Eventually(t, func() bool {
+		bob.sendMu.Lock()
+		defer bob.sendMu.Unlock()
+		return bob.closed
+	}, 3*time.Second, 10*time.Millisecond)
+
+	bob.sendMu.Lock()
+	defer bob.sendMu.Unlock()
+	assert.Equal(t, CloseSlowConsumer, bob.closeCode)
+	assert.Equal(t, 4008, bob.closeCode)
+}
@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	commandAdmit = "admit"
+	commandDeny  = "deny"
+)
+
+// parkClient keeps a client that is waiting for admission on this node. It
+// gets no room traffic until a host admits it.
+func (s *shard) parkClient(client *Client) {
+	r := s.room(client.RoomID)
+	previous := r.lobby[client.ParticipantID]
+	r.lobby[client.ParticipantID] = client
+
+	if previous != nil && previous != client {
+		previous.closeSend()
+	}
+
+	log.Printf("[Hub] Client %s waiting in lobby of room %s", client.ParticipantID, client.RoomID)
+
+	client.sendMessage(entity.SignalMessage{
+		Type:      "waiting",
+		From:      "server",
+		RoomID:    client.RoomID,
+		Timestamp: time.Now(),
+	})
+
+	h := s.hub
+	s.async(func() { h.publishKnock(client) })
+}
+
+func (s *shard) unparkClient(client *Client) {
+	r := s.rooms[client.RoomID]
+	parked := r != nil && r.lobby[client.ParticipantID] == client
+	if parked {
+		delete(r.lobby, client.ParticipantID)
+		s.release(r)
+	}
+
+	client.closeSend()
+
+	if !parked {
+		return
+	}
+
+	h := s.hub
+	s.async(func() { h.leaveLobby(client) })
+}
+
+func (h *SignalingHub) leaveLobby(client *Client) {
+	ctx := context.Background()
+
+	// false when a host already denied or admitted them
+	removed, err := h.roomUseCase.LeaveLobby(ctx, client.RoomID, client.ParticipantID)
+	if err != nil {
+		log.Printf("[Hub] Failed to remove %s from lobby: %v", client.ParticipantID, err)
+		return
+	}
+	if removed {
+		h.publishKnockResolved(client.RoomID, client.ParticipantID, "left")
+		return
+	}
+
+	// admitted as they dropped: the admit command finds nobody to register
+	// here, so nothing else would ever take them out of the room
+	if _, err := h.roomUseCase.GetParticipant(ctx, client.RoomID, client.ParticipantID); err == nil {
+		log.Printf("[Hub] %s left room %s while being admitted", client.ParticipantID, client.RoomID)
+		h.handleDeparture(client.RoomID, client.ParticipantID, client.UserID)
+	}
+}
+
+func (s *shard) admitClient(roomID, participantID string) {
+	r := s.rooms[roomID]
+	if r == nil || r.lobby[participantID] == nil {
+		return
+	}
+	client := r.lobby[participantID]
+
+	client.waiting.Store(false)
+	client.sendMessage(entity.SignalMessage{
+		Type:      "admitted",
+		From:      "server",
+		RoomID:    roomID,
+		Timestamp: time.Now(),
+	})
+
+	s.registerClient(client)
+}
+
+func (s *shard) denyClient(roomID, participantID string) {
+	r := s.rooms[roomID]
+	if r == nil || r.lobby[participantID] == nil {
+		return
+	}
+	client := r.lobby[participantID]
+
+	delete(r.lobby, participantID)
+	s.release(r)
+
+	client.sendMessage(entity.SignalMessage{
+		Type:      "denied",
+		From:      "server",
+		RoomID:    roomID,
+		Timestamp: time.Now(),
+	})
+
+	// the write pump flushes the denial, then closes the socket
+	client.closeSend()
+}
+
+func (h *SignalingHub) publishKnock(client *Client) {
+	data, _ := json.Marshal(knockMessage(client.RoomID, &entity.Participant{
+		ID:          client.ParticipantID,
+		UserID:      client.UserID,
+		DisplayName: client.DisplayName,
+		IsGuest:     client.IsGuest,
+		Device:      client.Device,
+	}))
+	h.publish(&BroadcastMessage{
+		RoomID:    client.RoomID,
+		Message:   data,
+		HostsOnly: true,
+	})
+}
+
+// publishKnockResolved lets every host drop the request from their lobby list.
+func (h *SignalingHub) publishKnockResolved(roomID, participantID, status string) {
+	notification := entity.SignalMessage{
+		Type:   "knock-resolved",
+		From:   "server",
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": participantID,
+			"status":        status,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:    roomID,
+		Message:   data,
+		HostsOnly: true,
+	})
+}
+
+func knockMessage(roomID string, p *entity.Participant) entity.SignalMessage {
+	return entity.SignalMessage{
+		Type:   "knock",
+		From:   p.ID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": p.ID,
+			"userId":        p.UserID,
+			"displayName":   p.DisplayName,
+			"device":        p.Device,
+			"isGuest":       p.IsGuest,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+// handleLobbyDecision handles "admit"/"deny" from a host.
+func (c *Client) handleLobbyDecision(req *protocol.Request, payload *protocol.LobbyDecision) {
+	participantIDs := payload.Targets()
+
+	var decided []*entity.Participant
+	var err error
+	command, status := commandAdmit, "admitted"
+	if req.Type == protocol.TypeAdmit {
+		decided, err = c.Hub.roomUseCase.AdmitParticipants(context.Background(), c.RoomID, c.ParticipantID, participantIDs)
+	} else {
+		command, status = commandDeny, "denied"
+		decided, err = c.Hub.roomUseCase.DenyParticipants(context.Background(), c.RoomID, c.ParticipantID, participantIDs)
+	}
+
+	// apply whatever was decided even if the batch stopped early
+	for _, p := range decided {
+		c.Hub.publishCommand(c.RoomID, p.ID, command)
+		c.Hub.publishKnockResolved(c.RoomID, p.ID, status)
+	}
+
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
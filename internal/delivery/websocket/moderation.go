@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	commandRemove = "remove"
+	commandBan    = "ban" // addressed to a user, all of their devices go
+)
+
+// handleModeration handles host actions on another participant. The host
+// check happens in the use case, never on the client's word.
+func (c *Client) handleModeration(req *protocol.Request, payload *protocol.Target) {
+	ctx := context.Background()
+	targetID := payload.ParticipantID
+
+	var err error
+	switch req.Type {
+	case protocol.TypeMuteParticipant:
+		var target *entity.Participant
+		if target, err = c.Hub.roomUseCase.MuteParticipant(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.notifyModerated(c.RoomID, c.ParticipantID, target, "muted-by-host")
+		}
+
+	case protocol.TypeStopVideo:
+		var target *entity.Participant
+		if target, err = c.Hub.roomUseCase.StopParticipantVideo(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.notifyModerated(c.RoomID, c.ParticipantID, target, "video-stopped-by-host")
+		}
+
+	case protocol.TypeRemoveParticipant:
+		if err = c.Hub.roomUseCase.RemoveParticipant(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.publishCommand(c.RoomID, targetID, commandRemove)
+		}
+
+	case protocol.TypeBanParticipant:
+		var userID string
+		if userID, err = c.Hub.roomUseCase.BanParticipant(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.publishCommand(c.RoomID, userID, commandBan)
+		}
+	}
+
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
+
+// notifyModerated tells the target to apply the change locally and updates
+// everyone else's view of the target's media state.
+func (h *SignalingHub) notifyModerated(roomID, hostID string, target *entity.Participant, notice string) {
+	instruction := entity.SignalMessage{
+		Type:   notice,
+		From:   hostID,
+		To:     target.ID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": target.ID,
+		},
+		Timestamp: time.Now(),
+	}
+	data, _ := json.Marshal(instruction)
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
+		Message: data,
+		To:      target.ID,
+	})
+
+	state := entity.SignalMessage{
+		Type:   "media-state",
+		From:   target.ID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"isMuted":    target.IsMuted,
+			"isVideoOff": target.IsVideoOff,
+		},
+		Version:   h.nextVersion(roomID),
+		Timestamp: time.Now(),
+	}
+	data, _ = json.Marshal(state)
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
+		Message: data,
+		Exclude: target.ID,
+	})
+}
+
+// kickClient closes a local connection after a host removed or banned it.
+// The regular unregister path then announces peer-left.
+func (s *shard) kickClient(roomID, participantID string, code int, reason string) {
+	r := s.rooms[roomID]
+	if r == nil {
+		return
+	}
+
+	client := r.clients[participantID]
+	if client == nil {
+		client = r.lobby[participantID]
+	}
+	if client == nil {
+		return
+	}
+
+	kick(client, code, reason)
+}
+
+// kickUser closes every local connection of userID in the room.
+func (s *shard) kickUser(roomID, userID string, code int, reason string) {
+	r := s.rooms[roomID]
+	if r == nil {
+		return
+	}
+
+	for _, clients := range []map[string]*Client{r.clients, r.lobby} {
+		for _, client := range clients {
+			if client.UserID == userID {
+				kick(client, code, reason)
+			}
+		}
+	}
+}
+
+func kick(client *Client, code int, reason string) {
+	log.Printf("[Hub] Closing %s in room %s: %s", client.ParticipantID, client.RoomID, reason)
+
+	client.leaving.Store(true)
+	client.sendMessage(entity.SignalMessage{
+		Type:   "removed",
+		From:   "server",
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"reason": reason,
+		},
+		Timestamp: time.Now(),
+	})
+	client.closeWithCode(code, reason)
+}
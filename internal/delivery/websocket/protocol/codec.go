@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Subprotocols select the wire encoding of a connection.
+const (
+	SubprotocolJSON    = "signal.json"
+	SubprotocolMsgpack = "signal.msgpack"
+)
+
+// Codec encodes and decodes frames of one connection. Messages travel
+// between nodes as JSON and are re-encoded for each recipient.
+type Codec interface {
+	Name() string
+	// Binary reports whether frames go out as binary WebSocket messages.
+	Binary() bool
+	Decode(raw []byte) (*Request, *Error)
+	// FromJSON re-encodes a JSON message in this codec.
+	FromJSON(data []byte) ([]byte, error)
+}
+
+var (
+	JSON    Codec = jsonCodec{}
+	Msgpack Codec = msgpackCodec{}
+)
+
+// CodecFor returns the codec for a negotiated subprotocol, JSON by default.
+func CodecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return Msgpack
+	}
+	return JSON
+}
+
+// ============= JSON =============
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return SubprotocolJSON }
+
+func (jsonCodec) Binary() bool { return false }
+
+func (jsonCodec) Decode(raw []byte) (*Request, *Error) {
+	return Decode(raw)
+}
+
+func (jsonCodec) FromJSON(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// ============= MESSAGEPACK =============
+
+// msgpackCodec uses the same field names as JSON, so both encodings share
+// one message model.
+type msgpackCodec struct{}
+
+type rawMsgpackRequest struct {
+	ID   string             `msgpack:"id,omitempty"`
+	Type string             `msgpack:"type"`
+	To   string             `msgpack:"to,omitempty"`
+	Data msgpack.RawMessage `msgpack:"data,omitempty"`
+}
+
+func (msgpackCodec) Name() string { return SubprotocolMsgpack }
+
+func (msgpackCodec) Binary() bool { return true }
+
+func (msgpackCodec) Decode(raw []byte) (*Request, *Error) {
+	var req rawMsgpackRequest
+	if err := msgpack.Unmarshal(raw, &req); err != nil {
+		return nil, NewError(CodeInvalidMessage, "message is not valid MessagePack")
+	}
+
+	// 0xc0 is nil
+	hasData := len(req.Data) > 0 && !bytes.Equal(req.Data, []byte{0xc0})
+	return newRequest(req.ID, req.Type, req.To, hasData, func(payload Payload) error {
+		dec := msgpack.NewDecoder(bytes.NewReader(req.Data))
+		dec.SetCustomStructTag("json")
+		dec.DisallowUnknownFields(true)
+		return dec.Decode(payload)
+	})
+}
+
+func (msgpackCodec) FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	return msgpack.Marshal(normalizeNumbers(v))
+}
+
+// normalizeNumbers turns json.Number into int64 where possible so integers
+// stay integers in MessagePack.
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = normalizeNumbers(item)
+		}
+		return t
+	case []interface{}:
+		for i, item := range t {
+			t[i] = normalizeNumbers(item)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,260 @@
+package protocol
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Client message types.
+const (
+	TypeOffer             = "offer"
+	TypeAnswer            = "answer"
+	TypeICE               = "ice"
+	TypePing              = "ping"
+	TypeChat              = "chat"
+	TypeMediaState        = "media-state"
+	TypeScreenShare       = "screen-share"
+	TypeLeave             = "leave"
+	TypeAdmit             = "admit"
+	TypeDeny              = "deny"
+	TypeMuteParticipant   = "mute-participant"
+	TypeStopVideo         = "stop-video"
+	TypeRemoveParticipant = "remove-participant"
+	TypeBanParticipant    = "ban-participant"
+	TypeListDevices       = "list-devices"
+	TypeDisconnectDevice  = "disconnect-device"
+	TypeTransferHere      = "transfer-here"
+	TypeSync              = "sync"
+	TypeGrantScreenShare  = "grant-screen-share"
+	TypeDenyScreenShare   = "deny-screen-share"
+	TypeStopPresenter     = "stop-presenter"
+	TypeTakeOverScreen    = "take-over-screen"
+	TypeUpdateRoom        = "update-room"
+	TypeExtendMeeting     = "extend-meeting"
+	TypeEndMeeting        = "end-meeting"
+	TypeLockRoom          = "lock-room"
+)
+
+// TypeError is the server reply to a request that could not be handled.
+const TypeError = "error"
+
+// TypePong is the server reply to a ping.
+const TypePong = "pong"
+
+// IsBulk reports whether a message may be dropped when a connection can't
+// keep up. Everything else, signaling above all, is critical.
+func IsBulk(msgType string) bool {
+	switch msgType {
+	case TypeChat, TypePong:
+		return true
+	default:
+		return false
+	}
+}
+
+const maxChatLength = 2000
+
+// Payload is the typed data of a client message.
+type Payload interface {
+	Validate() error
+}
+
+var payloads = map[string]func() Payload{
+	TypeOffer:             func() Payload { return &SessionDescription{kind: TypeOffer} },
+	TypeAnswer:            func() Payload { return &SessionDescription{kind: TypeAnswer} },
+	TypeICE:               func() Payload { return &ICECandidate{} },
+	TypePing:              func() Payload { return &Empty{} },
+	TypeChat:              func() Payload { return &Chat{} },
+	TypeMediaState:        func() Payload { return &MediaState{} },
+	TypeScreenShare:       func() Payload { return &ScreenShare{} },
+	TypeLeave:             func() Payload { return &Empty{} },
+	TypeAdmit:             func() Payload { return &LobbyDecision{} },
+	TypeDeny:              func() Payload { return &LobbyDecision{} },
+	TypeMuteParticipant:   func() Payload { return &Target{} },
+	TypeStopVideo:         func() Payload { return &Target{} },
+	TypeRemoveParticipant: func() Payload { return &Target{} },
+	TypeBanParticipant:    func() Payload { return &Target{} },
+	TypeListDevices:       func() Payload { return &Empty{} },
+	TypeDisconnectDevice:  func() Payload { return &Target{} },
+	TypeTransferHere:      func() Payload { return &Empty{} },
+	TypeSync:              func() Payload { return &Empty{} },
+	TypeGrantScreenShare:  func() Payload { return &Target{} },
+	TypeDenyScreenShare:   func() Payload { return &Target{} },
+	TypeStopPresenter:     func() Payload { return &Target{} },
+	TypeTakeOverScreen:    func() Payload { return &Empty{} },
+	TypeUpdateRoom:        func() Payload { return &RoomUpdate{} },
+	TypeExtendMeeting:     func() Payload { return &Extension{} },
+	TypeEndMeeting:        func() Payload { return &Empty{} },
+	TypeLockRoom:          func() Payload { return &RoomLock{} },
+}
+
+// Empty is the payload of messages that carry no data.
+type Empty struct{}
+
+func (p *Empty) Validate() error { return nil }
+
+// SessionDescription carries an SDP offer or answer.
+type SessionDescription struct {
+	SDP  string `json:"sdp"`
+	Type string `json:"type,omitempty"`
+	kind string
+}
+
+func (p *SessionDescription) Validate() error {
+	if strings.TrimSpace(p.SDP) == "" {
+		return errors.New("sdp is required")
+	}
+	if p.Type != "" && p.Type != p.kind {
+		return errors.New("type must be " + p.kind)
+	}
+	return nil
+}
+
+// ICECandidate mirrors RTCIceCandidateInit. An empty candidate signals the
+// end of candidates.
+type ICECandidate struct {
+	Candidate        string  `json:"candidate"`
+	SDPMid           *string `json:"sdpMid,omitempty"`
+	SDPMLineIndex    *int    `json:"sdpMLineIndex,omitempty"`
+	UsernameFragment string  `json:"usernameFragment,omitempty"`
+}
+
+func (p *ICECandidate) Validate() error {
+	if p.Candidate != "" && p.SDPMid == nil && p.SDPMLineIndex == nil {
+		return errors.New("sdpMid or sdpMLineIndex is required")
+	}
+	if p.SDPMLineIndex != nil && *p.SDPMLineIndex < 0 {
+		return errors.New("sdpMLineIndex must not be negative")
+	}
+	return nil
+}
+
+type Chat struct {
+	Message string `json:"message"`
+}
+
+func (p *Chat) Validate() error {
+	if strings.TrimSpace(p.Message) == "" {
+		return errors.New("message is required")
+	}
+	if utf8.RuneCountInString(p.Message) > maxChatLength {
+		return errors.New("message is too long")
+	}
+	return nil
+}
+
+type MediaState struct {
+	IsMuted    *bool `json:"isMuted,omitempty"`
+	IsVideoOff *bool `json:"isVideoOff,omitempty"`
+}
+
+func (p *MediaState) Validate() error {
+	if p.IsMuted == nil && p.IsVideoOff == nil {
+		return errors.New("isMuted or isVideoOff is required")
+	}
+	return nil
+}
+
+type ScreenShare struct {
+	IsSharing *bool `json:"isSharing"`
+}
+
+func (p *ScreenShare) Validate() error {
+	if p.IsSharing == nil {
+		return errors.New("isSharing is required")
+	}
+	return nil
+}
+
+// LobbyDecision names the waiting participants a host admits or denies.
+type LobbyDecision struct {
+	ParticipantID  string   `json:"participantId,omitempty"`
+	ParticipantIDs []string `json:"participantIds,omitempty"`
+	All            bool     `json:"all,omitempty"`
+}
+
+func (p *LobbyDecision) Validate() error {
+	if p.All {
+		return nil
+	}
+	for _, id := range p.ParticipantIDs {
+		if id == "" {
+			return errors.New("participantIds must not contain empty ids")
+		}
+	}
+	if p.ParticipantID == "" && len(p.ParticipantIDs) == 0 {
+		return errors.New("participantId, participantIds or all is required")
+	}
+	return nil
+}
+
+// Targets returns the participant ids the decision applies to, nil meaning
+// everyone.
+func (p *LobbyDecision) Targets() []string {
+	if p.All {
+		return nil
+	}
+	var ids []string
+	if p.ParticipantID != "" {
+		ids = append(ids, p.ParticipantID)
+	}
+	return append(ids, p.ParticipantIDs...)
+}
+
+// Target names the participant, that is the connection, an action applies to.
+type Target struct {
+	ParticipantID string `json:"participantId"`
+}
+
+func (p *Target) Validate() error {
+	if p.ParticipantID == "" {
+		return errors.New("participantId is required")
+	}
+	return nil
+}
+
+// RoomUpdate changes the room the sender hosts. Version is the room version
+// the change is based on; fields left out stay as they are.
+type RoomUpdate struct {
+	Version         *int64                 `json:"version"`
+	Name            *string                `json:"name,omitempty"`
+	MaxParticipants *int                   `json:"maxParticipants,omitempty"`
+	Settings        *entity.RoomSettings   `json:"settings,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (p *RoomUpdate) Validate() error {
+	if p.Version == nil {
+		return errors.New("version is required")
+	}
+	if p.Name == nil && p.MaxParticipants == nil && p.Settings == nil && len(p.Metadata) == 0 {
+		return errors.New("name, maxParticipants, settings or metadata is required")
+	}
+	return nil
+}
+
+// Extension gives a meeting with a time limit more time.
+type Extension struct {
+	Minutes int `json:"minutes"`
+}
+
+func (p *Extension) Validate() error {
+	if p.Minutes <= 0 {
+		return errors.New("minutes must be positive")
+	}
+	return nil
+}
+
+// RoomLock locks the room against new joins, or opens it again.
+type RoomLock struct {
+	Locked *bool `json:"locked"`
+}
+
+func (p *RoomLock) Validate() error {
+	if p.Locked == nil {
+		return errors.New("locked is required")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package protocol defines the signaling wire format spoken over /ws: the
+// message envelope, typed payloads per message type, validation and the
+// structured errors sent back to clients.
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the newest protocol version this server speaks.
+const Version = 1
+
+// MaxMessageSize caps a single client frame; SDPs are the largest messages.
+const MaxMessageSize = 64 * 1024
+
+var supportedVersions = []int{1}
+
+// Error codes sent in "error" messages.
+const (
+	CodeInvalidMessage     = "invalid_message"
+	CodeUnknownType        = "unknown_type"
+	CodeInvalidPayload     = "invalid_payload"
+	CodeUnsupportedVersion = "unsupported_version"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeRoomFull           = "room_full"
+	CodeRateLimited        = "rate_limited"
+	CodeInternal           = "internal"
+)
+
+// Error is a protocol level failure reported back to the client.
+type Error struct {
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	// Details says more about why, e.g. which room setting refused it.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Fields returns the error as message data.
+func (e *Error) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if e.CorrelationID != "" {
+		fields["correlationId"] = e.CorrelationID
+	}
+	if len(e.Details) > 0 {
+		fields["details"] = e.Details
+	}
+	return fields
+}
+
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Envelope is the shape of every message on the wire. Clients set ID to
+// correlate error replies with their request.
+type Envelope struct {
+	ID        string      `json:"id,omitempty"`
+	Type      string      `json:"type"`
+	From      string      `json:"from,omitempty"`
+	To        string      `json:"to,omitempty"`
+	RoomID    string      `json:"roomId,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Version   int64       `json:"version,omitempty"` // room state version, set on state changes
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Request is a decoded and validated client message.
+type Request struct {
+	ID      string
+	Type    string
+	To      string
+	Payload Payload
+}
+
+type rawRequest struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	To   string          `json:"to,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode parses a JSON client frame into its typed payload and validates it.
+// Unknown message types and unknown payload fields are rejected. The returned
+// *Error carries the request's ID when it could be read.
+func Decode(raw []byte) (*Request, *Error) {
+	var req rawRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, NewError(CodeInvalidMessage, "message is not valid JSON")
+	}
+
+	hasData := len(req.Data) > 0 && !bytes.Equal(req.Data, []byte("null"))
+	return newRequest(req.ID, req.Type, req.To, hasData, func(payload Payload) error {
+		dec := json.NewDecoder(bytes.NewReader(req.Data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(payload)
+	})
+}
+
+// newRequest resolves the payload type, decodes the data into it with
+// decodeData and validates the result.
+func newRequest(id, msgType, to string, hasData bool, decodeData func(Payload) error) (*Request, *Error) {
+	fail := func(code, message string) (*Request, *Error) {
+		return nil, &Error{Code: code, Message: message, CorrelationID: id}
+	}
+
+	if msgType == "" {
+		return fail(CodeInvalidMessage, "type is required")
+	}
+
+	newPayload, ok := payloads[msgType]
+	if !ok {
+		return fail(CodeUnknownType, fmt.Sprintf("unknown message type '%s'", msgType))
+	}
+
+	payload := newPayload()
+	if hasData {
+		if err := decodeData(payload); err != nil {
+			return fail(CodeInvalidPayload, fmt.Sprintf("invalid %s data: %v", msgType, err))
+		}
+	}
+
+	if err := payload.Validate(); err != nil {
+		return fail(CodeInvalidPayload, fmt.Sprintf("invalid %s data: %v", msgType, err))
+	}
+
+	return &Request{
+		ID:      id,
+		Type:    msgType,
+		To:      to,
+		Payload: payload,
+	}, nil
+}
+
+// Negotiate picks the newest version both sides speak from a comma separated
+// list sent by the client. Clients that send nothing get version 1.
+func Negotiate(requested string) (int, error) {
+	if strings.TrimSpace(requested) == "" {
+		return 1, nil
+	}
+
+	offered := make(map[int]bool)
+	for _, v := range strings.Split(requested, ",") {
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("invalid protocol version '%s'", v)
+		}
+		offered[version] = true
+	}
+
+	versions := append([]int(nil), supportedVersions...)
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	for _, version := range versions {
+		if offered[version] {
+			return version, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no supported protocol version in '%s', server speaks %v", requested, supportedVersions)
+}
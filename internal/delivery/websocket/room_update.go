@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// handleUpdateRoom lets the host change the room from the call. The use case
+// announces the change, just as for an update over HTTP.
+func (c *Client) handleUpdateRoom(req *protocol.Request, payload *protocol.RoomUpdate) {
+	_, err := c.Hub.roomUseCase.UpdateRoom(context.Background(), usecase.UpdateRoomInput{
+		RoomID:          c.RoomID,
+		UserID:          c.UserID,
+		Version:         *payload.Version,
+		Name:            payload.Name,
+		MaxParticipants: payload.MaxParticipants,
+		Settings:        payload.Settings,
+		Metadata:        payload.Metadata,
+	})
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
+
+// RoomUpdated tells everyone in the room, on every node, about the room's
+// new name, limits or settings.
+func (h *SignalingHub) RoomUpdated(room *entity.Room) {
+	notification := entity.SignalMessage{
+		Type:   "room-updated",
+		From:   "server",
+		RoomID: room.ID,
+		Data: map[string]interface{}{
+			"room": room,
+		},
+		Version:   h.nextVersion(room.ID),
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  room.ID,
+		Message: data,
+	})
+}
+
+// handleLockRoom lets the host lock or unlock the room. The change reaches
+// everyone as room-updated.
+func (c *Client) handleLockRoom(req *protocol.Request, payload *protocol.RoomLock) {
+	if _, err := c.Hub.roomUseCase.SetRoomLocked(context.Background(), c.RoomID, c.UserID, *payload.Locked); err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+// handleScreenShare starts or stops the client's own screen share. Whether
+// it may present, or has to wait for a host, is up to the room's policy.
+func (c *Client) handleScreenShare(req *protocol.Request, payload *protocol.ScreenShare) {
+	ctx := context.Background()
+
+	if *payload.IsSharing {
+		pending, err := c.Hub.roomUseCase.StartScreenShare(ctx, c.RoomID, c.ParticipantID)
+		if err != nil {
+			log.Printf("[WebSocket] Screen share by %s failed: %v", c.UserID, err)
+			c.replyError(req, err)
+			return
+		}
+		if pending {
+			c.sendMessage(entity.SignalMessage{
+				Type:      "screen-share-pending",
+				From:      "server",
+				RoomID:    c.RoomID,
+				Timestamp: time.Now(),
+			})
+			c.Hub.publishShareRequest(c)
+			return
+		}
+	} else {
+		err := c.Hub.roomUseCase.StopScreenShare(ctx, c.RoomID, c.ParticipantID)
+		if errors.Is(err, usecase.ErrNotScreenSharer) {
+			// stopping before a host answered withdraws the request
+			if cancelled, _ := c.Hub.roomUseCase.CancelScreenShareRequest(ctx, c.RoomID, c.ParticipantID); cancelled {
+				c.Hub.publishShareRequestResolved(c.RoomID, c.ParticipantID, "cancelled")
+				return
+			}
+		}
+		if err != nil {
+			log.Printf("[WebSocket] Screen share by %s failed: %v", c.UserID, err)
+			c.replyError(req, err)
+			return
+		}
+	}
+
+	req.To = ""
+	c.relay(req, c.ParticipantID, c.Hub.nextVersion(c.RoomID))
+}
+
+// handleShareDecision handles a host granting or denying a pending request,
+// or stopping someone else's screen share.
+func (c *Client) handleShareDecision(req *protocol.Request, payload *protocol.Target) {
+	ctx := context.Background()
+	targetID := payload.ParticipantID
+
+	var err error
+	switch req.Type {
+	case protocol.TypeGrantScreenShare:
+		if err = c.Hub.roomUseCase.GrantScreenShare(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.publishShareRequestResolved(c.RoomID, targetID, "granted")
+			c.Hub.publishShareNotice(c.RoomID, c.ParticipantID, targetID, "screen-share-granted")
+			c.Hub.publishScreenShare(c.RoomID, targetID, true)
+		}
+
+	case protocol.TypeDenyScreenShare:
+		if err = c.Hub.roomUseCase.DenyScreenShare(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.publishShareRequestResolved(c.RoomID, targetID, "denied")
+			c.Hub.publishShareNotice(c.RoomID, c.ParticipantID, targetID, "screen-share-denied")
+		}
+
+	case protocol.TypeStopPresenter:
+		if err = c.Hub.roomUseCase.StopPresenter(ctx, c.RoomID, c.ParticipantID, targetID); err == nil {
+			c.Hub.stopPresenter(c.RoomID, c.ParticipantID, targetID)
+		}
+	}
+
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+	}
+}
+
+// handleTakeOverScreen makes the host the only presenter.
+func (c *Client) handleTakeOverScreen(req *protocol.Request) {
+	stopped, err := c.Hub.roomUseCase.TakeOverScreenShare(context.Background(), c.RoomID, c.ParticipantID)
+
+	// announce whoever was already stopped even if the take-over failed
+	for _, presenter := range stopped {
+		c.Hub.stopPresenter(c.RoomID, c.ParticipantID, presenter)
+	}
+
+	if err != nil {
+		log.Printf("[WebSocket] %s by %s failed: %v", req.Type, c.UserID, err)
+		c.replyError(req, err)
+		return
+	}
+
+	c.Hub.publishScreenShare(c.RoomID, c.ParticipantID, true)
+}
+
+// stopPresenter tells a presenter a host ended their screen share, and
+// everyone else that it ended.
+func (h *SignalingHub) stopPresenter(roomID, hostID, presenterID string) {
+	h.publishShareNotice(roomID, hostID, presenterID, "screen-share-stopped-by-host")
+	h.publishScreenShare(roomID, presenterID, false)
+}
+
+// publishScreenShare announces a screen share the presenter didn't start or
+// stop itself, in the same shape as a relayed screen-share message.
+func (h *SignalingHub) publishScreenShare(roomID, presenterID string, sharing bool) {
+	notification := entity.SignalMessage{
+		Type:   protocol.TypeScreenShare,
+		From:   presenterID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"isSharing": sharing,
+		},
+		Version:   h.nextVersion(roomID),
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
+		Message: data,
+	})
+}
+
+// publishShareNotice tells one participant what a host decided about their
+// screen share.
+func (h *SignalingHub) publishShareNotice(roomID, hostID, targetID, notice string) {
+	notification := entity.SignalMessage{
+		Type:   notice,
+		From:   hostID,
+		To:     targetID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": targetID,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
+		Message: data,
+		To:      targetID,
+	})
+}
+
+// publishShareRequest asks every host to grant or deny a screen share.
+func (h *SignalingHub) publishShareRequest(client *Client) {
+	notification := entity.SignalMessage{
+		Type:   "screen-share-requested",
+		From:   "server",
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"participantId": client.ParticipantID,
+			"userId":        client.UserID,
+			"displayName":   client.DisplayName,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:    client.RoomID,
+		Message:   data,
+		HostsOnly: true,
+	})
+}
+
+// publishShareRequestResolved lets every host drop the request from their
+// queue.
+func (h *SignalingHub) publishShareRequestResolved(roomID, participantID, status string) {
+	notification := entity.SignalMessage{
+		Type:   "screen-share-request-resolved",
+		From:   "server",
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": participantID,
+			"status":        status,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:    roomID,
+		Message:   data,
+		HostsOnly: true,
+	})
+}
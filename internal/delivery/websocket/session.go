@@ -0,0 +1,376 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+const (
+	commandResumed = "resumed"
+
+	// messages kept per dropped client; older ones are discarded
+	resumeBufferSize = 256
+)
+
+// suspendedClient is a participant whose connection dropped on this node. It
+// keeps its place in the room until the grace period runs out.
+type suspendedClient struct {
+	roomID        string
+	participantID string
+	userID        string
+	isHost        bool
+	token         string
+	presence      entity.Presence // the disconnected entry written for it
+	timer         *time.Timer
+}
+
+func (h *SignalingHub) gracePeriod() time.Duration {
+	return time.Duration(h.config.ResumeGracePeriod) * time.Second
+}
+
+// createSession issues the resume token a client presents to pick up where it
+// left off after a dropped connection.
+func (h *SignalingHub) createSession(client *Client) string {
+	if h.config.ResumeGracePeriod <= 0 {
+		return ""
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("[Hub] Failed to generate resume token: %v", err)
+		return ""
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	session := &entity.Session{
+		Token:         token,
+		RoomID:        client.RoomID,
+		ParticipantID: client.ParticipantID,
+		UserID:        client.UserID,
+		DisplayName:   client.DisplayName,
+		IsGuest:       client.IsGuest,
+	}
+	if err := h.sessionRepo.CreateSession(context.Background(), session); err != nil {
+		log.Printf("[Hub] Failed to create session for %s: %v", client.UserID, err)
+		return ""
+	}
+
+	return token
+}
+
+func (h *SignalingHub) endSession(token string) {
+	if token == "" {
+		return
+	}
+	if err := h.sessionRepo.DeleteSession(context.Background(), token); err != nil {
+		log.Printf("[Hub] Failed to delete session: %v", err)
+	}
+}
+
+// resumeClient takes over the participant's place in the room when the token
+// is valid and the participant hasn't been reaped yet. It returns nil when the
+// caller should join from scratch.
+func (h *SignalingHub) resumeClient(c *websocket.Conn, params ConnectParams) *Client {
+	ctx := context.Background()
+
+	session, err := h.sessionRepo.GetSession(ctx, params.ResumeToken)
+	if err != nil || session.RoomID != params.RoomID {
+		return nil
+	}
+
+	// a signed-in user can only resume their own session
+	if !session.IsGuest && (params.IsGuest || params.UserID != session.UserID) {
+		return nil
+	}
+
+	presence, err := h.presenceRepo.GetPresence(ctx, session.RoomID)
+	if err != nil {
+		log.Printf("[Hub] Failed to get presence for room %s: %v", session.RoomID, err)
+		return nil
+	}
+	current, exists := presence[session.ParticipantID]
+	if !exists {
+		return nil
+	}
+
+	// claiming the entry is what makes the resume win over the grace timer
+	next := entity.Presence{NodeID: h.nodeID, ClientID: params.ClientID}
+	claimed, err := h.presenceRepo.ReplacePresence(ctx, session.RoomID, session.ParticipantID, current, next)
+	if err != nil || !claimed {
+		return nil
+	}
+
+	participant, err := h.roomUseCase.GetParticipant(ctx, session.RoomID, session.ParticipantID)
+	if err != nil {
+		_, _ = h.presenceRepo.RemovePresence(ctx, session.RoomID, session.ParticipantID, next)
+		return nil
+	}
+
+	log.Printf("[WebSocket] Resuming session of %s in room %s", session.ParticipantID, session.RoomID)
+
+	return &Client{
+		ID:            params.ClientID,
+		ParticipantID: session.ParticipantID,
+		UserID:        session.UserID,
+		RoomID:        session.RoomID,
+		DisplayName:   session.DisplayName,
+		Device:        participant.Device,
+		IsGuest:       session.IsGuest,
+		IsHost:        participant.IsHost,
+		Conn:          c,
+		Send:          make(chan []byte, h.config.SendQueueSize),
+		priority:      make(chan []byte, h.config.SendQueueSize),
+		Hub:           h,
+		ResumeToken:   session.Token,
+		resumed:       true,
+	}
+}
+
+// suspendClient keeps a dropped client's place in the room for the grace
+// period instead of announcing its departure. It reports false when the
+// client should leave right away.
+func (s *shard) suspendClient(client *Client) bool {
+	h := s.hub
+	if h.config.ResumeGracePeriod <= 0 || client.ResumeToken == "" {
+		return false
+	}
+
+	r := s.rooms[client.RoomID]
+	if r == nil || r.clients[client.ParticipantID] != client {
+		return false
+	}
+
+	grace := h.gracePeriod()
+	live := entity.Presence{NodeID: h.nodeID, ClientID: client.ID}
+	dropped := entity.Presence{
+		NodeID:       h.nodeID,
+		ClientID:     client.ID,
+		Disconnected: true,
+		ResumeBy:     time.Now().Add(grace).UnixMilli(),
+	}
+
+	sc := &suspendedClient{
+		roomID:        client.RoomID,
+		participantID: client.ParticipantID,
+		userID:        client.UserID,
+		isHost:        client.IsHost,
+		token:         client.ResumeToken,
+		presence:      dropped,
+	}
+	sc.timer = time.AfterFunc(grace, func() {
+		h.dispatch(sc.roomID, func(s *shard) { s.expireSuspended(sc) })
+	})
+
+	delete(r.clients, client.ParticipantID)
+	r.suspended[client.ParticipantID] = sc
+
+	log.Printf("[Hub] Client %s dropped from room %s, holding for %s", client.ParticipantID, client.RoomID, grace)
+
+	s.async(func() { h.markSuspended(client, sc, live) })
+
+	// room traffic it never got because it was still catching up
+	for _, msg := range client.backlog {
+		s.async(func() { h.bufferMessage(sc.token, msg.Message) })
+	}
+	client.backlog = nil
+	return true
+}
+
+// markSuspended is the I/O of a suspend. When the presence entry can't be
+// marked as dropped the client leaves after all.
+func (h *SignalingHub) markSuspended(client *Client, sc *suspendedClient, live entity.Presence) {
+	ctx := context.Background()
+	marked, err := h.presenceRepo.ReplacePresence(ctx, sc.roomID, sc.participantID, live, sc.presence)
+	if err != nil || !marked {
+		h.dispatch(sc.roomID, func(s *shard) { s.forgetSuspended(sc) })
+		h.departClient(client)
+		return
+	}
+
+	// drop leftovers from an earlier disconnect
+	if _, err := h.sessionRepo.DrainBuffer(ctx, sc.token); err != nil {
+		log.Printf("[Hub] Failed to clear buffer of %s: %v", sc.userID, err)
+	}
+
+	h.notifyPeerReconnecting(sc.roomID, sc.participantID, h.gracePeriod())
+}
+
+// forgetSuspended undoes a suspend whose presence entry couldn't be marked.
+func (s *shard) forgetSuspended(sc *suspendedClient) {
+	r := s.rooms[sc.roomID]
+	if r == nil || r.suspended[sc.participantID] != sc {
+		return
+	}
+
+	r.dropSuspended(sc)
+	s.release(r)
+}
+
+// expireSuspended runs when a dropped client did not come back in time.
+func (s *shard) expireSuspended(sc *suspendedClient) {
+	r := s.rooms[sc.roomID]
+	if r == nil || r.suspended[sc.participantID] != sc {
+		return
+	}
+
+	r.dropSuspended(sc)
+	s.release(r)
+
+	h := s.hub
+	s.async(func() { h.expireSession(sc) })
+}
+
+// expireSession announces the departure of a dropped client that did not
+// come back in time.
+func (h *SignalingHub) expireSession(sc *suspendedClient) {
+	// fails when the client resumed on another node in the meantime
+	removed, err := h.presenceRepo.RemovePresence(context.Background(), sc.roomID, sc.participantID, sc.presence)
+	if err != nil {
+		log.Printf("[Hub] Failed to remove presence for %s: %v", sc.participantID, err)
+	}
+	if removed || err != nil {
+		log.Printf("[Hub] Grace period over for %s in room %s", sc.participantID, sc.roomID)
+		h.handleDeparture(sc.roomID, sc.participantID, sc.userID)
+	}
+	if removed {
+		h.endSession(sc.token)
+	}
+}
+
+// dropSuspended forgets a dropped client.
+func (r *room) dropSuspended(sc *suspendedClient) {
+	sc.timer.Stop()
+	delete(r.suspended, sc.participantID)
+}
+
+// resumedElsewhere cleans up after a participant resumed on another node: the
+// suspended entry and any stale socket still open here.
+func (s *shard) resumedElsewhere(roomID, participantID, clientID string) {
+	r := s.rooms[roomID]
+	if r == nil {
+		return
+	}
+
+	if sc := r.suspended[participantID]; sc != nil {
+		r.dropSuspended(sc)
+	}
+
+	// its presence entry is gone, so unregistering it won't announce anything
+	if stale := r.clients[participantID]; stale != nil && stale.ID != clientID {
+		stale.closeSend()
+	}
+
+	s.release(r)
+}
+
+func (h *SignalingHub) publishResumed(client *Client) {
+	h.publishEnvelope(&envelope{
+		NodeID:   h.nodeID,
+		RoomID:   client.RoomID,
+		To:       client.ParticipantID,
+		Command:  commandResumed,
+		ClientID: client.ID,
+	})
+}
+
+func (h *SignalingHub) bufferMessage(token string, message []byte) {
+	// outlive the grace period a little so a late resume still finds it
+	ttl := 2 * h.gracePeriod()
+	if err := h.sessionRepo.BufferMessage(context.Background(), token, message, resumeBufferSize, ttl); err != nil {
+		log.Printf("[Hub] Failed to buffer message: %v", err)
+	}
+}
+
+func (h *SignalingHub) sendSession(client *Client) {
+	if client.ResumeToken == "" {
+		return
+	}
+
+	client.sendMessage(entity.SignalMessage{
+		Type:   "session",
+		From:   "server",
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"resumeToken": client.ResumeToken,
+			"resumed":     client.resumed,
+			"gracePeriod": h.config.ResumeGracePeriod,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// drainSession takes what a resumed client missed out of its buffer.
+func (h *SignalingHub) drainSession(client *Client) [][]byte {
+	messages, err := h.sessionRepo.DrainBuffer(context.Background(), client.ResumeToken)
+	if err != nil {
+		log.Printf("[Hub] Failed to drain buffer of %s: %v", client.UserID, err)
+		return nil
+	}
+	return messages
+}
+
+// replaySession delivers what the client missed. Messages published while
+// the takeover is in flight may arrive twice.
+func (h *SignalingHub) replaySession(client *Client, messages [][]byte) {
+	log.Printf("[Hub] Replaying %d messages to %s", len(messages), client.UserID)
+
+	for _, message := range messages {
+		var peek struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(message, &peek)
+
+		// a dropped chat message is no reason to stop, a full signaling queue is
+		bulk := protocol.IsBulk(peek.Type)
+		if !client.queue(message, bulk) && !bulk {
+			return
+		}
+	}
+}
+
+func (h *SignalingHub) notifyPeerReconnecting(roomID, participantID string, grace time.Duration) {
+	notification := entity.SignalMessage{
+		Type:   "peer-reconnecting",
+		From:   participantID,
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"participantId": participantID,
+			"gracePeriod":   int(grace.Seconds()),
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
+		Message: data,
+		Exclude: participantID,
+	})
+}
+
+func (h *SignalingHub) notifyPeerReconnected(client *Client) {
+	notification := entity.SignalMessage{
+		Type:   "peer-reconnected",
+		From:   client.ParticipantID,
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"participantId": client.ParticipantID,
+		},
+		Timestamp: time.Now(),
+	}
+
+	data, _ := json.Marshal(notification)
+	h.publish(&BroadcastMessage{
+		RoomID:  client.RoomID,
+		Message: data,
+		Exclude: client.ParticipantID,
+	})
+}
@@ -0,0 +1,445 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+)
+
+// events queued per shard before dispatchers block
+const shardInboxSize = 1024
+
+// shard owns the rooms hashed to it. Room state is only touched from the
+// shard's goroutine, so there is no hub-wide lock and rooms on different
+// shards never wait on each other. The shard never waits on Redis either:
+// its repository and broker calls run on its I/O worker.
+type shard struct {
+	hub   *SignalingHub
+	rooms map[string]*room
+	inbox chan func(*shard)
+	io    *ioQueue
+}
+
+// room is what this node knows about one room: its local connections, the
+// clients waiting in its lobby and the dropped clients it is holding on to.
+type room struct {
+	id        string
+	clients   map[string]*Client
+	lobby     map[string]*Client
+	suspended map[string]*suspendedClient
+}
+
+func newShard(hub *SignalingHub) *shard {
+	return &shard{
+		hub:   hub,
+		rooms: make(map[string]*room),
+		inbox: make(chan func(*shard), shardInboxSize),
+		io:    newIOQueue(),
+	}
+}
+
+func (s *shard) run() {
+	for {
+		select {
+		case fn := <-s.inbox:
+			fn(s)
+		case <-s.hub.done:
+			// the I/O worker still finishes what it was handed
+			s.io.close()
+			return
+		}
+	}
+}
+
+func (h *SignalingHub) startShards() {
+	for _, s := range h.shards {
+		h.workers.Add(2)
+		go func(s *shard) {
+			defer h.workers.Done()
+			s.run()
+		}(s)
+		go func(s *shard) {
+			defer h.workers.Done()
+			s.io.run()
+		}(s)
+	}
+}
+
+// async hands I/O to the shard's I/O worker, which runs it in the order it
+// was handed over: a presence is set before it is removed, a buffer drained
+// before it is filled again. I/O whose result changes room state dispatches
+// back to the shard.
+func (s *shard) async(fn func()) {
+	s.io.push(fn)
+}
+
+// ioQueue is a shard's I/O worker queue. It is unbounded so the shard never
+// blocks on it, and a worker dispatching back to a busy shard can't
+// deadlock with it.
+type ioQueue struct {
+	mu     sync.Mutex
+	tasks  []func()
+	wake   chan struct{}
+	closed bool
+}
+
+func newIOQueue() *ioQueue {
+	return &ioQueue{wake: make(chan struct{}, 1)}
+}
+
+func (q *ioQueue) push(fn func()) {
+	q.mu.Lock()
+	q.tasks = append(q.tasks, fn)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close lets the worker stop once it ran everything already pushed.
+func (q *ioQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *ioQueue) run() {
+	for {
+		q.mu.Lock()
+		tasks, closed := q.tasks, q.closed
+		q.tasks = nil
+		q.mu.Unlock()
+
+		for _, fn := range tasks {
+			fn()
+		}
+		if len(tasks) > 0 {
+			continue
+		}
+		if closed {
+			return
+		}
+		<-q.wake
+	}
+}
+
+func (h *SignalingHub) shardFor(roomID string) *shard {
+	f := fnv.New32a()
+	f.Write([]byte(roomID))
+	return h.shards[f.Sum32()%uint32(len(h.shards))]
+}
+
+// dispatch runs fn on the shard owning roomID. It reports false once the hub
+// is shutting down. Never call it and wait from a shard goroutine.
+func (h *SignalingHub) dispatch(roomID string, fn func(*shard)) bool {
+	select {
+	case h.shardFor(roomID).inbox <- fn:
+		return true
+	case <-h.done:
+		return false
+	}
+}
+
+// query runs fn on the shard owning roomID and waits for it to finish.
+func (h *SignalingHub) query(roomID string, fn func(*shard)) bool {
+	finished := make(chan struct{})
+	if !h.dispatch(roomID, func(s *shard) {
+		defer close(finished)
+		fn(s)
+	}) {
+		return false
+	}
+
+	select {
+	case <-finished:
+		return true
+	case <-h.done:
+		return false
+	}
+}
+
+// eachShard runs fn on every shard and waits for all of them.
+func (h *SignalingHub) eachShard(fn func(*shard)) {
+	pending := make([]chan struct{}, 0, len(h.shards))
+	for _, s := range h.shards {
+		finished := make(chan struct{})
+		select {
+		case s.inbox <- func(s *shard) {
+			defer close(finished)
+			fn(s)
+		}:
+			pending = append(pending, finished)
+		case <-h.done:
+			return
+		}
+	}
+
+	for _, finished := range pending {
+		select {
+		case <-finished:
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// room returns the room, subscribing this node to it on first use.
+func (s *shard) room(roomID string) *room {
+	r, exists := s.rooms[roomID]
+	if !exists {
+		r = &room{
+			id:        roomID,
+			clients:   make(map[string]*Client),
+			lobby:     make(map[string]*Client),
+			suspended: make(map[string]*suspendedClient),
+		}
+		s.rooms[roomID] = r
+		h := s.hub
+		s.async(func() { h.subscribeRoom(roomID) })
+		log.Printf("[Hub] Created new room: %s", roomID)
+	}
+	return r
+}
+
+// release forgets the room once this node has no clients, admitted, waiting
+// or suspended, in it.
+func (s *shard) release(r *room) {
+	if len(r.clients) > 0 || len(r.lobby) > 0 || len(r.suspended) > 0 {
+		return
+	}
+
+	delete(s.rooms, r.id)
+	h, roomID := s.hub, r.id
+	s.async(func() { h.unsubscribeRoom(roomID) })
+	log.Printf("[Hub] Empty room deleted: %s", r.id)
+}
+
+func (s *shard) registerClient(client *Client) {
+	if client == nil {
+		return
+	}
+
+	if client.waiting.Load() {
+		s.parkClient(client)
+		return
+	}
+
+	h := s.hub
+	r := s.room(client.RoomID)
+
+	// admitted from the lobby
+	if r.lobby[client.ParticipantID] == client {
+		delete(r.lobby, client.ParticipantID)
+	}
+
+	// came back on this node within the grace period
+	if dropped := r.suspended[client.ParticipantID]; dropped != nil {
+		r.dropSuspended(dropped)
+	}
+
+	previous := r.clients[client.ParticipantID]
+	r.clients[client.ParticipantID] = client
+
+	// resumed on this node before the old socket timed out
+	if previous != nil && previous != client {
+		previous.closeSend()
+	}
+
+	log.Printf("[Hub] Client registered: %s (user %s) in room %s (Total: %d)",
+		client.ParticipantID, client.UserID, client.RoomID, len(r.clients))
+
+	// room traffic is held back until the client has its snapshot
+	client.syncing = true
+	client.backlog = nil
+	s.async(func() { h.syncClient(client) })
+}
+
+// clientSync is what a registered client catches up on before room traffic
+// reaches it.
+type clientSync struct {
+	replay [][]byte // missed while it was away
+	state  []byte   // nil when the room state couldn't be loaded
+	knocks []*entity.Participant
+}
+
+// syncClient is the I/O of a registration: it records the presence,
+// announces the client and loads what it catches up on, then hands that
+// back to the shard.
+func (h *SignalingHub) syncClient(client *Client) {
+	ctx := context.Background()
+	catchUp := &clientSync{}
+
+	presence := entity.Presence{NodeID: h.nodeID, ClientID: client.ID}
+	if err := h.presenceRepo.SetPresence(ctx, client.RoomID, client.ParticipantID, presence); err != nil {
+		log.Printf("[Hub] Failed to set presence for %s: %v", client.UserID, err)
+	}
+
+	if client.resumed {
+		h.notifyPeerReconnected(client)
+		h.publishResumed(client)
+		catchUp.replay = h.drainSession(client)
+	} else {
+		h.notifyPeerJoined(client)
+	}
+
+	// after the join was announced, so the snapshot includes this client
+	state, err := h.loadRoomState(client.RoomID)
+	if err != nil {
+		log.Printf("[Hub] Failed to get state of room %s: %v", client.RoomID, err)
+	}
+	catchUp.state = state
+
+	if client.IsHost {
+		waiting, err := h.roomUseCase.GetLobby(ctx, client.RoomID)
+		if err != nil {
+			log.Printf("[Hub] Error getting lobby: %v", err)
+		}
+		catchUp.knocks = waiting
+	}
+
+	h.dispatch(client.RoomID, func(s *shard) { s.clientSynced(client, catchUp) })
+}
+
+// clientSynced sends a registered client what it catches up on, then the room
+// traffic held back meanwhile.
+func (s *shard) clientSynced(client *Client, catchUp *clientSync) {
+	r := s.rooms[client.RoomID]
+	if r == nil || r.clients[client.ParticipantID] != client {
+		return
+	}
+
+	h := s.hub
+	h.sendSession(client)
+	if client.resumed {
+		h.replaySession(client, catchUp.replay)
+	}
+
+	if catchUp.state != nil {
+		client.queue(catchUp.state, false)
+	} else {
+		client.sendError(protocol.NewError(protocol.CodeInternal, "failed to load room state"))
+	}
+
+	for _, p := range catchUp.knocks {
+		client.sendMessage(knockMessage(client.RoomID, p))
+	}
+
+	client.syncing = false
+	for _, msg := range client.backlog {
+		client.queue(msg.Message, msg.Bulk)
+	}
+	client.backlog = nil
+}
+
+func (s *shard) unregisterClient(client *Client) {
+	if client == nil {
+		return
+	}
+
+	if client.waiting.Load() {
+		s.unparkClient(client)
+		return
+	}
+
+	if !client.leaving.Load() && s.suspendClient(client) {
+		client.closeSend()
+		return
+	}
+
+	h := s.hub
+	r := s.rooms[client.RoomID]
+	registered := r != nil && r.clients[client.ParticipantID] == client
+	if registered {
+		delete(r.clients, client.ParticipantID)
+		log.Printf("[Hub] Client unregistered: %s from room %s (Remaining: %d)",
+			client.ParticipantID, client.RoomID, len(r.clients))
+		s.release(r)
+	}
+
+	client.closeSend()
+
+	if !registered || client.ParticipantID == "" {
+		return
+	}
+
+	s.async(func() { h.departClient(client) })
+}
+
+// departClient is the I/O of a client leaving for good.
+func (h *SignalingHub) departClient(client *Client) {
+	// the room and everyone's presence went with the meeting
+	if client.ended.Load() {
+		h.endSession(client.ResumeToken)
+		return
+	}
+
+	// only the connection that still owns the presence entry may announce the
+	// departure; the participant may already have resumed on another node
+	presence := entity.Presence{NodeID: h.nodeID, ClientID: client.ID}
+	removed, err := h.presenceRepo.RemovePresence(context.Background(), client.RoomID, client.ParticipantID, presence)
+	if err != nil {
+		log.Printf("[Hub] Failed to remove presence for %s: %v", client.ParticipantID, err)
+	}
+	if removed || err != nil {
+		h.handleDeparture(client.RoomID, client.ParticipantID, client.UserID)
+	}
+	if removed {
+		h.endSession(client.ResumeToken)
+	}
+}
+
+// deliver hands a room message to the local recipients. Each message is
+// encoded once per codec, not once per recipient.
+func (s *shard) deliver(msg *BroadcastMessage) {
+	r := s.rooms[msg.RoomID]
+	if r == nil {
+		return
+	}
+
+	var frames map[protocol.Codec][]byte
+	for participantID, client := range r.clients {
+		if !msg.deliversTo(participantID, client.IsHost) {
+			continue
+		}
+		if client.syncing {
+			client.backlog = append(client.backlog, msg)
+			continue
+		}
+
+		frame, encoded := frames[client.codec]
+		if !encoded {
+			var err error
+			if frame, err = client.codec.FromJSON(msg.Message); err != nil {
+				log.Printf("[Hub] Failed to encode message as %s: %v", client.codec.Name(), err)
+			}
+			if frames == nil {
+				frames = make(map[protocol.Codec][]byte, 2)
+			}
+			frames[client.codec] = frame
+		}
+		if frame == nil {
+			continue
+		}
+
+		client.enqueue(frame, msg.Bulk)
+	}
+
+	// hold on to what a dropped client would have received
+	h := s.hub
+	for participantID, sc := range r.suspended {
+		if msg.deliversTo(participantID, sc.isHost) {
+			token := sc.token
+			s.async(func() { h.bufferMessage(token, msg.Message) })
+		}
+	}
+}
@@ -1,270 +1,368 @@
 package websocket
 
 import (
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/delivery/websocket/protocol"
 	"bincang-visual/internal/domain/entity"
 	"bincang-visual/internal/domain/repository"
 	"bincang-visual/internal/domain/usecase"
+	"bincang-visual/internal/infrastructure/pubsub"
 
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 )
 
+// Application close codes (4000-4999) so clients know why they were dropped
+// and whether reconnecting makes sense.
+const (
+	CloseRemovedByHost       = 4001
+	CloseUnsupportedProtocol = 4002
+	CloseBanned              = 4003
+	CloseDeviceDisconnected  = 4004 // the same user disconnected this device from another one
+	CloseCallTransferred     = 4005 // the same user moved the call to another device
+	CloseMeetingEnded        = 4006
+	CloseSlowConsumer        = 4008 // reconnect and resume the session
+)
+
 type SignalingHub struct {
-	userRepo    repository.UserRepository
-	rooms       map[string]map[string]*Client
-	mu          sync.RWMutex
-	roomUseCase *usecase.RoomUseCase
-	register    chan *Client
-	unregister  chan *Client
-	broadcast   chan *BroadcastMessage
+	userRepo     repository.UserRepository
+	presenceRepo repository.PresenceRepository
+	sessionRepo  repository.SessionRepository
+	broker       pubsub.Broker
+	nodeID       string
+	config       config.SignalingConfig
+	shards       []*shard
+	workers      sync.WaitGroup
+	roomUseCase  *usecase.RoomUseCase
+	done         chan struct{}
+	shutdownOnce sync.Once
 }
 
 type Client struct {
-	UserID      string
-	RoomID      string
-	DisplayName string
-	Conn        *websocket.Conn
-	Send        chan []byte
-	Hub         *SignalingHub
+	ID            string // this connection
+	ParticipantID string // addresses the client in its room, kept when it resumes
+	UserID        string
+	RoomID        string
+	DisplayName   string
+	Device        string
+	IsGuest       bool
+	IsHost        bool
+	Conn          *websocket.Conn
+	Send          chan []byte // bulk messages such as chat; closing it ends the connection
+	priority      chan []byte // signaling, written before anything in Send
+	Hub           *SignalingHub
+	Protocol      int // negotiated protocol version
+	codec         protocol.Codec
+	sendMu        sync.Mutex // guards the queues against sends after close
+	closed        bool
+	saturated     time.Time // when the bulk queue first overflowed
+	closeCode     int
+	closeReason   string
+	ResumeToken   string
+	resumed       bool
+	waiting       atomic.Bool
+	leaving       atomic.Bool         // left on purpose, no grace period
+	ended         atomic.Bool         // the room was closed, nothing left to clean up
+	syncing       bool                // shard only: registered, snapshot not sent yet
+	backlog       []*BroadcastMessage // shard only: room traffic held while syncing
 }
 
 type BroadcastMessage struct {
-	RoomID  string
-	Message []byte
-	Exclude string
+	RoomID    string
+	Message   []byte
+	Exclude   string
+	To        string
+	HostsOnly bool
+	Bulk      bool // may be dropped for a client that can't keep up
 }
 
-func NewSignalingHub(roomUseCase *usecase.RoomUseCase) *SignalingHub {
-	return &SignalingHub{
-		rooms:       make(map[string]map[string]*Client),
-		roomUseCase: roomUseCase,
-		register:    make(chan *Client),
-		unregister:  make(chan *Client),
-		broadcast:   make(chan *BroadcastMessage, 256),
-	}
+func NewSignalingHub(
+	roomUseCase *usecase.RoomUseCase,
+	presenceRepo repository.PresenceRepository,
+	sessionRepo repository.SessionRepository,
+	broker pubsub.Broker,
+	cfg config.SignalingConfig,
+) *SignalingHub {
+	h := &SignalingHub{
+		presenceRepo: presenceRepo,
+		sessionRepo:  sessionRepo,
+		broker:       broker,
+		nodeID:       cfg.NodeID,
+		config:       cfg,
+		roomUseCase:  roomUseCase,
+		done:         make(chan struct{}),
+	}
+
+	shards := cfg.Shards
+	if shards <= 0 {
+		shards = 1
+	}
+	h.shards = make([]*shard, shards)
+	for i := range h.shards {
+		h.shards[i] = newShard(h)
+	}
+
+	return h
 }
 
 func (h *SignalingHub) Run() {
-	log.Println("[Hub] Starting SignalingHub...")
-	for {
-		select {
-		case client := <-h.register:
-			h.registerClient(client)
-		case client := <-h.unregister:
-			h.unregisterClient(client)
-		case message := <-h.broadcast:
-			h.broadcastMessage(message)
-		}
-	}
-}
+	log.Printf("[Hub] Starting SignalingHub on node %s with %d shards...", h.nodeID, len(h.shards))
 
-func (h *SignalingHub) registerClient(client *Client) {
-	if client == nil {
-		return
-	}
+	h.startShards()
+	go h.consume()
+	go h.heartbeat()
+	go h.watchExpiry()
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.rooms[client.RoomID]; !exists {
-		h.rooms[client.RoomID] = make(map[string]*Client)
-		log.Printf("[Hub] Created new room: %s", client.RoomID)
-	}
-
-	h.rooms[client.RoomID][client.UserID] = client
-
-	log.Printf("[Hub] Client registered: %s in room %s (Total: %d)",
-		client.UserID, client.RoomID, len(h.rooms[client.RoomID]))
-
-	h.notifyPeerJoined(client)
+	<-h.done
 }
 
-func (h *SignalingHub) unregisterClient(client *Client) {
-	if client == nil {
-		return
-	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if room, exists := h.rooms[client.RoomID]; exists {
-		if _, exists := room[client.UserID]; exists {
-			delete(room, client.UserID)
-
-			select {
-			case <-client.Send:
-			default:
-				close(client.Send)
-			}
+// handleDeparture releases everything a participant held in the room and
+// tells the rest of the cluster they are gone. userID is looked up when the
+// caller doesn't know it.
+func (h *SignalingHub) handleDeparture(roomID, participantID, userID string) {
+	ctx := context.Background()
 
-			log.Printf("[Hub] Client unregistered: %s from room %s (Remaining: %d)",
-				client.UserID, client.RoomID, len(room))
-
-			currentSharer, _ := h.roomUseCase.GetScreenSharer(context.Background(), client.RoomID)
-			if currentSharer == client.UserID {
-				_ = h.roomUseCase.StopScreenShare(context.Background(), client.RoomID, client.UserID)
-				notification := entity.SignalMessage{
-					Type:   "screen-share",
-					From:   client.UserID,
-					RoomID: client.RoomID,
-					Data: map[string]interface{}{
-						"isSharing": false,
-					},
-				}
-				data, _ := json.Marshal(notification)
-				h.broadcast <- &BroadcastMessage{
-					RoomID:  client.RoomID,
-					Message: data,
-				}
-			}
-
-			if len(room) == 0 {
-				delete(h.rooms, client.RoomID)
-				log.Printf("[Hub] Empty room deleted: %s", client.RoomID)
-			}
-
-			h.notifyPeerLeft(client)
+	if userID == "" {
+		if participant, err := h.roomUseCase.GetParticipant(ctx, roomID, participantID); err == nil {
+			userID = participant.UserID
 		}
 	}
 
-	if client.UserID != "" {
-		_ = h.roomUseCase.LeaveRoom(context.Background(), client.RoomID, client.UserID)
+	if err := h.roomUseCase.StopScreenShare(ctx, roomID, participantID); err == nil {
+		h.publishScreenShare(roomID, participantID, false)
 	}
-}
-
-func (h *SignalingHub) broadcastMessage(msg *BroadcastMessage) {
-	if msg == nil {
-		return
+	if cancelled, _ := h.roomUseCase.CancelScreenShareRequest(ctx, roomID, participantID); cancelled {
+		h.publishShareRequestResolved(roomID, participantID, "left")
 	}
-	h.mu.RLock()
-	defer h.mu.RUnlock()
 
-	if room, exists := h.rooms[msg.RoomID]; exists {
-		for clientID, client := range room {
-			if clientID == msg.Exclude {
-				continue
-			}
+	_ = h.roomUseCase.LeaveRoom(ctx, roomID, participantID)
 
-			select {
-			case client.Send <- msg.Message:
-			default:
-				log.Printf("[Hub] Failed to send to %s, channel full", clientID)
-			}
-		}
-	}
+	h.notifyPeerLeft(roomID, participantID, userID)
 }
 
-func (h *SignalingHub) notifyPeerJoined(client *Client) {
-	participants, err := h.roomUseCase.GetParticipants(context.Background(), client.RoomID)
-	if err != nil {
-		log.Printf("[Hub] Error getting participants: %v", err)
-		return
+func (m *BroadcastMessage) deliversTo(participantID string, isHost bool) bool {
+	if m.To != "" {
+		return participantID == m.To
 	}
-
-	var newParticipant *entity.Participant
-	for _, p := range participants {
-		if p.UserID == client.UserID {
-			newParticipant = p
-			break
-		}
+	if participantID == m.Exclude {
+		return false
 	}
+	return !m.HostsOnly || isHost
+}
 
-	if newParticipant == nil {
-		log.Printf("[Hub] New participant not found in DB")
+func (h *SignalingHub) notifyPeerJoined(client *Client) {
+	newParticipant, err := h.roomUseCase.GetParticipant(context.Background(), client.RoomID, client.ParticipantID)
+	if err != nil {
+		log.Printf("[Hub] New participant not found in DB: %v", err)
 		return
 	}
 
+	// userId lets clients group the devices of one person
 	notification := entity.SignalMessage{
 		Type:   "peer-joined",
-		From:   client.UserID,
+		From:   client.ParticipantID,
 		RoomID: client.RoomID,
 		Data: map[string]interface{}{
-			"userId":      client.UserID,
-			"displayName": client.DisplayName,
-			"isHost":      false,
+			"participantId": client.ParticipantID,
+			"userId":        client.UserID,
+			"displayName":   client.DisplayName,
+			"device":        client.Device,
+			"isHost":        newParticipant.IsHost,
+			"isGuest":       newParticipant.IsGuest,
 		},
+		Version: h.nextVersion(client.RoomID),
 	}
 
-	// notification := entity.SignalMessage{
-	// 	Type:   "peer-joined",
-	// 	From:   client.ID,
-	// 	RoomID: client.RoomID,
-	// 	Data: map[string]interface{}{
-	// 		"participantId": newParticipant.ID,
-	// 		"userId":        newParticipant.UserID,
-	// 		"displayName":   newParticipant.DisplayName,
-	// 		"isHost":        newParticipant.IsHost,
-	// 	},
-	// }
-
 	data, _ := json.Marshal(notification)
-	h.broadcast <- &BroadcastMessage{
+	h.publish(&BroadcastMessage{
 		RoomID:  client.RoomID,
 		Message: data,
-		Exclude: client.UserID,
-	}
+		Exclude: client.ParticipantID,
+	})
 }
 
-func (h *SignalingHub) notifyPeerLeft(client *Client) {
+func (h *SignalingHub) notifyPeerLeft(roomID, participantID, userID string) {
 	notification := entity.SignalMessage{
 		Type:   "peer-left",
-		From:   client.UserID,
-		RoomID: client.RoomID,
+		From:   participantID,
+		RoomID: roomID,
 		Data: map[string]interface{}{
-			"userId": client.UserID,
+			"participantId": participantID,
+			"userId":        userID,
 		},
+		Version: h.nextVersion(roomID),
 	}
 
 	data, _ := json.Marshal(notification)
-	h.broadcast <- &BroadcastMessage{
-		RoomID:  client.RoomID,
+	h.publish(&BroadcastMessage{
+		RoomID:  roomID,
 		Message: data,
+	})
+}
+
+// deviceLabel trims the client supplied device name to something short
+// enough to show in a list.
+func deviceLabel(device string) string {
+	label := []rune(strings.TrimSpace(device))
+	if len(label) > maxDeviceLabel {
+		label = label[:maxDeviceLabel]
 	}
+	return string(label)
+}
+
+// ConnectParams describes an authenticated WebSocket connection request.
+type ConnectParams struct {
+	RoomID      string
+	UserID      string
+	Email       string // of a signed in user, checked against invites
+	ClientID    string
+	DisplayName string
+	IsGuest     bool
+	Device      string // optional label shown to the user's other devices
+	ResumeToken string // from a previous connection's session message
+	Protocol    string // protocol versions the client speaks, e.g. "1,2"
+	Passcode    string // for rooms with a passcode
+	InviteToken string
+	ClientIP    string
 }
 
-func (h *SignalingHub) HandleWebSocket(c *websocket.Conn, roomID, userID, clientID, displayName string) {
+func (h *SignalingHub) HandleWebSocket(c *websocket.Conn, params ConnectParams) {
 	if c == nil {
 		log.Println("[WebSocket] Nil connection received")
 		return
 	}
 
-	log.Printf("[WebSocket] New connection: clientID=%s, userID=%s, roomID=%s", clientID, userID, roomID)
-	// user, err := h.roomUseCase.GetCurrentUser(context.Background(), userID)
-	// if err != nil {
-	// 	log.Println("[WebSocket] User not found/ unauthenticated")
-	// 	return
-	// }
+	roomID, userID, clientID := params.RoomID, params.UserID, params.ClientID
+
+	log.Printf("[WebSocket] New connection: clientID=%s, userID=%s, roomID=%s, guest=%t",
+		clientID, userID, roomID, params.IsGuest)
+
+	codec := protocol.CodecFor(c.Subprotocol())
+
+	version, err := protocol.Negotiate(params.Protocol)
+	if err != nil {
+		log.Printf("[WebSocket] Rejecting %s: %v", clientID, err)
+		rejectConnection(c, codec, protocol.NewError(protocol.CodeUnsupportedVersion, err.Error()), CloseUnsupportedProtocol)
+		return
+	}
+
+	// the room may be addressed by its code, everything past here needs its ID
+	roomID, err = h.roomUseCase.ResolveRoomID(context.Background(), roomID)
+	if err != nil {
+		log.Printf("[WebSocket] Rejecting %s: %v", clientID, err)
+		rejectConnection(c, codec, protocol.NewError(errorCode(err), "Room not found"), websocket.ClosePolicyViolation)
+		return
+	}
+	params.RoomID = roomID
+
+	if params.ResumeToken != "" {
+		if client := h.resumeClient(c, params); client != nil {
+			client.Protocol = version
+			client.codec = codec
+			h.startClient(client)
+			return
+		}
+		log.Printf("[WebSocket] Could not resume session for %s, joining again", userID)
+	}
+
+	// every connection is its own participant, so a second device joins
+	// next to the first instead of replacing it
 	participant, err := h.roomUseCase.JoinRoom(context.Background(), usecase.JoinRoomInput{
-		RoomID:      roomID,
-		UserID:      userID,
-		DisplayName: displayName,
+		RoomID:        roomID,
+		ParticipantID: clientID,
+		UserID:        userID,
+		Email:         params.Email,
+		DisplayName:   params.DisplayName,
+		IsGuest:       params.IsGuest,
+		Device:        deviceLabel(params.Device),
+		Passcode:      params.Passcode,
+		InviteToken:   params.InviteToken,
+		ClientIP:      params.ClientIP,
 	})
 
 	if err != nil {
 		log.Printf("[WebSocket] Error joining room: %v", err)
-		c.WriteJSON(map[string]string{"error": "Failed to join room"})
-		c.Close()
+		message := "Failed to join room"
+		switch {
+		case errors.Is(err, usecase.ErrGuestsNotAllowed):
+			message = "Sign in to join this room"
+		case errors.Is(err, usecase.ErrMeetingOver):
+			message = "This meeting has ended"
+		case errors.Is(err, usecase.ErrPasscodeRequired):
+			message = "Enter the passcode to join this room"
+		case errors.Is(err, usecase.ErrWrongPasscode):
+			message = "Wrong passcode"
+		case errors.Is(err, usecase.ErrTooManyAttempts):
+			message = "Too many wrong passcodes, try again later"
+		case errors.Is(err, usecase.ErrRoomLocked):
+			message = "The host has locked this room"
+		case errors.Is(err, usecase.ErrInvalidInvite):
+			message = "This invitation is no longer valid"
+		case errors.Is(err, usecase.ErrInviteUsedUp):
+			message = "This invitation has been used up"
+		case errors.Is(err, usecase.ErrInviteNotForYou):
+			message = "This invitation was sent to someone else"
+		case errors.Is(err, usecase.ErrInviteSignInRequired):
+			message = "Sign in to use this invitation"
+		}
+		rejectConnection(c, codec, protocol.NewError(errorCode(err), message), websocket.ClosePolicyViolation)
 		return
 	}
 
-	log.Printf("[WebSocket] Participant joined: ID=%s", participant.UserID)
+	log.Printf("[WebSocket] Participant joined: ID=%s, userID=%s", participant.ID, participant.UserID)
 
 	client := &Client{
-		UserID:      userID,
-		RoomID:      roomID,
-		DisplayName: displayName,
-		Conn:        c,
-		Send:        make(chan []byte, 256),
-		Hub:         h,
-	}
+		ID:            clientID,
+		ParticipantID: participant.ID,
+		UserID:        userID,
+		RoomID:        roomID,
+		DisplayName:   params.DisplayName,
+		Device:        participant.Device,
+		IsGuest:       params.IsGuest,
+		IsHost:        participant.IsHost,
+		Conn:          c,
+		Send:          make(chan []byte, h.config.SendQueueSize),
+		priority:      make(chan []byte, h.config.SendQueueSize),
+		Hub:           h,
+		Protocol:      version,
+		codec:         codec,
+	}
+	client.waiting.Store(participant.Status == entity.ParticipantStatusWaiting)
+	client.ResumeToken = h.createSession(client)
+
+	h.startClient(client)
+}
+
+// startClient registers the client on its room's shard and serves it until
+// the connection ends.
+func (h *SignalingHub) startClient(client *Client) {
+	client.sendMessage(entity.SignalMessage{
+		Type:   "welcome",
+		From:   "server",
+		RoomID: client.RoomID,
+		Data: map[string]interface{}{
+			"protocolVersion": client.Protocol,
+			"participantId":   client.ParticipantID,
+			"userId":          client.UserID,
+			"clientId":        client.ID,
+		},
+		Timestamp: time.Now(),
+	})
 
-	h.register <- client
+	if !h.dispatch(client.RoomID, func(s *shard) { s.registerClient(client) }) {
+		client.Conn.Close()
+		return
+	}
 
-	log.Printf("[WebSocket] Starting pumps for client %s", clientID)
+	log.Printf("[WebSocket] Starting pumps for client %s", client.ID)
 
 	go client.writePump()
 	client.readPump()
@@ -274,11 +372,12 @@ func (h *SignalingHub) HandleWebSocket(c *websocket.Conn, roomID, userID, client
 func (c *Client) readPump() {
 	defer func() {
 		log.Printf("[WebSocket] ReadPump ending for client %s", c.UserID)
-		c.Hub.unregister <- c
+		c.Hub.requestUnregister(c)
 		c.Conn.Close()
 	}()
 
 	// configure connection
+	c.Conn.SetReadLimit(protocol.MaxMessageSize)
 	c.Conn.SetReadDeadline(time.Now().Add(70 * time.Second))
 	c.Conn.SetPongHandler(func(string) error {
 		log.Printf("[WebSocket] Pong received from %s", c.UserID)
@@ -299,23 +398,26 @@ func (c *Client) readPump() {
 			break
 		}
 
-		if messageType != websocket.TextMessage {
-			log.Printf("[WebSocket] Received non-text message from %s, type: %d", c.UserID, messageType)
+		// JSON is always understood; binary frames need a binary codec
+		codec := protocol.JSON
+		if messageType == websocket.BinaryMessage && c.codec.Binary() {
+			codec = c.codec
+		} else if messageType != websocket.TextMessage {
+			log.Printf("[WebSocket] Received unsupported message from %s, type: %d", c.UserID, messageType)
+			c.sendError(protocol.NewError(protocol.CodeInvalidMessage, "binary messages need the "+protocol.SubprotocolMsgpack+" subprotocol"))
 			continue
 		}
 
-		var msg entity.SignalMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("[WebSocket] Failed to parse message from %s: %v. Raw: %s", c.UserID, err, string(message))
-
+		req, perr := codec.Decode(message)
+		if perr != nil {
+			log.Printf("[WebSocket] Rejected message from %s: %v", c.UserID, perr)
+			c.sendError(perr)
 			continue
 		}
 
-		log.Printf("[WebSocket] Received '%s' from %s", msg.Type, c.UserID)
+		log.Printf("[WebSocket] Received '%s' from %s", req.Type, c.UserID)
 
-		msg.From = c.UserID
-		msg.RoomID = c.RoomID
-		c.handleMessage(&msg)
+		c.handleMessage(req)
 	}
 }
 
@@ -331,18 +433,39 @@ func (c *Client) writePump() {
 	log.Printf("[WebSocket] WritePump started for client %s", c.UserID)
 
 	for {
+		// signaling never waits behind bulk messages
 		select {
-		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		case message := <-c.priority:
+			if err := c.write(message); err != nil {
+				log.Printf("[WebSocket] Write error for %s: %v", c.UserID, err)
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case message := <-c.priority:
+			if err := c.write(message); err != nil {
+				log.Printf("[WebSocket] Write error for %s: %v", c.UserID, err)
+				return
+			}
 
+		case message, ok := <-c.Send:
 			if !ok {
 				// Channel closed
 				log.Printf("[WebSocket] Send channel closed for %s", c.UserID)
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.flushPriority()
+				c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				closeMessage := []byte{}
+				if c.closeCode != 0 {
+					closeMessage = websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeMessage)
 				return
 			}
 
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if err := c.write(message); err != nil {
 				log.Printf("[WebSocket] Write error for %s: %v", c.UserID, err)
 				return
 			}
@@ -359,119 +482,137 @@ func (c *Client) writePump() {
 	}
 }
 
-func (c *Client) handleMessage(msg *entity.SignalMessage) {
-	switch msg.Type {
-	case "offer", "answer", "ice":
-		c.forwardToPeer(msg)
-	case "ping":
-		c.handlePing()
-	case "chat":
-		c.handleChatMessage(msg)
-	case "media-state":
-		c.handleMediaState(msg)
-	case "screen-share":
-		c.forwardToPeer(msg)
-	case "leave":
-		c.handleLeave(msg)
+func (c *Client) handleMessage(req *protocol.Request) {
+	// clients in the lobby can't signal anyone until a host admits them
+	if c.waiting.Load() {
+		switch req.Type {
+		case protocol.TypePing:
+			c.handlePing()
+		case protocol.TypeLeave:
+			c.handleLeave()
+		default:
+			c.replyError(req, protocol.NewError(protocol.CodeForbidden, "waiting for a host to admit you"))
+		}
+		return
+	}
+
+	switch payload := req.Payload.(type) {
+	case *protocol.SessionDescription, *protocol.ICECandidate:
+		c.forwardToPeer(req)
+	case *protocol.Chat:
+		c.handleChatMessage(req, payload)
+	case *protocol.MediaState:
+		c.handleMediaState(req, payload)
+	case *protocol.ScreenShare:
+		c.handleScreenShare(req, payload)
+	case *protocol.LobbyDecision:
+		c.handleLobbyDecision(req, payload)
+	case *protocol.RoomUpdate:
+		c.handleUpdateRoom(req, payload)
+	case *protocol.Extension:
+		c.handleExtendMeeting(req, payload)
+	case *protocol.RoomLock:
+		c.handleLockRoom(req, payload)
+	case *protocol.Target:
+		switch req.Type {
+		case protocol.TypeDisconnectDevice:
+			c.handleDisconnectDevice(req, payload)
+		case protocol.TypeGrantScreenShare, protocol.TypeDenyScreenShare, protocol.TypeStopPresenter:
+			c.handleShareDecision(req, payload)
+		default:
+			c.handleModeration(req, payload)
+		}
 	default:
-		log.Printf("[WebSocket] 📢 Broadcasting message type '%s'", msg.Type)
-		data, _ := json.Marshal(msg)
-		c.Hub.broadcast <- &BroadcastMessage{
-			RoomID:  c.RoomID,
-			Message: data,
-			Exclude: c.UserID,
+		switch req.Type {
+		case protocol.TypePing:
+			c.handlePing()
+		case protocol.TypeLeave:
+			c.handleLeave()
+		case protocol.TypeListDevices:
+			c.handleListDevices()
+		case protocol.TypeTransferHere:
+			c.handleTransferHere(req)
+		case protocol.TypeSync:
+			c.Hub.sendRoomState(c)
+		case protocol.TypeTakeOverScreen:
+			c.handleTakeOverScreen(req)
+		case protocol.TypeEndMeeting:
+			c.handleEndMeeting(req)
+		default:
+			c.replyError(req, protocol.NewError(protocol.CodeUnknownType, "unhandled message type"))
 		}
 	}
 }
 
-func (c *Client) handleLeave(msg *entity.SignalMessage) {
+func (c *Client) handleLeave() {
 	log.Printf("[WebSocket] Client %s leaving room %s", c.UserID, c.RoomID)
 
-	notification := entity.SignalMessage{
-		Type:   "peer-left",
-		From:   c.UserID,
-		RoomID: c.RoomID,
-		Data: map[string]interface{}{
-			"userId": c.UserID,
-		},
-	}
-
-	data, _ := json.Marshal(notification)
-	c.Hub.broadcast <- &BroadcastMessage{
-		RoomID:  c.RoomID,
-		Message: data,
-	}
-
-	_ = c.Hub.roomUseCase.LeaveRoom(context.Background(), c.RoomID, c.UserID)
-
-	c.Hub.unregister <- c
+	c.leaving.Store(true)
+	// unregistering announces peer-left and removes the participant
+	c.Hub.requestUnregister(c)
 	c.Conn.Close()
 }
 
-func (c *Client) forwardToPeer(msg *entity.SignalMessage) {
-	data, err := json.Marshal(msg)
+// relay publishes a validated client message to the room, or to req.To only.
+// Messages that change the room state carry the version they produced.
+func (c *Client) relay(req *protocol.Request, exclude string, version int64) {
+	data, err := json.Marshal(protocol.Envelope{
+		ID:        req.ID,
+		Type:      req.Type,
+		From:      c.ParticipantID,
+		To:        req.To,
+		RoomID:    c.RoomID,
+		Data:      req.Payload,
+		Version:   version,
+		Timestamp: time.Now(),
+	})
 	if err != nil {
 		log.Printf("[WebSocket] Failed to marshal message: %v", err)
 		return
 	}
 
-	if msg.To != "" {
-		// send to specific peer
-		c.Hub.mu.RLock()
-		if room, exists := c.Hub.rooms[c.RoomID]; exists {
-			if targetClient, exists := room[msg.To]; exists {
-				select {
-				case targetClient.Send <- data:
-					log.Printf("[WebSocket] Forwarded %s to %s", msg.Type, msg.To)
-				default:
-					log.Printf("[WebSocket] Failed to forward to %s, channel full", msg.To)
-				}
-			} else {
-				log.Printf("[WebSocket] Target client %s not found", msg.To)
-			}
-		}
-		c.Hub.mu.RUnlock()
-	} else {
-		// broadcast
-		c.Hub.broadcast <- &BroadcastMessage{
-			RoomID:  c.RoomID,
-			Message: data,
-			Exclude: c.UserID,
-		}
-	}
+	c.Hub.publish(&BroadcastMessage{
+		RoomID:  c.RoomID,
+		Message: data,
+		To:      req.To,
+		Exclude: exclude,
+		Bulk:    protocol.IsBulk(req.Type),
+	})
+}
+
+func (c *Client) forwardToPeer(req *protocol.Request) {
+	// to a specific peer, whichever node it is connected to, or everyone else
+	c.relay(req, c.ParticipantID, 0)
 }
 
 func (c *Client) handlePing() {
 	response := entity.SignalMessage{
-		Type:   "pong",
+		Type:   protocol.TypePong,
 		From:   "server",
 		RoomID: c.RoomID,
 	}
-	data, _ := json.Marshal(response)
-	select {
-	case c.Send <- data:
+	if c.sendMessage(response) {
 		log.Printf("[WebSocket] Pong sent to %s", c.UserID)
-	default:
-		log.Printf("[WebSocket] Failed to send pong to %s", c.UserID)
 	}
 }
 
-func (c *Client) handleChatMessage(msg *entity.SignalMessage) {
-	messageText, ok := msg.Data["message"].(string)
-	if !ok {
-		log.Printf("[WebSocket] Invalid chat message format")
+func (c *Client) handleChatMessage(req *protocol.Request, payload *protocol.Chat) {
+	if err := c.Hub.roomUseCase.Authorize(context.Background(), c.RoomID, c.ParticipantID, entity.CapabilityChat); err != nil {
+		log.Printf("[WebSocket] Chat by %s refused: %v", c.UserID, err)
+		c.replyError(req, err)
 		return
 	}
 
 	participant, err := c.Hub.roomUseCase.GetParticipants(context.Background(), c.RoomID)
 	if err != nil {
 		log.Printf("[WebSocket] Error getting participants: %v", err)
+		c.replyError(req, err)
 		return
 	}
 
 	var userName string
 	for _, p := range participant {
-		if p.UserID == c.UserID {
+		if p.ID == c.ParticipantID {
 			userName = p.DisplayName
 			break
 		}
@@ -481,7 +622,7 @@ func (c *Client) handleChatMessage(msg *entity.SignalMessage) {
 		RoomID:   c.RoomID,
 		UserID:   c.UserID,
 		UserName: userName,
-		Message:  messageText,
+		Message:  payload.Message,
 		Type:     "text",
 	}
 
@@ -489,128 +630,219 @@ func (c *Client) handleChatMessage(msg *entity.SignalMessage) {
 		log.Printf("[WebSocket] Failed to save chat message: %v", err)
 	}
 
-	data, _ := json.Marshal(msg)
-	c.Hub.broadcast <- &BroadcastMessage{
-		RoomID:  c.RoomID,
-		Message: data,
-	}
+	// chat goes to everyone, the sender included
+	req.To = ""
+	c.relay(req, "", 0)
 }
 
-func (c *Client) handleMediaState(msg *entity.SignalMessage) {
-	participants, err := c.Hub.roomUseCase.GetParticipants(context.Background(), c.RoomID)
+func (c *Client) handleMediaState(req *protocol.Request, payload *protocol.MediaState) {
+	ctx := context.Background()
+	p, err := c.Hub.roomUseCase.GetParticipant(ctx, c.RoomID, c.ParticipantID)
 	if err != nil {
-		log.Printf("[WebSocket] Error getting participants: %v", err)
+		log.Printf("[WebSocket] Error getting participant: %v", err)
+		c.replyError(req, err)
 		return
 	}
 
-	for _, p := range participants {
-		if p.UserID == c.UserID {
-			if muted, ok := msg.Data["isMuted"].(bool); ok {
-				p.IsMuted = muted
-			}
-			if videoOff, ok := msg.Data["isVideoOff"].(bool); ok {
-				p.IsVideoOff = videoOff
-			}
-			_ = c.Hub.roomUseCase.UpdateParticipantState(context.Background(), p)
-			break
-		}
+	if payload.IsMuted != nil {
+		p.IsMuted = *payload.IsMuted
+	}
+	if payload.IsVideoOff != nil {
+		p.IsVideoOff = *payload.IsVideoOff
 	}
 
-	data, _ := json.Marshal(msg)
-	c.Hub.broadcast <- &BroadcastMessage{
-		RoomID:  c.RoomID,
-		Message: data,
-		Exclude: c.UserID,
+	// fails once they were removed, so their state isn't announced either
+	if err := c.Hub.roomUseCase.UpdateParticipantState(ctx, p); err != nil {
+		log.Printf("[WebSocket] Error updating participant %s: %v", c.ParticipantID, err)
+		c.replyError(req, err)
+		return
 	}
+
+	req.To = ""
+	c.relay(req, c.ParticipantID, c.Hub.nextVersion(c.RoomID))
 }
 
 func (h *SignalingHub) GetRoomClients(roomID string) map[string]*Client {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	if room, exists := h.rooms[roomID]; exists {
-		clients := make(map[string]*Client)
-		for k, v := range room {
-			clients[k] = v
+	var clients map[string]*Client
+	h.query(roomID, func(s *shard) {
+		if r := s.rooms[roomID]; r != nil {
+			clients = make(map[string]*Client, len(r.clients))
+			for k, v := range r.clients {
+				clients[k] = v
+			}
 		}
-		return clients
+	})
+	return clients
+}
+
+func (h *SignalingHub) requestUnregister(client *Client) {
+	h.dispatch(client.RoomID, func(s *shard) { s.unregisterClient(client) })
+}
+
+func (c *Client) sendMessage(msg entity.SignalMessage) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[WebSocket] Failed to marshal message: %v", err)
+		return false
 	}
-	return nil
+
+	return c.queue(data, protocol.IsBulk(msg.Type))
 }
 
-func (c *Client) handleScreenShare(msg *entity.SignalMessage) {
-	isSharing := msg.Data["isSharing"].(bool)
+// queue encodes a JSON message for this client and hands it to the write
+// pump without blocking.
+func (c *Client) queue(data []byte, bulk bool) bool {
+	frame, err := c.codec.FromJSON(data)
+	if err != nil {
+		log.Printf("[WebSocket] Failed to encode message for %s: %v", c.UserID, err)
+		return false
+	}
 
-	if isSharing {
-		// request to START screen share
-		err := c.Hub.roomUseCase.StartScreenShare(
-			context.Background(),
-			c.RoomID,
-			c.UserID,
-		)
+	return c.enqueue(frame, bulk)
+}
 
-		if err != nil {
-			errorMsg := entity.SignalMessage{
-				Type:   "screen-share-error",
-				From:   "server",
-				RoomID: c.RoomID,
-				Data: map[string]interface{}{
-					"error": err.Error(),
-				},
-			}
+// replyError reports a failed request back to the client that sent it.
+func (c *Client) replyError(req *protocol.Request, err error) {
+	var perr *protocol.Error
+	if !errors.As(err, &perr) {
+		perr = protocol.NewError(errorCode(err), err.Error())
+		perr.Details = errorDetails(err)
+	}
+	perr.CorrelationID = req.ID
+	c.sendError(perr)
+}
 
-			data, _ := json.Marshal(errorMsg)
-			select {
-			case c.Send <- data:
-				log.Printf("[WebSocket] Sent screen share error to %s: %s", c.UserID, err.Error())
-			default:
-			}
-			return
-		}
-	} else {
-		// request to STOP screen share
-		err := c.Hub.roomUseCase.StopScreenShare(
-			context.Background(),
-			c.RoomID,
-			c.UserID,
-		)
+func (c *Client) sendError(perr *protocol.Error) {
+	c.sendMessage(entity.SignalMessage{
+		Type:      protocol.TypeError,
+		From:      "server",
+		RoomID:    c.RoomID,
+		Data:      perr.Fields(),
+		Timestamp: time.Now(),
+	})
+}
 
-		if err != nil {
-			log.Printf("[WebSocket] Error stopping screen share: %v", err)
-			return
-		}
+// errorCode maps use case errors to protocol error codes.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, usecase.ErrNotHost),
+		errors.Is(err, usecase.ErrCannotModerateHost),
+		errors.Is(err, usecase.ErrGuestsNotAllowed),
+		errors.Is(err, usecase.ErrBanned),
+		errors.Is(err, usecase.ErrNotScreenSharer),
+		errors.Is(err, usecase.ErrNotPermitted),
+		errors.Is(err, usecase.ErrMeetingOver),
+		errors.Is(err, usecase.ErrNotOwnDevice),
+		errors.Is(err, usecase.ErrPasscodeRequired),
+		errors.Is(err, usecase.ErrWrongPasscode),
+		errors.Is(err, usecase.ErrRoomLocked),
+		errors.Is(err, usecase.ErrInvalidInvite),
+		errors.Is(err, usecase.ErrInviteUsedUp),
+		errors.Is(err, usecase.ErrInviteNotForYou),
+		errors.Is(err, usecase.ErrInviteSignInRequired):
+		return protocol.CodeForbidden
+	case errors.Is(err, usecase.ErrTooManyAttempts):
+		return protocol.CodeRateLimited
+	case errors.Is(err, usecase.ErrInvalidRoomUpdate),
+		errors.Is(err, usecase.ErrBelowParticipantCount),
+		errors.Is(err, usecase.ErrInvalidExtension):
+		return protocol.CodeInvalidPayload
+	case errors.Is(err, repository.ErrRoomNotFound),
+		errors.Is(err, repository.ErrParticipantNotFound),
+		errors.Is(err, usecase.ErrParticipantMissing),
+		errors.Is(err, usecase.ErrNoShareRequest),
+		errors.Is(err, usecase.ErrNotPresenting):
+		return protocol.CodeNotFound
+	case errors.Is(err, usecase.ErrScreenShareBusy),
+		errors.Is(err, repository.ErrRoomChanged),
+		errors.Is(err, usecase.ErrNoTimeLimit):
+		return protocol.CodeConflict
+	case errors.Is(err, usecase.ErrRoomFull):
+		return protocol.CodeRoomFull
+	default:
+		return protocol.CodeInternal
 	}
+}
 
-	data, _ := json.Marshal(msg)
-	c.Hub.broadcast <- &BroadcastMessage{
-		RoomID:  c.RoomID,
-		Message: data,
-		Exclude: c.UserID,
+// errorDetails spells out which room setting refused a request.
+func errorDetails(err error) map[string]interface{} {
+	var policyErr *usecase.PolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	return map[string]interface{}{
+		"capability": policyErr.Capability,
+		"role":       policyErr.Role,
+	}
+}
+
+// rejectConnection explains why a connection is refused and closes it before
+// the client is registered.
+func rejectConnection(c *websocket.Conn, codec protocol.Codec, perr *protocol.Error, closeCode int) {
+	data, _ := json.Marshal(protocol.Envelope{
+		Type:      protocol.TypeError,
+		From:      "server",
+		Data:      perr,
+		Timestamp: time.Now(),
+	})
+	if frame, err := codec.FromJSON(data); err == nil {
+		frameType := websocket.TextMessage
+		if codec.Binary() {
+			frameType = websocket.BinaryMessage
+		}
+		c.WriteMessage(frameType, frame)
 	}
+	c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, perr.Code))
+	c.Close()
 }
 
 func (h *SignalingHub) Shutdown() {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.shutdownOnce.Do(h.shutdown)
+}
 
+func (h *SignalingHub) shutdown() {
 	log.Println("[Hub] Shutting down SignalingHub...")
 
-	for roomID, room := range h.rooms {
-		for clientID, client := range room {
-			select {
-			case <-client.Send:
-			default:
-				close(client.Send)
+	close(h.done)
+
+	// the shards are stopped from here on, so their rooms can be read directly
+	h.workers.Wait()
+
+	ctx := context.Background()
+	for _, s := range h.shards {
+		for roomID, r := range s.rooms {
+			for participantID, client := range r.clients {
+				client.closeSend()
+
+				presence := entity.Presence{NodeID: h.nodeID, ClientID: client.ID}
+				if removed, err := h.presenceRepo.RemovePresence(ctx, roomID, participantID, presence); err == nil && removed {
+					h.handleDeparture(roomID, participantID, client.UserID)
+				}
+				log.Printf("[Hub] Closed client %s in room %s", participantID, roomID)
+			}
+
+			for _, client := range r.lobby {
+				client.closeSend()
+			}
+
+			// nobody is left on this node to finish the grace periods
+			for participantID, sc := range r.suspended {
+				sc.timer.Stop()
+				if removed, err := h.presenceRepo.RemovePresence(ctx, roomID, participantID, sc.presence); err == nil && removed {
+					h.handleDeparture(roomID, participantID, sc.userID)
+				}
 			}
-			log.Printf("[Hub] Closed client %s in room %s", clientID, roomID)
 		}
+		s.rooms = make(map[string]*room)
 	}
 
-	h.rooms = make(map[string]map[string]*Client)
+	if err := h.presenceRepo.RemoveNode(ctx, h.nodeID); err != nil {
+		log.Printf("[Hub] Failed to remove node %s: %v", h.nodeID, err)
+	}
 
-	close(h.register)
-	close(h.unregister)
-	close(h.broadcast)
+	if err := h.broker.Close(); err != nil {
+		log.Printf("[Hub] Failed to close broker: %v", err)
+	}
 
 	log.Println("[Hub] SignalingHub shutdown complete")
 }
@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"bincang-visual/internal/delivery/websocket/protocol"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// nextVersion bumps the room's state version for a broadcast that changes
+// the room state. It returns 0, which leaves the message unversioned, when
+// the version can't be bumped.
+func (h *SignalingHub) nextVersion(roomID string) int64 {
+	version, err := h.roomUseCase.NextStateVersion(context.Background(), roomID)
+	if err != nil {
+		log.Printf("[Hub] Failed to bump state version of room %s: %v", roomID, err)
+		return 0
+	}
+	return version
+}
+
+// sendRoomState sends the client a snapshot of the room. Clients apply
+// updates with a higher version on top of it and ask for a new one with
+// "sync" when they notice a gap.
+func (h *SignalingHub) sendRoomState(client *Client) {
+	data, err := h.loadRoomState(client.RoomID)
+	if err != nil {
+		log.Printf("[Hub] Failed to get state of room %s: %v", client.RoomID, err)
+		client.sendError(protocol.NewError(protocol.CodeInternal, "failed to load room state"))
+		return
+	}
+
+	client.queue(data, false)
+}
+
+// loadRoomState returns the encoded "room-state" message of the room.
+func (h *SignalingHub) loadRoomState(roomID string) ([]byte, error) {
+	state, err := h.roomUseCase.GetRoomState(context.Background(), roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(protocol.Envelope{
+		Type:      "room-state",
+		From:      "server",
+		RoomID:    roomID,
+		Data:      state,
+		Version:   state.Version,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal room state: %w", err)
+	}
+
+	return data, nil
+}
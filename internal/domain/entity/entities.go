@@ -5,34 +5,255 @@ import (
 	"time"
 )
 
+// AnonymousHostID is the host of rooms created without signing in.
+const AnonymousHostID = "anonymous"
+
 type Room struct {
 	ID              string                 `json:"id"`
+	Code            string                 `json:"code,omitempty"` // short alias for the ID, e.g. abc-defg-hij
 	Name            string                 `json:"name"`
 	HostID          string                 `json:"hostId"`
 	CreatedAt       time.Time              `json:"createdAt"`
 	MaxParticipants int                    `json:"maxParticipants"`
 	IsRecording     bool                   `json:"isRecording"`
+	RecordingID     string                 `json:"recordingId,omitempty"` // the recording in progress
+	ExtendedMinutes int                    `json:"extendedMinutes,omitempty"`
+	Personal        bool                   `json:"personal,omitempty"`  // the host's permanent room
+	StartedAt       time.Time              `json:"startedAt,omitempty"` // of a personal room's current session
+	HasPasscode     bool                   `json:"hasPasscode"`         // the hash is kept apart from the room
+	Locked          bool                   `json:"locked"`              // nobody new gets in
 	Settings        RoomSettings           `json:"settings"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Version         int64                  `json:"version"` // bumped on every update
 }
 
 type RoomSettings struct {
 	AllowScreenShare bool `json:"allowScreenShare"`
 	AllowChat        bool `json:"allowChat"`
 	WaitingRoom      bool `json:"waitingRoom"`
+	AllowGuests      bool `json:"allowGuests"` // join without signing in
 	RecordingEnabled bool `json:"recordingEnabled"`
 	MaxDuration      int  `json:"maxDuration"` // in minutes
+
+	ScreenShare ScreenSharePolicy `json:"screenShare"`
+
+	// Roles overrides the switches above for one role, e.g. to mute chat
+	// for guests only.
+	Roles map[Role]RolePermissions `json:"roles,omitempty"`
+}
+
+// Role is how much say a participant has in a room.
+type Role string
+
+const (
+	RoleHost        Role = "host"
+	RoleParticipant Role = "participant"
+	RoleGuest       Role = "guest" // joined without signing in
+
+	// a co-host has the host's powers inside the room, but only the host
+	// changes, locks or ends it
+	RoleCoHost Role = "co-host"
+	RoleViewer Role = "viewer" // watches, never presents
+)
+
+// Capability is something a room's settings can allow or forbid.
+type Capability string
+
+const (
+	CapabilityChat        Capability = "chat"
+	CapabilityScreenShare Capability = "screen-share"
+	CapabilityRecord      Capability = "record"
+)
+
+// RolePermissions overrides the room-wide settings for one role. A nil field
+// keeps the room-wide default.
+type RolePermissions struct {
+	Chat        *bool `json:"chat,omitempty"`
+	ScreenShare *bool `json:"screenShare,omitempty"`
+	Record      *bool `json:"record,omitempty"`
+}
+
+func (p RolePermissions) get(capability Capability) *bool {
+	switch capability {
+	case CapabilityChat:
+		return p.Chat
+	case CapabilityScreenShare:
+		return p.ScreenShare
+	case CapabilityRecord:
+		return p.Record
+	default:
+		return nil
+	}
+}
+
+// Allows reports whether role may use capability. Hosts may chat and share
+// their screen whatever the room-wide switches say, viewers never share, and
+// only hosts record.
+// A role override replaces those defaults, but nobody records in a room
+// with recording disabled.
+func (s RoomSettings) Allows(role Role, capability Capability) bool {
+	if capability == CapabilityRecord && !s.RecordingEnabled {
+		return false
+	}
+
+	if override := s.Roles[role].get(capability); override != nil {
+		return *override
+	}
+
+	switch capability {
+	case CapabilityChat:
+		return role == RoleHost || s.AllowChat
+	case CapabilityScreenShare:
+		return role == RoleHost || (role != RoleViewer && s.AllowScreenShare)
+	case CapabilityRecord:
+		return role == RoleHost
+	default:
+		return false
+	}
+}
+
+// RoomDefaults is what a new room starts with before its creator picks
+// anything.
+type RoomDefaults struct {
+	MaxParticipants int          `json:"maxParticipants"`
+	Settings        RoomSettings `json:"settings"`
+}
+
+// RoomTemplate is a named starting point for rooms, e.g. "standup".
+type RoomTemplate struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"ownerId,omitempty"` // empty for the built-in templates
+	CreatedAt time.Time `json:"createdAt"`
+	RoomDefaults
+}
+
+// DefaultRoomSettings are the settings of a room created without any: chat
+// and screen sharing are on, everything else is opt-in.
+func DefaultRoomSettings() RoomSettings {
+	return RoomSettings{
+		AllowScreenShare: true,
+		AllowChat:        true,
+	}
+}
+
+// ScreenSharePolicy decides how many participants may present at once and
+// whether they need a host's approval first. Hosts never need approval.
+type ScreenSharePolicy struct {
+	MaxPresenters   int  `json:"maxPresenters,omitempty"` // 0 means a single presenter
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+// Presenters returns how many participants may share their screen at once.
+func (p ScreenSharePolicy) Presenters() int {
+	if p.MaxPresenters <= 0 {
+		return 1
+	}
+	return p.MaxPresenters
+}
+
+// EndsAt returns when the meeting reaches its maximum duration plus any
+// extensions, or the zero time if it may run for as long as the room exists.
+// A personal room's meeting starts with its session rather than the room.
+func (r *Room) EndsAt() time.Time {
+	if r.Settings.MaxDuration <= 0 {
+		return time.Time{}
+	}
+
+	start := r.CreatedAt
+	if r.Personal {
+		if r.StartedAt.IsZero() {
+			return time.Time{}
+		}
+		start = r.StartedAt
+	}
+
+	minutes := r.Settings.MaxDuration + r.ExtendedMinutes
+	return start.Add(time.Duration(minutes) * time.Minute)
 }
 
+const (
+	ParticipantStatusActive  = "active"
+	ParticipantStatusWaiting = "waiting" // parked in the lobby until a host admits
+)
+
+// Participant is one connection in a room. A user who joins from a laptop and
+// a phone is two participants sharing a UserID.
 type Participant struct {
+	ID            string    `json:"id"`
 	UserID        string    `json:"userId"`
 	RoomID        string    `json:"roomId"`
 	DisplayName   string    `json:"displayName"`
 	JoinedAt      time.Time `json:"joinedAt"`
 	IsHost        bool      `json:"isHost"`
+	IsGuest       bool      `json:"isGuest"`
+	IsCoHost      bool      `json:"isCoHost,omitempty"` // IsHost too, invited as co-host
+	IsViewer      bool      `json:"isViewer,omitempty"`
 	IsMuted       bool      `json:"isMuted"`
 	IsVideoOff    bool      `json:"isVideoOff"`
 	IsScreenShare bool      `json:"isScreenShare"`
+	Status        string    `json:"status"`
+	Device        string    `json:"device,omitempty"` // label the client picked, e.g. "Phone"
+}
+
+// Role returns the participant's role for permission checks.
+func (p *Participant) Role() Role {
+	switch {
+	case p.IsHost:
+		return RoleHost
+	case p.IsViewer:
+		return RoleViewer
+	case p.IsGuest:
+		return RoleGuest
+	default:
+		return RoleParticipant
+	}
+}
+
+// Invite lets whoever holds its signed token into a room as Role, skipping
+// the waiting room and passcode. Uses counts the users who redeemed it.
+type Invite struct {
+	ID        string    `json:"id"`
+	RoomID    string    `json:"roomId"`
+	Role      Role      `json:"role"`
+	Email     string    `json:"email,omitempty"`   // only this signed in user may use it
+	MaxUses   int       `json:"maxUses,omitempty"` // 0 means unlimited
+	Uses      int       `json:"uses"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Presence records which node currently holds a participant's live connection.
+// A disconnected entry is kept until ResumeBy so the client can resume.
+type Presence struct {
+	NodeID       string `json:"nodeId"`
+	ClientID     string `json:"clientId"`
+	Disconnected bool   `json:"disconnected,omitempty"`
+	ResumeBy     int64  `json:"resumeBy,omitempty"` // unix millis
+}
+
+// Session lets a client that lost its connection resume its place in the
+// room with the resume token it was given on join.
+type Session struct {
+	Token         string `json:"token"`
+	RoomID        string `json:"roomId"`
+	ParticipantID string `json:"participantId"`
+	UserID        string `json:"userId"`
+	DisplayName   string `json:"displayName"`
+	IsGuest       bool   `json:"isGuest"`
+}
+
+// RoomState is everything a client needs to render a room it just joined.
+// Version is the room's state version when the snapshot was taken.
+type RoomState struct {
+	Version       int64          `json:"version"`
+	Room          *Room          `json:"room"`
+	Participants  []*Participant `json:"participants"`
+	Presenters    []string       `json:"presenters"`
+	ShareRequests []string       `json:"shareRequests,omitempty"` // waiting for a host, oldest first
+	IsRecording   bool           `json:"isRecording"`
+	Chat          []*ChatMessage `json:"chat"`
 }
 
 type ChatMessage struct {
@@ -51,6 +272,7 @@ type SignalMessage struct {
 	To        string                 `json:"to,omitempty"`
 	RoomID    string                 `json:"roomId"`
 	Data      map[string]interface{} `json:"data,omitempty"`
+	Version   int64                  `json:"version,omitempty"` // room state version, set on state changes
 	Timestamp time.Time              `json:"timestamp"`
 }
 
@@ -3,28 +3,184 @@ package repository
 import (
 	"bincang-visual/internal/domain/entity"
 	"context"
+	"errors"
 	"time"
 )
 
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomChanged  = errors.New("room was changed by someone else")
+	ErrRoomFull     = errors.New("room is full")
+	ErrUserBanned   = errors.New("user is banned from the room")
+	ErrRoomLocked   = errors.New("room is locked")
+	ErrCodeTaken    = errors.New("room code is taken")
+	ErrRoomExists   = errors.New("room already exists")
+	ErrNotWaiting   = errors.New("participant is not waiting")
+
+	ErrParticipantNotFound = errors.New("participant not found")
+
+	ErrTemplateNotFound = errors.New("room template not found")
+
+	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteUsedUp   = errors.New("invite has no uses left")
+)
+
 type RoomRepository interface {
+	// Create claims room.Code for the room along with storing it, failing
+	// with ErrCodeTaken if another room holds the code, or ErrRoomExists if
+	// the room does. A ttl of 0 keeps the room until it is deleted.
 	Create(ctx context.Context, room *entity.Room, ttl time.Duration) error
 	Get(ctx context.Context, roomID string) (*entity.Room, error)
+	// ResolveCode returns the ID of the room with code, or ErrRoomNotFound.
+	ResolveCode(ctx context.Context, code string) (string, error)
+	// ClaimCode makes code another alias of the room for as long as it
+	// exists, failing with ErrCodeTaken if another room holds it.
+	ClaimCode(ctx context.Context, code, roomID string) error
+	// ReleaseCode drops code unless it went to another room meanwhile.
+	ReleaseCode(ctx context.Context, code, roomID string) error
+	// Update saves room only if the stored room still has room.Version,
+	// failing with ErrRoomChanged otherwise, and bumps room.Version.
 	Update(ctx context.Context, room *entity.Room) error
+	// Delete removes the room together with its participants, lobby, chat,
+	// screen share and presence in one go.
 	Delete(ctx context.Context, roomID string) error
+	// ClearSession removes what a meeting leaves behind, its participants,
+	// lobby, chat, screen share and presence, but keeps the room. Bans last
+	// as long as the room, so they stay too.
+	ClearSession(ctx context.Context, roomID string) error
 	Exists(ctx context.Context, roomID string) (bool, error)
+	// ExtendTTL keeps the room for at least duration, it never shortens it.
 	ExtendTTL(ctx context.Context, roomID string, duration time.Duration) error
-	SetScreenSharer(ctx context.Context, roomID, userID string) error
-	GetScreenSharer(ctx context.Context, roomID string) (string, error)
-	ClearScreenSharer(ctx context.Context, roomID string) error
+	// AddPresenter lets participantID share its screen unless max others
+	// already are, and reports whether it is presenting now.
+	AddPresenter(ctx context.Context, roomID, participantID string, max int) (bool, error)
+	RemovePresenter(ctx context.Context, roomID, participantID string) (bool, error)
+	GetPresenters(ctx context.Context, roomID string) ([]string, error)
+	AddShareRequest(ctx context.Context, roomID, participantID string) error
+	RemoveShareRequest(ctx context.Context, roomID, participantID string) (bool, error)
+	// GetShareRequests returns the pending requests, oldest first.
+	GetShareRequests(ctx context.Context, roomID string) ([]string, error)
+	// ClaimNotice reports true to the first caller only, so a notice about
+	// the room goes out once however many nodes notice it.
+	ClaimNotice(ctx context.Context, roomID, notice string, ttl time.Duration) (bool, error)
+	// SetPasscode keeps the hash of the room's passcode for as long as the
+	// room exists.
+	SetPasscode(ctx context.Context, roomID, hash string) error
+	GetPasscode(ctx context.Context, roomID string) (string, error)
+	// ReservePasscodeAttempt counts a passcode attempt from ip, across rooms,
+	// and against the room before the passcode is checked. It reports false,
+	// counting nothing, when either used up its attempts for the window.
+	ReservePasscodeAttempt(ctx context.Context, roomID, ip string, maxPerIP, maxPerRoom int, window time.Duration) (bool, error)
+	// ReleasePasscodeAttempt takes back an attempt whose passcode was right.
+	ReleasePasscodeAttempt(ctx context.Context, roomID, ip string) error
+	// CreateInvite keeps the invite for as long as the room exists.
+	CreateInvite(ctx context.Context, invite *entity.Invite) error
+	GetInvite(ctx context.Context, roomID, inviteID string) (*entity.Invite, error)
+	// ListInvites returns the room's unexpired invites, oldest first.
+	ListInvites(ctx context.Context, roomID string) ([]*entity.Invite, error)
+	RevokeInvite(ctx context.Context, roomID, inviteID string) error
+	// RedeemInvite counts userID as a user of the invite, unless it already
+	// is, failing with ErrInviteUsedUp once MaxUses others have used it or
+	// ErrInviteNotFound if it was revoked.
+	RedeemInvite(ctx context.Context, invite *entity.Invite, userID string) error
+	AddBan(ctx context.Context, roomID, userID string) error
+	IsBanned(ctx context.Context, roomID, userID string) (bool, error)
+	// NextVersion increments and returns the room's state version.
+	NextVersion(ctx context.Context, roomID string) (int64, error)
+	GetVersion(ctx context.Context, roomID string) (int64, error)
+	// IdleRooms returns up to limit indexed rooms without activity since
+	// before, least recently active first. Their room key may be gone.
+	IdleRooms(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// TouchRoom marks an indexed room as active now.
+	TouchRoom(ctx context.Context, roomID string) error
+	// ListHostRooms returns up to limit of the rooms hostID created, newest
+	// first, starting after cursor unless it is nil. Rooms that expired are
+	// left out and dropped from the indexes.
+	ListHostRooms(ctx context.Context, hostID string, after *RoomCursor, limit int) ([]*entity.Room, error)
+	// ActiveRooms reports which of roomIDs have a meeting going on, that is
+	// anybody admitted to them.
+	ActiveRooms(ctx context.Context, roomIDs []string) ([]bool, error)
+}
+
+// RoomCursor marks the room a listing of rooms left off at.
+type RoomCursor struct {
+	CreatedAt time.Time
+	RoomID    string
 }
 
+// ParticipantRepository keys participants, waiting or admitted, by their
+// participant ID.
 type ParticipantRepository interface {
+	// JoinRoom adds participant, or parks it in the lobby while its status is
+	// waiting, in one step with the checks guarding the room. It fails with
+	// ErrRoomNotFound, ErrUserBanned, ErrRoomLocked or ErrRoomFull; max
+	// counts admitted participants only, and a participant already in doesn't
+	// count twice. A locked room still lets in the host and users who are
+	// already in it on another connection.
+	JoinRoom(ctx context.Context, participant *entity.Participant, max int) error
+	// AdmitParticipant moves a waiting participant into the room with the
+	// same checks as JoinRoom. It fails with ErrNotWaiting when the lobby
+	// entry is already gone, and drops the entry of a banned user.
+	AdmitParticipant(ctx context.Context, participant *entity.Participant, max int) error
 	AddParticipant(ctx context.Context, participant *entity.Participant) error
-	RemoveParticipant(ctx context.Context, roomID, userId string) error
+	RemoveParticipant(ctx context.Context, roomID, participantID string) error
 	GetParticipants(ctx context.Context, roomID string) ([]*entity.Participant, error)
-	GetParticipant(ctx context.Context, roomID, userId string) (*entity.Participant, error)
+	GetParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error)
+	// UpdateParticipant fails with ErrParticipantNotFound once the
+	// participant left, so a late update can't put them back.
 	UpdateParticipant(ctx context.Context, participant *entity.Participant) error
 	GetParticipantCount(ctx context.Context, roomID string) (int, error)
+	// CountParticipants returns the participant count of each of roomIDs.
+	CountParticipants(ctx context.Context, roomIDs []string) ([]int, error)
+	// RemoveFromLobby reports whether the participant was still waiting.
+	RemoveFromLobby(ctx context.Context, roomID, participantID string) (bool, error)
+	GetLobby(ctx context.Context, roomID string) ([]*entity.Participant, error)
+	GetLobbyParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error)
+}
+
+type PresenceRepository interface {
+	SetPresence(ctx context.Context, roomID, participantID string, presence entity.Presence) error
+	// RemovePresence only removes the entry if it still matches presence,
+	// and reports whether it did.
+	RemovePresence(ctx context.Context, roomID, participantID string, presence entity.Presence) (bool, error)
+	// ReplacePresence swaps the entry for next only if it still matches
+	// current, and reports whether it did.
+	ReplacePresence(ctx context.Context, roomID, participantID string, current, next entity.Presence) (bool, error)
+	GetPresence(ctx context.Context, roomID string) (map[string]entity.Presence, error)
+	NodeHeartbeat(ctx context.Context, nodeID string, ttl time.Duration) error
+	RemoveNode(ctx context.Context, nodeID string) error
+	IsNodeAlive(ctx context.Context, nodeID string) (bool, error)
+}
+
+// LockRepository hands out locks shared by every replica. A lock expires
+// after its ttl in case its owner dies holding it.
+type LockRepository interface {
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLock only releases the lock if owner still holds it.
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
+
+// TemplateRepository keeps room templates and room defaults by their owner,
+// a user so far.
+type TemplateRepository interface {
+	SaveTemplate(ctx context.Context, template *entity.RoomTemplate) error
+	GetTemplate(ctx context.Context, ownerID, templateID string) (*entity.RoomTemplate, error)
+	// ListTemplates returns the owner's templates, oldest first.
+	ListTemplates(ctx context.Context, ownerID string) ([]*entity.RoomTemplate, error)
+	DeleteTemplate(ctx context.Context, ownerID, templateID string) error
+	// GetRoomDefaults returns nil when the owner never set any.
+	GetRoomDefaults(ctx context.Context, ownerID string) (*entity.RoomDefaults, error)
+	SetRoomDefaults(ctx context.Context, ownerID string, defaults *entity.RoomDefaults) error
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *entity.Session) error
+	GetSession(ctx context.Context, token string) (*entity.Session, error)
+	DeleteSession(ctx context.Context, token string) error
+	// BufferMessage keeps at most limit messages for a disconnected session.
+	BufferMessage(ctx context.Context, token string, message []byte, limit int, ttl time.Duration) error
+	// DrainBuffer returns the buffered messages in order and clears them.
+	DrainBuffer(ctx context.Context, token string) ([][]byte, error)
 }
 
 type ChatRepository interface {
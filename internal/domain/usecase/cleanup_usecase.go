@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// MeetingEndedExpired is sent when a room's key ran out while clients
+	// were still connected.
+	MeetingEndedExpired = "expired"
+
+	// empty rooms older than this are closed even when rooms are kept open
+	maxEmptyRoomAge = 24 * time.Hour
+)
+
+// CleanupReport counts what one cleanup run did.
+type CleanupReport struct {
+	Checked           int
+	Closed            int
+	Orphaned          int // rooms whose key expired, leaving the rest behind
+	StaleParticipants int
+}
+
+// CleanupUseCase reaps what crashed nodes and expired rooms leave behind in
+// Redis. It relies on the room index, so rooms nobody touched are found
+// without scanning the keyspace.
+type CleanupUseCase struct {
+	rooms        *RoomUseCase
+	presenceRepo repository.PresenceRepository
+}
+
+func NewCleanupUseCase(rooms *RoomUseCase, presenceRepo repository.PresenceRepository) *CleanupUseCase {
+	return &CleanupUseCase{
+		rooms:        rooms,
+		presenceRepo: presenceRepo,
+	}
+}
+
+// CleanupIdleRooms looks at up to limit rooms without activity for idleFor.
+// Rooms that stay are marked active again so the next run moves on.
+func (uc *CleanupUseCase) CleanupIdleRooms(ctx context.Context, idleFor time.Duration, limit int) (CleanupReport, error) {
+	var report CleanupReport
+
+	roomIDs, err := uc.rooms.roomRepo.IdleRooms(ctx, time.Now().Add(-idleFor), limit)
+	if err != nil {
+		return report, fmt.Errorf("failed to list idle rooms: %w", err)
+	}
+
+	for _, roomID := range roomIDs {
+		report.Checked++
+		if err := uc.cleanupRoom(ctx, roomID, &report); err != nil {
+			log.Printf("[UseCase] Failed to clean up room %s: %v", roomID, err)
+		}
+	}
+
+	return report, nil
+}
+
+func (uc *CleanupUseCase) cleanupRoom(ctx context.Context, roomID string, report *CleanupReport) error {
+	room, err := uc.rooms.roomRepo.Get(ctx, roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) {
+		if err := uc.rooms.roomRepo.Delete(ctx, roomID); err != nil {
+			return fmt.Errorf("failed to delete orphaned keys: %w", err)
+		}
+		report.Orphaned++
+		uc.rooms.meetingEnded(roomID, MeetingEndedExpired)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	remaining, removed, err := uc.reconcileParticipants(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	report.StaleParticipants += removed
+
+	if checkDuration(room) != nil {
+		ended, err := uc.rooms.EndExpiredMeeting(ctx, roomID)
+		if ended {
+			report.Closed++
+		}
+		return err
+	}
+
+	// the stale participants were the last to leave, or nobody came for a
+	// day; a personal room waits for its host however long it takes
+	lastLeft := removed > 0 && (room.Personal || !uc.rooms.config.Rooms.KeepEmpty)
+	abandoned := !room.Personal && time.Since(room.CreatedAt) > maxEmptyRoomAge
+	if remaining == 0 && (lastLeft || abandoned) {
+		if err := uc.rooms.closeRoom(ctx, room, MeetingEndedEmpty); err != nil {
+			return err
+		}
+		report.Closed++
+		return nil
+	}
+
+	return uc.rooms.roomRepo.TouchRoom(ctx, roomID)
+}
+
+// reconcileParticipants removes the participants no live connection stands
+// for anymore: nobody registered them, or their node died. Participants
+// holding on to a dropped connection are left to their node, which lets them
+// go once the grace period is over.
+func (uc *CleanupUseCase) reconcileParticipants(ctx context.Context, roomID string) (remaining, removed int, err error) {
+	participants, err := uc.rooms.participantRepo.GetParticipants(ctx, roomID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get participants: %w", err)
+	}
+	if len(participants) == 0 {
+		return 0, 0, nil
+	}
+
+	presence, err := uc.presenceRepo.GetPresence(ctx, roomID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get presence: %w", err)
+	}
+
+	alive := make(map[string]bool)
+	for _, participant := range participants {
+		if p, ok := presence[participant.ID]; ok {
+			isAlive, checked := alive[p.NodeID]
+			if !checked {
+				if isAlive, err = uc.presenceRepo.IsNodeAlive(ctx, p.NodeID); err != nil {
+					return 0, 0, fmt.Errorf("failed to check node %s: %w", p.NodeID, err)
+				}
+				alive[p.NodeID] = isAlive
+			}
+			if isAlive {
+				continue
+			}
+
+			// a node that just came back may have claimed it in the meantime
+			if ok, err := uc.presenceRepo.RemovePresence(ctx, roomID, participant.ID, p); err != nil || !ok {
+				continue
+			}
+		}
+
+		if err := uc.removeStaleParticipant(ctx, roomID, participant.ID); err != nil {
+			return 0, 0, err
+		}
+		removed++
+	}
+
+	return len(participants) - removed, removed, nil
+}
+
+func (uc *CleanupUseCase) removeStaleParticipant(ctx context.Context, roomID, participantID string) error {
+	if err := uc.rooms.removeParticipant(ctx, roomID, participantID); err != nil {
+		return err
+	}
+
+	// nobody is left to stop sharing for them
+	if _, err := uc.rooms.roomRepo.RemovePresenter(ctx, roomID, participantID); err != nil {
+		log.Printf("[UseCase] Failed to remove presenter %s: %v", participantID, err)
+	}
+	if _, err := uc.rooms.roomRepo.RemoveShareRequest(ctx, roomID, participantID); err != nil {
+		log.Printf("[UseCase] Failed to remove share request of %s: %v", participantID, err)
+	}
+
+	log.Printf("[UseCase] Removed stale participant %s from room %s", participantID, roomID)
+	return nil
+}
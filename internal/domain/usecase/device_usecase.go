@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"log"
+)
+
+var ErrNotOwnDevice = errors.New("participant is not another of your devices")
+
+// GetDevices returns every participant of the user behind participantID,
+// that one included. Guests get a new identity per connection, so they only
+// ever have one device.
+func (uc *RoomUseCase) GetDevices(ctx context.Context, roomID, participantID string) ([]*entity.Participant, error) {
+	self, err := uc.participantRepo.GetParticipant(ctx, roomID, participantID)
+	if err != nil {
+		return nil, ErrParticipantMissing
+	}
+
+	participants, err := uc.GetParticipants(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]*entity.Participant, 0, 2)
+	for _, p := range participants {
+		if p.UserID == self.UserID {
+			devices = append(devices, p)
+		}
+	}
+	return devices, nil
+}
+
+// DisconnectDevice removes another device of the same user from the room.
+func (uc *RoomUseCase) DisconnectDevice(ctx context.Context, roomID, participantID, targetID string) (*entity.Participant, error) {
+	if targetID == participantID {
+		return nil, ErrNotOwnDevice
+	}
+
+	devices, err := uc.GetDevices(ctx, roomID, participantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		if device.ID != targetID {
+			continue
+		}
+		if err := uc.removeParticipant(ctx, roomID, targetID); err != nil {
+			return nil, err
+		}
+		log.Printf("[UseCase] %s disconnected their device %s from room %s", device.UserID, targetID, roomID)
+		return device, nil
+	}
+
+	return nil, ErrNotOwnDevice
+}
+
+// TransferToDevice moves the user's call to participantID: every other
+// device of theirs leaves the room. It returns the devices that left.
+func (uc *RoomUseCase) TransferToDevice(ctx context.Context, roomID, participantID string) ([]*entity.Participant, error) {
+	devices, err := uc.GetDevices(ctx, roomID, participantID)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make([]*entity.Participant, 0, len(devices))
+	for _, device := range devices {
+		if device.ID == participantID {
+			continue
+		}
+		if err := uc.removeParticipant(ctx, roomID, device.ID); err != nil {
+			return moved, err
+		}
+		moved = append(moved, device)
+	}
+
+	if len(moved) > 0 {
+		log.Printf("[UseCase] Call of %s in room %s transferred to %s", moved[0].UserID, roomID, participantID)
+	}
+	return moved, nil
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"fmt"
+	"log"
+)
+
+// Why a meeting ended, as sent to clients in "meeting-ended".
+const (
+	MeetingEndedByHost    = "ended-by-host"
+	MeetingEndedTimeLimit = "time-limit"
+	MeetingEndedEmpty     = "empty"
+)
+
+// EndMeeting lets the host end the meeting for everyone. The room is gone
+// afterwards, or reset if it is personal, and every connected client is sent
+// away.
+func (uc *RoomUseCase) EndMeeting(ctx context.Context, roomID, userID string) error {
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if room.HostID != userID {
+		return ErrNotHost
+	}
+
+	log.Printf("[UseCase] Host %s ended room %s", userID, roomID)
+	return uc.closeRoom(ctx, room, MeetingEndedByHost)
+}
+
+// closeRoom stops the recording, deletes the room with everything kept
+// about it and has the clients still connected closed. A personal room
+// outlives its meetings, only their session is cleared.
+func (uc *RoomUseCase) closeRoom(ctx context.Context, room *entity.Room, reason string) error {
+	if room.IsRecording && room.RecordingID != "" {
+		if err := uc.closeRecording(ctx, room.RecordingID); err != nil {
+			log.Printf("[UseCase] Failed to stop recording of room %s: %v", room.ID, err)
+		}
+	}
+
+	if room.Personal {
+		if err := uc.resetRoom(ctx, room); err != nil {
+			return err
+		}
+	} else if err := uc.roomRepo.Delete(ctx, room.ID); err != nil {
+		return fmt.Errorf("failed to delete room: %w", err)
+	}
+
+	uc.meetingEnded(room.ID, reason)
+	return nil
+}
+
+func (uc *RoomUseCase) meetingEnded(roomID, reason string) {
+	if uc.events != nil {
+		uc.events.MeetingEnded(roomID, reason)
+	}
+}
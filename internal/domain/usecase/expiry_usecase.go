@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// longest single extension a host can ask for
+	maxExtension = 2 * time.Hour
+
+	// how long a room outlives the end of its meeting
+	endedRoomRetention = time.Hour
+
+	// shortest a notice claim is kept, even for a meeting long over
+	minNoticeRetention = time.Minute
+)
+
+var (
+	ErrNoTimeLimit      = errors.New("meeting has no time limit")
+	ErrInvalidExtension = errors.New("extension must be between one minute and two hours")
+)
+
+// ExtendRoomDuration lets the host push back the end of a meeting with a
+// time limit. The room is kept in Redis at least until the new end.
+func (uc *RoomUseCase) ExtendRoomDuration(ctx context.Context, roomID, userID string, duration time.Duration) (*entity.Room, error) {
+	if duration < time.Minute || duration > maxExtension {
+		return nil, ErrInvalidExtension
+	}
+
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.HostID != userID {
+		return nil, ErrNotHost
+	}
+	if room.EndsAt().IsZero() {
+		return nil, ErrNoTimeLimit
+	}
+	if err := checkDuration(room); err != nil {
+		return nil, err
+	}
+
+	room, err = uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		r.ExtendedMinutes += int(duration / time.Minute)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend meeting: %w", err)
+	}
+
+	if err := uc.roomRepo.ExtendTTL(ctx, roomID, time.Until(room.EndsAt())+endedRoomRetention); err != nil {
+		return nil, fmt.Errorf("failed to extend room duration: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s extended room %s until %s", userID, roomID, room.EndsAt().Format(time.RFC3339))
+	return room, nil
+}
+
+// ClaimExpiryNotice reports whether the caller is the first to send notice
+// about the meeting ending at endsAt. An extension moves endsAt, so the
+// notices of the new end go out again.
+func (uc *RoomUseCase) ClaimExpiryNotice(ctx context.Context, roomID string, endsAt time.Time, notice string) (bool, error) {
+	key := fmt.Sprintf("%d:%s", endsAt.Unix(), notice)
+
+	// a claim without a TTL would never go away
+	ttl := time.Until(endsAt) + endedRoomRetention
+	if ttl < minNoticeRetention {
+		ttl = minNoticeRetention
+	}
+	claimed, err := uc.roomRepo.ClaimNotice(ctx, roomID, key, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim expiry notice: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// EndExpiredMeeting closes a meeting that ran out of time. It reports false
+// when the meeting isn't over after all, or another node already ended it.
+func (uc *RoomUseCase) EndExpiredMeeting(ctx context.Context, roomID string) (bool, error) {
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+
+	endsAt := room.EndsAt()
+	if endsAt.IsZero() || time.Now().Before(endsAt) {
+		return false, nil
+	}
+
+	claimed, err := uc.ClaimExpiryNotice(ctx, roomID, endsAt, "ended")
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	log.Printf("[UseCase] Room %s reached its time limit", roomID)
+	if err := uc.closeRoom(ctx, room, MeetingEndedTimeLimit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
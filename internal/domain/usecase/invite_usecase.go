@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInviteLifetime = 24 * time.Hour
+	maxInviteLifetime     = 30 * 24 * time.Hour
+
+	// keeps invite tokens and sign-in tokens, signed with the same secret,
+	// from standing in for each other
+	inviteAudience = "room-invite"
+)
+
+var (
+	ErrInvalidInviteRequest = errors.New("invite must have a role of participant, co-host or viewer, a valid email, no negative uses and expire within 30 days")
+	ErrInvalidInvite        = errors.New("invitation is invalid or has expired")
+	ErrInviteUsedUp         = errors.New("invitation has been used up")
+	ErrInviteNotForYou      = errors.New("invitation was sent to someone else")
+	ErrInviteSignInRequired = errors.New("sign in to use this invitation")
+)
+
+// inviteClaims is what an invite token carries. The invite stored with the
+// room has the final say, so a revoked invite stops working at once.
+type inviteClaims struct {
+	RoomID  string      `json:"rid"`
+	Role    entity.Role `json:"role"`
+	Email   string      `json:"email,omitempty"`
+	MaxUses int         `json:"maxUses,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type CreateInviteInput struct {
+	RoomID    string
+	UserID    string
+	Role      entity.Role   // participant when empty
+	Email     string        // optional, binds the invite to one signed in user
+	MaxUses   int           // 0 means unlimited
+	ExpiresIn time.Duration // a day when zero
+}
+
+// CreateInvite lets the host mint an invite to the room and returns it with
+// its signed token.
+func (uc *RoomUseCase) CreateInvite(ctx context.Context, input CreateInviteInput) (*entity.Invite, string, error) {
+	room, err := uc.GetRoom(ctx, input.RoomID)
+	if err != nil {
+		return nil, "", err
+	}
+	if room.HostID != input.UserID {
+		return nil, "", ErrNotHost
+	}
+
+	if input.Role == "" {
+		input.Role = entity.RoleParticipant
+	}
+	if input.ExpiresIn == 0 {
+		input.ExpiresIn = defaultInviteLifetime
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	switch {
+	case input.Role != entity.RoleParticipant && input.Role != entity.RoleCoHost && input.Role != entity.RoleViewer,
+		input.MaxUses < 0,
+		input.ExpiresIn < 0 || input.ExpiresIn > maxInviteLifetime:
+		return nil, "", ErrInvalidInviteRequest
+	case email != "":
+		if _, err := mail.ParseAddress(email); err != nil {
+			return nil, "", ErrInvalidInviteRequest
+		}
+	}
+
+	now := time.Now()
+	invite := &entity.Invite{
+		ID:        uuid.New().String(),
+		RoomID:    room.ID,
+		Role:      input.Role,
+		Email:     email,
+		MaxUses:   input.MaxUses,
+		CreatedBy: input.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(input.ExpiresIn),
+	}
+
+	claims := inviteClaims{
+		RoomID:  invite.RoomID,
+		Role:    invite.Role,
+		Email:   invite.Email,
+		MaxUses: invite.MaxUses,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        invite.ID,
+			Audience:  jwt.ClaimStrings{inviteAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(invite.ExpiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(uc.config.JWT.Secret))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign invite: %w", err)
+	}
+
+	if err := uc.roomRepo.CreateInvite(ctx, invite); err != nil {
+		return nil, "", fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s created a %s invite to room %s", input.UserID, invite.Role, room.ID)
+	return invite, token, nil
+}
+
+func (uc *RoomUseCase) ListInvites(ctx context.Context, roomID, userID string) ([]*entity.Invite, error) {
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.HostID != userID {
+		return nil, ErrNotHost
+	}
+
+	invites, err := uc.roomRepo.ListInvites(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	return invites, nil
+}
+
+// RevokeInvite stops an invite from letting anyone else in. Whoever already
+// joined with it stays.
+func (uc *RoomUseCase) RevokeInvite(ctx context.Context, roomID, inviteID, userID string) error {
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if room.HostID != userID {
+		return ErrNotHost
+	}
+
+	if err := uc.roomRepo.RevokeInvite(ctx, roomID, inviteID); err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s revoked invite %s to room %s", userID, inviteID, roomID)
+	return nil
+}
+
+// verifyInvite checks that token is a live invite to roomID the caller may
+// use. It doesn't count a use, redeemInvite does once they are let in.
+func (uc *RoomUseCase) verifyInvite(ctx context.Context, roomID, token, email string, isGuest bool) (*entity.Invite, error) {
+	var claims inviteClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(uc.config.JWT.Secret), nil
+	}, jwt.WithAudience(inviteAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.RoomID != roomID || claims.ID == "" {
+		return nil, ErrInvalidInvite
+	}
+
+	invite, err := uc.roomRepo.GetInvite(ctx, roomID, claims.ID)
+	if errors.Is(err, repository.ErrInviteNotFound) {
+		return nil, ErrInvalidInvite
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, ErrInvalidInvite
+	}
+
+	// only someone who signed in can be held to an email or run the room
+	if isGuest && (invite.Email != "" || invite.Role == entity.RoleCoHost) {
+		return nil, ErrInviteSignInRequired
+	}
+	if invite.Email != "" && !strings.EqualFold(invite.Email, strings.TrimSpace(email)) {
+		return nil, ErrInviteNotForYou
+	}
+
+	return invite, nil
+}
+
+func (uc *RoomUseCase) redeemInvite(ctx context.Context, invite *entity.Invite, userID string) error {
+	err := uc.roomRepo.RedeemInvite(ctx, invite, userID)
+	switch {
+	case errors.Is(err, repository.ErrInviteNotFound):
+		return ErrInvalidInvite
+	case errors.Is(err, repository.ErrInviteUsedUp):
+		return ErrInviteUsedUp
+	case err != nil:
+		return fmt.Errorf("failed to redeem invite: %w", err)
+	}
+	return nil
+}
+
+// applyInviteRole gives a participant let in by invite the invite's role.
+// Co-hosts are hosts inside the room.
+func applyInviteRole(participant *entity.Participant, role entity.Role) {
+	switch role {
+	case entity.RoleCoHost:
+		participant.IsHost = true
+		participant.IsCoHost = true
+	case entity.RoleViewer:
+		participant.IsViewer = true
+	}
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// requireHost verifies server-side that participantID is a host of the room.
+func (uc *RoomUseCase) requireHost(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
+	participant, err := uc.participantRepo.GetParticipant(ctx, roomID, participantID)
+	if err != nil || !participant.IsHost {
+		return nil, ErrNotHost
+	}
+	return participant, nil
+}
+
+func (uc *RoomUseCase) GetLobby(ctx context.Context, roomID string) ([]*entity.Participant, error) {
+	participants, err := uc.participantRepo.GetLobby(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lobby: %w", err)
+	}
+
+	return participants, nil
+}
+
+// AdmitParticipants moves waiting participants into the room. An empty
+// participantIDs admits everyone in the lobby. Participants already handled by
+// another host, or banned meanwhile, are skipped; admission stops once the
+// room is full or locked.
+func (uc *RoomUseCase) AdmitParticipants(ctx context.Context, roomID, hostID string, participantIDs []string) ([]*entity.Participant, error) {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return nil, err
+	}
+
+	room, err := uc.roomRepo.Get(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("room not found: %w", err)
+	}
+
+	waiting, err := uc.lobbyEntries(ctx, roomID, participantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	admitted := make([]*entity.Participant, 0, len(waiting))
+	for _, participant := range waiting {
+		// the admission is checked and written in one step, like a join,
+		// and whoever removes the lobby entry owns the decision
+		participant.Status = entity.ParticipantStatusActive
+		err := uc.participantRepo.AdmitParticipant(ctx, participant, room.MaxParticipants)
+		switch {
+		case errors.Is(err, repository.ErrNotWaiting), errors.Is(err, repository.ErrUserBanned):
+			continue
+		case errors.Is(err, repository.ErrRoomFull):
+			return admitted, ErrRoomFull
+		case errors.Is(err, repository.ErrRoomLocked):
+			return admitted, ErrRoomLocked
+		case err != nil:
+			return admitted, fmt.Errorf("failed to admit participant: %w", err)
+		}
+
+		log.Printf("[UseCase] Host %s admitted %s into room %s", hostID, participant.UserID, roomID)
+		admitted = append(admitted, participant)
+	}
+
+	if len(admitted) > 0 && room.Personal && room.StartedAt.IsZero() {
+		uc.startSession(ctx, room)
+	}
+	return admitted, nil
+}
+
+// DenyParticipants removes waiting participants from the lobby. An empty
+// participantIDs denies everyone in the lobby.
+func (uc *RoomUseCase) DenyParticipants(ctx context.Context, roomID, hostID string, participantIDs []string) ([]*entity.Participant, error) {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return nil, err
+	}
+
+	waiting, err := uc.lobbyEntries(ctx, roomID, participantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	denied := make([]*entity.Participant, 0, len(waiting))
+	for _, participant := range waiting {
+		removed, err := uc.participantRepo.RemoveFromLobby(ctx, roomID, participant.ID)
+		if err != nil {
+			return denied, fmt.Errorf("failed to remove participant from lobby: %w", err)
+		}
+		if !removed {
+			continue
+		}
+
+		log.Printf("[UseCase] Host %s denied %s from room %s", hostID, participant.UserID, roomID)
+		denied = append(denied, participant)
+	}
+
+	return denied, nil
+}
+
+// LeaveLobby is called when a waiting participant gives up or disconnects.
+func (uc *RoomUseCase) LeaveLobby(ctx context.Context, roomID, participantID string) (bool, error) {
+	removed, err := uc.participantRepo.RemoveFromLobby(ctx, roomID, participantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove participant from lobby: %w", err)
+	}
+
+	if removed {
+		uc.resetAbandonedSession(ctx, roomID)
+	}
+	return removed, nil
+}
+
+// resetAbandonedSession resets a personal room whose session is still
+// running although nobody is left in the room or its lobby.
+func (uc *RoomUseCase) resetAbandonedSession(ctx context.Context, roomID string) {
+	room, err := uc.roomRepo.Get(ctx, roomID)
+	if err != nil || !room.Personal || room.StartedAt.IsZero() {
+		return
+	}
+
+	count, err := uc.participantRepo.GetParticipantCount(ctx, roomID)
+	if err != nil || count > 0 {
+		return
+	}
+	waiting, err := uc.participantRepo.GetLobby(ctx, roomID)
+	if err != nil || len(waiting) > 0 {
+		return
+	}
+
+	if err := uc.resetRoom(ctx, room); err != nil {
+		log.Printf("[UseCase] Failed to reset session of room %s: %v", roomID, err)
+	}
+}
+
+func (uc *RoomUseCase) lobbyEntries(ctx context.Context, roomID string, participantIDs []string) ([]*entity.Participant, error) {
+	if len(participantIDs) == 0 {
+		return uc.GetLobby(ctx, roomID)
+	}
+
+	entries := make([]*entity.Participant, 0, len(participantIDs))
+	for _, participantID := range participantIDs {
+		participant, err := uc.participantRepo.GetLobbyParticipant(ctx, roomID, participantID)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, participant)
+	}
+	return entries, nil
+}
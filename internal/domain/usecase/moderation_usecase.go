@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrCannotModerateHost = errors.New("hosts cannot be moderated")
+	ErrParticipantMissing = errors.New("participant not found")
+)
+
+// moderationTarget checks that actorID is a host and targetID is a
+// non-host participant of the room.
+func (uc *RoomUseCase) moderationTarget(ctx context.Context, roomID, actorID, targetID string) (*entity.Participant, error) {
+	if _, err := uc.requireHost(ctx, roomID, actorID); err != nil {
+		return nil, err
+	}
+
+	target, err := uc.participantRepo.GetParticipant(ctx, roomID, targetID)
+	if err != nil {
+		return nil, ErrParticipantMissing
+	}
+
+	if target.IsHost {
+		return nil, ErrCannotModerateHost
+	}
+
+	return target, nil
+}
+
+func (uc *RoomUseCase) MuteParticipant(ctx context.Context, roomID, actorID, targetID string) (*entity.Participant, error) {
+	target, err := uc.moderationTarget(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	target.IsMuted = true
+	if err := uc.UpdateParticipantState(ctx, target); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[UseCase] Host %s muted %s in room %s", actorID, targetID, roomID)
+	return target, nil
+}
+
+func (uc *RoomUseCase) StopParticipantVideo(ctx context.Context, roomID, actorID, targetID string) (*entity.Participant, error) {
+	target, err := uc.moderationTarget(ctx, roomID, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	target.IsVideoOff = true
+	if err := uc.UpdateParticipantState(ctx, target); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[UseCase] Host %s stopped video of %s in room %s", actorID, targetID, roomID)
+	return target, nil
+}
+
+func (uc *RoomUseCase) RemoveParticipant(ctx context.Context, roomID, actorID, targetID string) error {
+	if _, err := uc.moderationTarget(ctx, roomID, actorID, targetID); err != nil {
+		return err
+	}
+
+	if err := uc.removeParticipant(ctx, roomID, targetID); err != nil {
+		return err
+	}
+
+	log.Printf("[UseCase] Host %s removed %s from room %s", actorID, targetID, roomID)
+	return nil
+}
+
+// BanParticipant blocks the user behind targetID from rejoining for the
+// room's lifetime and removes every device they have in the room or its
+// lobby. Waiting participants can be banned too. It returns the banned user.
+func (uc *RoomUseCase) BanParticipant(ctx context.Context, roomID, actorID, targetID string) (string, error) {
+	if _, err := uc.requireHost(ctx, roomID, actorID); err != nil {
+		return "", err
+	}
+
+	target, err := uc.participantRepo.GetLobbyParticipant(ctx, roomID, targetID)
+	if err != nil {
+		if target, err = uc.moderationTarget(ctx, roomID, actorID, targetID); err != nil {
+			return "", err
+		}
+	}
+
+	// ban first so none of their devices can slip back in meanwhile
+	if err := uc.roomRepo.AddBan(ctx, roomID, target.UserID); err != nil {
+		return "", fmt.Errorf("failed to ban participant: %w", err)
+	}
+
+	if err := uc.removeUser(ctx, roomID, target.UserID); err != nil {
+		return "", err
+	}
+
+	log.Printf("[UseCase] Host %s banned %s from room %s", actorID, target.UserID, roomID)
+	return target.UserID, nil
+}
+
+// removeUser drops every participant and lobby entry of userID.
+func (uc *RoomUseCase) removeUser(ctx context.Context, roomID, userID string) error {
+	waiting, err := uc.GetLobby(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	for _, p := range waiting {
+		if p.UserID != userID {
+			continue
+		}
+		if _, err := uc.participantRepo.RemoveFromLobby(ctx, roomID, p.ID); err != nil {
+			return fmt.Errorf("failed to remove participant from lobby: %w", err)
+		}
+	}
+
+	participants, err := uc.GetParticipants(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	for _, p := range participants {
+		if p.UserID != userID {
+			continue
+		}
+		if err := uc.removeParticipant(ctx, roomID, p.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
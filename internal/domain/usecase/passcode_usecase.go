@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasscodeLength = 4
+	maxPasscodeLength = 64
+
+	// wrong passcodes allowed per window before further attempts are refused
+	passcodeFailureWindow      = 15 * time.Minute
+	maxPasscodeFailuresPerIP   = 10
+	maxPasscodeFailuresPerRoom = 50
+)
+
+var (
+	ErrInvalidPasscode  = errors.New("passcode must be between 4 and 64 characters")
+	ErrPasscodeRequired = errors.New("this room needs a passcode")
+	ErrWrongPasscode    = errors.New("wrong passcode")
+	ErrTooManyAttempts  = errors.New("too many wrong passcodes, try again later")
+	ErrRoomLocked       = errors.New("room is locked")
+)
+
+func hashPasscode(passcode string) (string, error) {
+	if n := utf8.RuneCountInString(passcode); n < minPasscodeLength || n > maxPasscodeLength {
+		return "", ErrInvalidPasscode
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash passcode: %w", err)
+	}
+	return string(hash), nil
+}
+
+// checkPasscode lets the caller at ip into a room with a passcode. Every
+// attempt is counted before the passcode is compared, so parallel guesses
+// can't slip past the limit, and taken back when it was right. Once too many
+// wrong passcodes came from ip, or were tried on the room, every attempt is
+// refused until the window passes, right or wrong.
+func (uc *RoomUseCase) checkPasscode(ctx context.Context, room *entity.Room, passcode, ip string) error {
+	if !room.HasPasscode {
+		return nil
+	}
+	if passcode == "" {
+		return ErrPasscodeRequired
+	}
+
+	reserved, err := uc.roomRepo.ReservePasscodeAttempt(ctx, room.ID, ip,
+		maxPasscodeFailuresPerIP, maxPasscodeFailuresPerRoom, passcodeFailureWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check passcode attempts: %w", err)
+	}
+	if !reserved {
+		return ErrTooManyAttempts
+	}
+
+	hash, err := uc.roomRepo.GetPasscode(ctx, room.ID)
+	if err != nil {
+		uc.releasePasscodeAttempt(ctx, room.ID, ip)
+		return fmt.Errorf("failed to get passcode: %w", err)
+	}
+
+	// a lost hash locks everyone but the host out rather than letting all in
+	if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(passcode)) == nil {
+		uc.releasePasscodeAttempt(ctx, room.ID, ip)
+		return nil
+	}
+
+	return ErrWrongPasscode
+}
+
+func (uc *RoomUseCase) releasePasscodeAttempt(ctx context.Context, roomID, ip string) {
+	if err := uc.roomRepo.ReleasePasscodeAttempt(ctx, roomID, ip); err != nil {
+		log.Printf("[UseCase] Failed to release passcode attempt for room %s: %v", roomID, err)
+	}
+}
+
+type RoomAccessInput struct {
+	RoomID      string
+	UserID      string // empty for guests
+	Email       string
+	Passcode    string
+	InviteToken string
+	ClientIP    string
+}
+
+// ValidateRoomAccess tells someone about to join whether they will get in,
+// checking the passcode and invite the same way JoinRoom does, without
+// using the invite up. The host needs neither a passcode nor an unlocked
+// room.
+func (uc *RoomUseCase) ValidateRoomAccess(ctx context.Context, input RoomAccessInput) (*entity.Room, error) {
+	room, err := uc.GetRoom(ctx, input.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if input.UserID != "" && room.HostID == input.UserID {
+		return room, nil
+	}
+
+	if input.InviteToken != "" {
+		invite, err := uc.verifyInvite(ctx, room.ID, input.InviteToken, input.Email, input.UserID == "")
+		if err != nil {
+			return nil, err
+		}
+		if room.Locked && invite.Role != entity.RoleCoHost {
+			return nil, ErrRoomLocked
+		}
+		return room, nil
+	}
+
+	if err := uc.checkPasscode(ctx, room, input.Passcode, input.ClientIP); err != nil {
+		return nil, err
+	}
+	if room.Locked {
+		return nil, ErrRoomLocked
+	}
+
+	return room, nil
+}
+
+// SetRoomLocked lets the host stop new people from joining. Everyone in the
+// room stays and can reconnect.
+func (uc *RoomUseCase) SetRoomLocked(ctx context.Context, roomID, userID string, locked bool) (*entity.Room, error) {
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room.HostID != userID {
+		return nil, ErrNotHost
+	}
+	if room.Locked == locked {
+		return room, nil
+	}
+
+	room, err = uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		r.Locked = locked
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock room: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s set room %s locked=%t", userID, roomID, locked)
+	return room, nil
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// personalRoomNamespace derives a user's personal room ID from theirs, so
+// two first requests can't each create one.
+var personalRoomNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("bincang-visual:personal-room"))
+
+func personalRoomID(userID string) string {
+	return uuid.NewSHA1(personalRoomNamespace, []byte(userID)).String()
+}
+
+// GetPersonalRoom returns the user's permanent room, creating it on first
+// use. It keeps its code and settings across meetings and only lets anyone
+// in once the host admits them.
+func (uc *RoomUseCase) GetPersonalRoom(ctx context.Context, userID, displayName string) (*entity.Room, error) {
+	if userID == "" || userID == AnonymousHostID {
+		return nil, ErrNotHost
+	}
+
+	roomID := personalRoomID(userID)
+	room, err := uc.roomRepo.Get(ctx, roomID)
+	if err == nil {
+		return room, nil
+	}
+	if !errors.Is(err, repository.ErrRoomNotFound) {
+		return nil, fmt.Errorf("failed to get personal room: %w", err)
+	}
+
+	name := "Personal room"
+	if displayName != "" {
+		name = fmt.Sprintf("%s's room", displayName)
+	}
+
+	settings := entity.DefaultRoomSettings()
+	settings.WaitingRoom = true
+
+	room = &entity.Room{
+		ID:              roomID,
+		Name:            name,
+		HostID:          userID,
+		CreatedAt:       time.Now(),
+		MaxParticipants: 100,
+		Personal:        true,
+		Settings:        settings,
+	}
+
+	err = uc.createRoom(ctx, room, 0)
+	if errors.Is(err, repository.ErrRoomExists) {
+		return uc.GetRoom(ctx, roomID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create personal room: %w", err)
+	}
+
+	log.Printf("[UseCase] Created personal room %s for %s", roomID, userID)
+	return room, nil
+}
+
+// SetPersonalRoomCode gives the user's personal room a vanity code. The old
+// code stops working.
+func (uc *RoomUseCase) SetPersonalRoomCode(ctx context.Context, userID, code string) (*entity.Room, error) {
+	code, err := normalizeVanityCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := uc.GetRoom(ctx, personalRoomID(userID))
+	if err != nil {
+		return nil, err
+	}
+	if room.Code == code {
+		return room, nil
+	}
+
+	err = uc.roomRepo.ClaimCode(ctx, code, room.ID)
+	if errors.Is(err, repository.ErrCodeTaken) {
+		return nil, ErrCodeTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim room code: %w", err)
+	}
+
+	roomID := room.ID
+	var previous string
+	room, err = uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		previous = r.Code
+		r.Code = code
+		return nil
+	})
+	if err != nil {
+		_ = uc.roomRepo.ReleaseCode(ctx, code, roomID)
+		return nil, fmt.Errorf("failed to set room code: %w", err)
+	}
+
+	if previous != "" {
+		if err := uc.roomRepo.ReleaseCode(ctx, previous, roomID); err != nil {
+			log.Printf("[UseCase] Failed to release code %s of room %s: %v", previous, roomID, err)
+		}
+	}
+
+	return room, nil
+}
+
+// startSession starts the clock of a personal room's meeting when its first
+// participant arrives.
+func (uc *RoomUseCase) startSession(ctx context.Context, room *entity.Room) {
+	_, err := uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		if r.StartedAt.IsZero() {
+			r.StartedAt = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[UseCase] Failed to start session of room %s: %v", room.ID, err)
+	}
+}
+
+// resetRoom ends a personal room's session. What the meeting left behind is
+// cleared, what the host set up stays.
+func (uc *RoomUseCase) resetRoom(ctx context.Context, room *entity.Room) error {
+	if err := uc.roomRepo.ClearSession(ctx, room.ID); err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+
+	_, err := uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		r.StartedAt = time.Time{}
+		r.ExtendedMinutes = 0
+		r.IsRecording = false
+		r.RecordingID = ""
+		r.Locked = false
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset room: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotPermitted = errors.New("not allowed by the room settings")
+	ErrMeetingOver  = errors.New("meeting has reached its maximum duration")
+	ErrNotMember    = errors.New("not a member of the room")
+)
+
+// PolicyError tells a participant which room setting refused their action.
+// It matches ErrNotPermitted.
+type PolicyError struct {
+	Capability entity.Capability
+	Role       entity.Role
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s is not allowed for %s in this room", e.Capability, e.Role)
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrNotPermitted
+}
+
+// Authorize checks the room's settings before participantID uses
+// capability. Everything that acts on a setting goes through here, so the
+// signaling and HTTP paths can't disagree.
+func (uc *RoomUseCase) Authorize(ctx context.Context, roomID, participantID string, capability entity.Capability) error {
+	participant, err := uc.participantRepo.GetParticipant(ctx, roomID, participantID)
+	if err != nil {
+		return ErrParticipantMissing
+	}
+
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	return authorize(room, participant.Role(), capability)
+}
+
+// memberRole is the role of a signed in user as seen by the HTTP API: the
+// host's, or the one they joined the room with. Someone who isn't in the
+// room gets ErrNotMember rather than a role.
+func (uc *RoomUseCase) memberRole(ctx context.Context, room *entity.Room, userID string) (entity.Role, error) {
+	if room.HostID == userID {
+		return entity.RoleHost, nil
+	}
+
+	participants, err := uc.participantRepo.GetParticipants(ctx, room.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get participants: %w", err)
+	}
+	for _, participant := range participants {
+		if participant.UserID == userID {
+			return participant.Role(), nil
+		}
+	}
+	return "", ErrNotMember
+}
+
+func authorize(room *entity.Room, role entity.Role, capability entity.Capability) error {
+	if !room.Settings.Allows(role, capability) {
+		return &PolicyError{Capability: capability, Role: role}
+	}
+	return nil
+}
+
+// checkDuration refuses to let anyone into a meeting that ran out of time.
+func checkDuration(room *entity.Room) error {
+	if endsAt := room.EndsAt(); !endsAt.IsZero() && time.Now().After(endsAt) {
+		return ErrMeetingOver
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/utils"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// random codes collide so rarely that running out of attempts means Redis
+// is in trouble, not that the codes are used up
+const maxCodeAttempts = 5
+
+var (
+	ErrInvalidCode = errors.New("room code must be 4 to 32 lowercase letters, digits or dashes")
+	ErrCodeTaken   = errors.New("room code is already taken")
+)
+
+var vanityCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,30}[a-z0-9]$`)
+
+// createRoom stores room under its vanity code, or the first free random
+// code when it has none.
+func (uc *RoomUseCase) createRoom(ctx context.Context, room *entity.Room, ttl time.Duration) error {
+	if room.Code != "" {
+		err := uc.roomRepo.Create(ctx, room, ttl)
+		if errors.Is(err, repository.ErrCodeTaken) {
+			return ErrCodeTaken
+		}
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		room.Code = utils.GenerateMeetingCode()
+		if err = uc.roomRepo.Create(ctx, room, ttl); !errors.Is(err, repository.ErrCodeTaken) {
+			return err
+		}
+	}
+	return fmt.Errorf("no free room code after %d attempts: %w", maxCodeAttempts, err)
+}
+
+func normalizeVanityCode(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if !vanityCodePattern.MatchString(code) {
+		return "", ErrInvalidCode
+	}
+	return code, nil
+}
+
+// ResolveRoomID returns the ID of the room idOrCode names, by its ID or its
+// code. Codes are matched however they were typed, dashes or not.
+func (uc *RoomUseCase) ResolveRoomID(ctx context.Context, idOrCode string) (string, error) {
+	if uuid.Validate(idOrCode) == nil {
+		return idOrCode, nil
+	}
+
+	code := strings.ToLower(strings.Join(strings.Fields(idOrCode), ""))
+	roomID, err := uc.roomRepo.ResolveCode(ctx, code)
+
+	// a dictated abc-defg-hij code tends to lose its dashes
+	if errors.Is(err, repository.ErrRoomNotFound) && len(code) == 10 && !strings.Contains(code, "-") {
+		roomID, err = uc.roomRepo.ResolveCode(ctx, code[:3]+"-"+code[3:7]+"-"+code[7:])
+	}
+	if err != nil {
+		return "", err
+	}
+	return roomID, nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRoomPageSize = 20
+	maxRoomPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type ListRoomsInput struct {
+	UserID     string
+	ActiveOnly bool   // only rooms with a meeting going on
+	Query      string // matches the room's name or code
+	Cursor     string // NextCursor of the previous page
+	Limit      int
+}
+
+// RoomSummary is a room as it shows up in a listing.
+type RoomSummary struct {
+	*entity.Room
+	Active           bool `json:"active"`
+	ParticipantCount int  `json:"participantCount"`
+}
+
+type RoomPage struct {
+	Rooms      []RoomSummary `json:"rooms"`
+	NextCursor string        `json:"nextCursor,omitempty"` // empty on the last page
+}
+
+// ListRooms pages through the rooms the user created, newest first.
+func (uc *RoomUseCase) ListRooms(ctx context.Context, input ListRoomsInput) (*RoomPage, error) {
+	if input.UserID == "" || input.UserID == AnonymousHostID {
+		return nil, ErrNotHost
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultRoomPageSize
+	}
+	if limit > maxRoomPageSize {
+		limit = maxRoomPageSize
+	}
+
+	cursor, err := decodeRoomCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(strings.TrimSpace(input.Query))
+
+	// one more than asked tells whether there is another page
+	var rooms []*entity.Room
+	var active []bool
+	for len(rooms) <= limit {
+		batch, err := uc.roomRepo.ListHostRooms(ctx, input.UserID, cursor, limit+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rooms: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		last := batch[len(batch)-1]
+		cursor = &repository.RoomCursor{CreatedAt: last.CreatedAt, RoomID: last.ID}
+
+		isActive, err := uc.roomRepo.ActiveRooms(ctx, roomIDs(batch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check active rooms: %w", err)
+		}
+		for i, room := range batch {
+			if (isActive[i] || !input.ActiveOnly) && matchesQuery(room, query) {
+				rooms = append(rooms, room)
+				active = append(active, isActive[i])
+			}
+		}
+
+		if len(batch) <= limit {
+			break
+		}
+	}
+
+	page := &RoomPage{Rooms: make([]RoomSummary, 0, limit)}
+	if len(rooms) > limit {
+		rooms, active = rooms[:limit], active[:limit]
+		last := rooms[limit-1]
+		page.NextCursor = encodeRoomCursor(last.CreatedAt, last.ID)
+	}
+	if len(rooms) == 0 {
+		return page, nil
+	}
+
+	counts, err := uc.participantRepo.CountParticipants(ctx, roomIDs(rooms))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count participants: %w", err)
+	}
+
+	for i, room := range rooms {
+		page.Rooms = append(page.Rooms, RoomSummary{
+			Room:             room,
+			Active:           active[i],
+			ParticipantCount: counts[i],
+		})
+	}
+	return page, nil
+}
+
+func matchesQuery(room *entity.Room, query string) bool {
+	return query == "" || strings.Contains(strings.ToLower(room.Name), query) || strings.Contains(room.Code, query)
+}
+
+func roomIDs(rooms []*entity.Room) []string {
+	ids := make([]string, len(rooms))
+	for i, room := range rooms {
+		ids[i] = room.ID
+	}
+	return ids
+}
+
+func encodeRoomCursor(createdAt time.Time, roomID string) string {
+	raw := strconv.FormatInt(createdAt.UnixMilli(), 10) + "." + roomID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRoomCursor(cursor string) (*repository.RoomCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	millis, roomID, ok := strings.Cut(string(raw), ".")
+	if !ok || roomID == "" {
+		return nil, ErrInvalidCursor
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &repository.RoomCursor{CreatedAt: time.UnixMilli(ms), RoomID: roomID}, nil
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"fmt"
+)
+
+// chat messages included in a room state snapshot
+const roomStateChatLimit = 50
+
+// NextStateVersion bumps the room's state version. Every broadcast that
+// changes what GetRoomState would return carries the version it produced, so
+// clients can tell when they missed one.
+func (uc *RoomUseCase) NextStateVersion(ctx context.Context, roomID string) (int64, error) {
+	version, err := uc.roomRepo.NextVersion(ctx, roomID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to bump room version: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetRoomState takes a snapshot of the room. The version is read first, so
+// any update made while the snapshot is built carries a higher version and
+// is safe to apply on top of it.
+func (uc *RoomUseCase) GetRoomState(ctx context.Context, roomID string) (*entity.RoomState, error) {
+	version, err := uc.roomRepo.GetVersion(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room version: %w", err)
+	}
+
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := uc.GetParticipants(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	presenters, err := uc.GetPresenters(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := uc.GetShareRequests(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := uc.GetChatHistory(ctx, roomID, roomStateChatLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.RoomState{
+		Version:       version,
+		Room:          room,
+		Participants:  participants,
+		Presenters:    presenters,
+		ShareRequests: requests,
+		IsRecording:   room.IsRecording,
+		Chat:          chat,
+	}, nil
+}
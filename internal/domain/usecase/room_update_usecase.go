@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxRoomNameLength = 100
+
+	// attempts at an internal room update before giving up on a busy room
+	maxRoomUpdateAttempts = 3
+)
+
+var (
+	ErrInvalidRoomUpdate     = errors.New("invalid room update")
+	ErrBelowParticipantCount = errors.New("max participants is below the number of participants in the room")
+)
+
+// RoomEvents is told about changes every connected client has to see, no
+// matter whether they came in over HTTP or signaling.
+type RoomEvents interface {
+	RoomUpdated(room *entity.Room)
+	MeetingEnded(roomID, reason string)
+}
+
+// SetRoomEvents wires the listener for room changes, normally the signaling
+// hub. Without one changes are saved but not announced.
+func (uc *RoomUseCase) SetRoomEvents(events RoomEvents) {
+	uc.events = events
+}
+
+// UpdateRoomInput changes a room. Nil fields are left as they are; Metadata
+// is merged, a nil value removing its key. Version is the version of the room
+// the change was based on.
+type UpdateRoomInput struct {
+	RoomID          string
+	UserID          string
+	Version         int64
+	Name            *string
+	MaxParticipants *int
+	Settings        *entity.RoomSettings
+	Metadata        map[string]interface{}
+}
+
+// UpdateRoom lets the host change a room while it is in use. It fails with
+// repository.ErrRoomChanged if the room is no longer at input.Version.
+func (uc *RoomUseCase) UpdateRoom(ctx context.Context, input UpdateRoomInput) (*entity.Room, error) {
+	room, err := uc.GetRoom(ctx, input.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.HostID != input.UserID {
+		return nil, ErrNotHost
+	}
+	if room.Version != input.Version {
+		return nil, repository.ErrRoomChanged
+	}
+
+	if err := uc.applyRoomUpdate(ctx, room, input); err != nil {
+		return nil, err
+	}
+
+	if err := uc.roomRepo.Update(ctx, room); err != nil {
+		return nil, fmt.Errorf("failed to update room: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s updated room %s to version %d", input.UserID, room.ID, room.Version)
+	uc.roomUpdated(room)
+	return room, nil
+}
+
+func (uc *RoomUseCase) applyRoomUpdate(ctx context.Context, room *entity.Room, input UpdateRoomInput) error {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return fmt.Errorf("%w: name is required", ErrInvalidRoomUpdate)
+		}
+		if utf8.RuneCountInString(name) > maxRoomNameLength {
+			return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidRoomUpdate, maxRoomNameLength)
+		}
+		room.Name = name
+	}
+
+	if input.MaxParticipants != nil {
+		if *input.MaxParticipants <= 0 {
+			return fmt.Errorf("%w: maxParticipants must be positive", ErrInvalidRoomUpdate)
+		}
+		count, err := uc.participantRepo.GetParticipantCount(ctx, room.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count participants: %w", err)
+		}
+		if *input.MaxParticipants < count {
+			return ErrBelowParticipantCount
+		}
+		room.MaxParticipants = *input.MaxParticipants
+	}
+
+	if input.Settings != nil {
+		if err := validateSettings(input.Settings, ErrInvalidRoomUpdate); err != nil {
+			return err
+		}
+		settings := *input.Settings
+		// anonymous rooms have nobody who could sign in, see CreateRoom
+		if room.HostID == AnonymousHostID {
+			settings.AllowGuests = true
+		}
+		room.Settings = settings
+	}
+
+	if len(input.Metadata) > 0 && room.Metadata == nil {
+		room.Metadata = make(map[string]interface{}, len(input.Metadata))
+	}
+	for key, value := range input.Metadata {
+		if value == nil {
+			delete(room.Metadata, key)
+		} else {
+			room.Metadata[key] = value
+		}
+	}
+
+	return nil
+}
+
+// validateSettings reports what is wrong with s as an invalid error.
+func validateSettings(s *entity.RoomSettings, invalid error) error {
+	if s.MaxDuration < 0 {
+		return fmt.Errorf("%w: maxDuration must not be negative", invalid)
+	}
+	if s.ScreenShare.MaxPresenters < 0 {
+		return fmt.Errorf("%w: screenShare.maxPresenters must not be negative", invalid)
+	}
+	for role := range s.Roles {
+		switch role {
+		case entity.RoleHost, entity.RoleParticipant, entity.RoleGuest, entity.RoleViewer:
+		default:
+			return fmt.Errorf("%w: unknown role '%s'", invalid, role)
+		}
+	}
+	return nil
+}
+
+// changeRoom applies change to room, saves it and announces the result.
+// When someone else updated the room in between, it reloads the room and
+// applies change again.
+func (uc *RoomUseCase) changeRoom(ctx context.Context, room *entity.Room, change func(*entity.Room) error) (*entity.Room, error) {
+	for attempt := 1; ; attempt++ {
+		if err := change(room); err != nil {
+			return nil, err
+		}
+
+		err := uc.roomRepo.Update(ctx, room)
+		if err == nil {
+			uc.roomUpdated(room)
+			return room, nil
+		}
+		if !errors.Is(err, repository.ErrRoomChanged) || attempt == maxRoomUpdateAttempts {
+			return nil, err
+		}
+
+		if room, err = uc.roomRepo.Get(ctx, room.ID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (uc *RoomUseCase) roomUpdated(room *entity.Room) {
+	if uc.events != nil {
+		uc.events.RoomUpdated(room)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"bincang-visual/internal/domain/repository"
 	"bincang-visual/utils"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,6 +14,17 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidRoom      = errors.New("invalid room")
+	ErrGuestsNotAllowed = errors.New("guests are not allowed in this room")
+	ErrRoomFull         = errors.New("room is full")
+	ErrNotHost          = errors.New("only host can perform this action")
+	ErrBanned           = errors.New("you have been banned from this room")
+	ErrScreenShareBusy  = errors.New("no more participants can share their screen right now")
+	ErrNotScreenSharer  = errors.New("you are not sharing your screen")
+	ErrNotRoomRecording = errors.New("recording is not the room's current recording")
+)
+
 type RoomUseCase struct {
 	roomRepo        repository.RoomRepository
 	participantRepo repository.ParticipantRepository
@@ -20,6 +32,7 @@ type RoomUseCase struct {
 	recordingRepo   repository.RecordingRepository
 	defaultTTL      time.Duration
 	config          config.Config
+	events          RoomEvents
 }
 
 func NewRoomUseCase(
@@ -39,14 +52,27 @@ func NewRoomUseCase(
 	}
 }
 
+// AnonymousHostID is the host of rooms created without signing in.
+const AnonymousHostID = entity.AnonymousHostID
+
 type CreateRoomInput struct {
 	Name            string
 	HostID          string
 	MaxParticipants int
 	Settings        entity.RoomSettings
+	Passcode        string // optional, only its hash is stored
+	Code            string // vanity code, a random one is picked when empty
 }
 
 func (uc *RoomUseCase) CreateRoom(ctx context.Context, input CreateRoomInput) (*entity.Room, error) {
+	// a negative limit would keep everyone out
+	if input.MaxParticipants < 0 {
+		return nil, fmt.Errorf("%w: maxParticipants must not be negative", ErrInvalidRoom)
+	}
+	if err := validateSettings(&input.Settings, ErrInvalidRoom); err != nil {
+		return nil, err
+	}
+
 	room := &entity.Room{
 		ID:              uuid.New().String(),
 		Name:            input.Name,
@@ -61,16 +87,52 @@ func (uc *RoomUseCase) CreateRoom(ctx context.Context, input CreateRoomInput) (*
 		room.MaxParticipants = 100
 	}
 
+	// nobody could ever sign in as an anonymous host, so such rooms must stay open
+	if room.HostID == AnonymousHostID {
+		room.Settings.AllowGuests = true
+	}
+
+	if input.Code != "" {
+		code, err := normalizeVanityCode(input.Code)
+		if err != nil {
+			return nil, err
+		}
+		room.Code = code
+	}
+
+	var passcodeHash string
+	if input.Passcode != "" {
+		hash, err := hashPasscode(input.Passcode)
+		if err != nil {
+			return nil, err
+		}
+		passcodeHash = hash
+		room.HasPasscode = true
+	}
+
 	ttl := 24 * time.Hour
-	if err := uc.roomRepo.Create(ctx, room, ttl); err != nil {
+	if err := uc.createRoom(ctx, room, ttl); err != nil {
 		return nil, fmt.Errorf("failed to create room: %w", err)
 	}
 
+	if passcodeHash != "" {
+		if err := uc.roomRepo.SetPasscode(ctx, room.ID, passcodeHash); err != nil {
+			_ = uc.roomRepo.Delete(ctx, room.ID)
+			return nil, fmt.Errorf("failed to set passcode: %w", err)
+		}
+	}
+
 	return room, nil
 }
 
+// GetRoom finds a room by its ID or its code.
 func (uc *RoomUseCase) GetRoom(ctx context.Context, roomID string) (*entity.Room, error) {
 	room, err := uc.roomRepo.Get(ctx, roomID)
+	if errors.Is(err, repository.ErrRoomNotFound) && uuid.Validate(roomID) != nil {
+		if roomID, err = uc.ResolveRoomID(ctx, roomID); err == nil {
+			room, err = uc.roomRepo.Get(ctx, roomID)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get room: %w", err)
 	}
@@ -79,9 +141,16 @@ func (uc *RoomUseCase) GetRoom(ctx context.Context, roomID string) (*entity.Room
 }
 
 type JoinRoomInput struct {
-	RoomID      string
-	UserID      string
-	DisplayName string
+	RoomID        string
+	ParticipantID string // one per connection, defaults to UserID
+	UserID        string
+	Email         string // of a signed in user, checked against invites
+	DisplayName   string
+	IsGuest       bool
+	Device        string
+	Passcode      string
+	InviteToken   string
+	ClientIP      string // throttles wrong passcodes
 }
 
 func (uc *RoomUseCase) JoinRoom(ctx context.Context, input JoinRoomInput) (*entity.Participant, error) {
@@ -91,50 +160,120 @@ func (uc *RoomUseCase) JoinRoom(ctx context.Context, input JoinRoomInput) (*enti
 		return nil, fmt.Errorf("room not found: %w", err)
 	}
 
-	count, err := uc.participantRepo.GetParticipantCount(ctx, input.RoomID)
-	if err != nil {
+	isHost := !input.IsGuest && input.UserID == room.HostID
+
+	// an invite stands in for signing in, the passcode and the waiting room
+	var invite *entity.Invite
+	if input.InviteToken != "" && !isHost {
+		invite, err = uc.verifyInvite(ctx, room.ID, input.InviteToken, input.Email, input.IsGuest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.IsGuest && !room.Settings.AllowGuests && invite == nil {
+		return nil, ErrGuestsNotAllowed
+	}
+
+	if err := checkDuration(room); err != nil {
 		return nil, err
 	}
 
-	if count >= room.MaxParticipants {
-		return nil, fmt.Errorf("room is full")
+	if !isHost && invite == nil {
+		if err := uc.checkPasscode(ctx, room, input.Passcode, input.ClientIP); err != nil {
+			return nil, err
+		}
+	}
+
+	participantID := input.ParticipantID
+	if participantID == "" {
+		participantID = input.UserID
 	}
 
 	participant := &entity.Participant{
+		ID:          participantID,
 		UserID:      input.UserID,
 		RoomID:      input.RoomID,
 		DisplayName: input.DisplayName,
 		JoinedAt:    time.Now(),
-		IsHost:      input.UserID == room.HostID,
+		IsHost:      isHost,
+		IsGuest:     input.IsGuest,
 		IsMuted:     false,
 		IsVideoOff:  false,
+		Status:      entity.ParticipantStatusActive,
+		Device:      input.Device,
 	}
 
-	if err := uc.participantRepo.AddParticipant(ctx, participant); err != nil {
-		return nil, fmt.Errorf("failed to add participant: %w", err)
+	if invite != nil {
+		applyInviteRole(participant, invite.Role)
+	}
+
+	if room.Settings.WaitingRoom && !participant.IsHost && invite == nil {
+		participant.Status = entity.ParticipantStatusWaiting
+	}
+
+	// the ban, lock and capacity are checked by the write itself, a
+	// concurrent join can't slip in between
+	err = uc.participantRepo.JoinRoom(ctx, participant, room.MaxParticipants)
+	switch {
+	case errors.Is(err, repository.ErrUserBanned):
+		return nil, ErrBanned
+	case errors.Is(err, repository.ErrRoomLocked):
+		return nil, ErrRoomLocked
+	case errors.Is(err, repository.ErrRoomFull):
+		return nil, ErrRoomFull
+	case err != nil:
+		return nil, fmt.Errorf("failed to join room: %w", err)
+	}
+
+	// the invite is only spent once it actually let someone in; losing the
+	// last use to a concurrent join undoes this one
+	if invite != nil {
+		if err := uc.redeemInvite(ctx, invite, input.UserID); err != nil {
+			if rmErr := uc.participantRepo.RemoveParticipant(ctx, room.ID, participant.ID); rmErr != nil {
+				log.Printf("[UseCase] Failed to undo join of %s to room %s: %v", participant.ID, room.ID, rmErr)
+			}
+			return nil, err
+		}
+	}
+
+	// someone waiting in the lobby hasn't started the meeting yet
+	if room.Personal && room.StartedAt.IsZero() && participant.Status == entity.ParticipantStatusActive {
+		uc.startSession(ctx, room)
 	}
 
 	return participant, nil
 }
 
-func (uc *RoomUseCase) LeaveRoom(ctx context.Context, roomID, userId string) error {
-	if err := uc.participantRepo.RemoveParticipant(ctx, roomID, userId); err != nil {
-		return fmt.Errorf("failed to remove participant: %w", err)
+// LeaveRoom removes the participant and, unless rooms are kept open, closes
+// the room once nobody is left. A personal room has its session cleared
+// either way.
+func (uc *RoomUseCase) LeaveRoom(ctx context.Context, roomID, participantID string) error {
+	if err := uc.removeParticipant(ctx, roomID, participantID); err != nil {
+		return err
 	}
 
-	// count, err := uc.participantRepo.GetParticipantCount(ctx, roomID)
-	// if err != nil {
-	// 	return err
-	// }
-
-	// if count == 0 {
-	// 	if err := uc.roomRepo.Delete(ctx, roomID); err != nil {
-	// 		return err
-	// 	}
+	count, err := uc.participantRepo.GetParticipantCount(ctx, roomID)
+	if err != nil || count > 0 {
+		return err
+	}
 
-	// 	_ = uc.chatRepo.DeleteMessages(ctx, roomID)
-	// }
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if uc.config.Rooms.KeepEmpty && !room.Personal {
+		return nil
+	}
+	return uc.closeRoom(ctx, room, MeetingEndedEmpty)
+}
 
+// removeParticipant takes someone out of a room that still has the caller
+// in it, so it never needs closing.
+func (uc *RoomUseCase) removeParticipant(ctx context.Context, roomID, participantID string) error {
+	if err := uc.participantRepo.RemoveParticipant(ctx, roomID, participantID); err != nil {
+		return fmt.Errorf("failed to remove participant: %w", err)
+	}
 	return nil
 }
 
@@ -147,8 +286,21 @@ func (uc *RoomUseCase) GetParticipants(ctx context.Context, roomID string) ([]*e
 	return participants, nil
 }
 
+func (uc *RoomUseCase) GetParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
+	participant, err := uc.participantRepo.GetParticipant(ctx, roomID, participantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+
+	return participant, nil
+}
+
 func (uc *RoomUseCase) UpdateParticipantState(ctx context.Context, participant *entity.Participant) error {
-	if err := uc.participantRepo.UpdateParticipant(ctx, participant); err != nil {
+	err := uc.participantRepo.UpdateParticipant(ctx, participant)
+	if errors.Is(err, repository.ErrParticipantNotFound) {
+		return ErrParticipantMissing
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update participant: %w", err)
 	}
 
@@ -175,15 +327,19 @@ func (uc *RoomUseCase) GetChatHistory(ctx context.Context, roomID string, limit
 	return messages, nil
 }
 
-func (uc *RoomUseCase) StartRecording(ctx context.Context, roomID, hostID string) (*entity.Recording, error) {
+func (uc *RoomUseCase) StartRecording(ctx context.Context, roomID, userID string) (*entity.Recording, error) {
 
 	room, err := uc.roomRepo.Get(ctx, roomID)
 	if err != nil {
 		return nil, fmt.Errorf("room not found: %w", err)
 	}
 
-	if room.HostID != hostID {
-		return nil, fmt.Errorf("only host can start recording")
+	role, err := uc.memberRole(ctx, room, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(room, role, entity.CapabilityRecord); err != nil {
+		return nil, err
 	}
 
 	if room.IsRecording {
@@ -202,25 +358,61 @@ func (uc *RoomUseCase) StartRecording(ctx context.Context, roomID, hostID string
 		return nil, fmt.Errorf("failed to create recording: %w", err)
 	}
 
-	room.IsRecording = true
-	if err := uc.roomRepo.Update(ctx, room); err != nil {
+	_, err = uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		r.IsRecording = true
+		r.RecordingID = recording.ID
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return recording, nil
 }
 
-func (uc *RoomUseCase) StopRecording(ctx context.Context, roomID, recordingID, hostID string) error {
+func (uc *RoomUseCase) StopRecording(ctx context.Context, roomID, recordingID, userID string) error {
 
 	room, err := uc.roomRepo.Get(ctx, roomID)
 	if err != nil {
 		return fmt.Errorf("room not found: %w", err)
 	}
 
-	if room.HostID != hostID {
-		return fmt.Errorf("only host can stop recording")
+	// a host can always stop, even after recording was disabled
+	role, err := uc.memberRole(ctx, room, userID)
+	if err != nil {
+		return err
+	}
+	if role != entity.RoleHost {
+		if err := authorize(room, role, entity.CapabilityRecord); err != nil {
+			return err
+		}
+	}
+
+	// the caller's say over recording ends at their own room's
+	if recordingID == "" || recordingID != room.RecordingID {
+		return ErrNotRoomRecording
+	}
+
+	return uc.finishRecording(ctx, room, recordingID)
+}
+
+// finishRecording closes the recording and hands it to processing.
+func (uc *RoomUseCase) finishRecording(ctx context.Context, room *entity.Room, recordingID string) error {
+	if err := uc.closeRecording(ctx, recordingID); err != nil {
+		return err
 	}
 
+	_, err := uc.changeRoom(ctx, room, func(r *entity.Room) error {
+		r.IsRecording = false
+		r.RecordingID = ""
+		return nil
+	})
+	return err
+}
+
+// closeRecording hands the recording to processing without touching the
+// room, which may be on its way out.
+func (uc *RoomUseCase) closeRecording(ctx context.Context, recordingID string) error {
 	recording, err := uc.recordingRepo.Get(ctx, recordingID)
 	if err != nil {
 		return fmt.Errorf("recording not found: %w", err)
@@ -230,16 +422,7 @@ func (uc *RoomUseCase) StopRecording(ctx context.Context, roomID, recordingID, h
 	recording.Duration = int(recording.EndTime.Sub(recording.StartTime).Seconds())
 	recording.Status = "processing"
 
-	if err := uc.recordingRepo.Update(ctx, recording); err != nil {
-		return err
-	}
-
-	room.IsRecording = false
-	if err := uc.roomRepo.Update(ctx, room); err != nil {
-		return err
-	}
-
-	return nil
+	return uc.recordingRepo.Update(ctx, recording)
 }
 
 func (uc *RoomUseCase) AddRecordingChunk(ctx context.Context, recordingID, chunkURL string) error {
@@ -259,14 +442,6 @@ func (uc *RoomUseCase) GetRecording(ctx context.Context, recordingID string) (*e
 	return recording, nil
 }
 
-func (uc *RoomUseCase) ExtendRoomDuration(ctx context.Context, roomID string, duration time.Duration) error {
-	if err := uc.roomRepo.ExtendTTL(ctx, roomID, duration); err != nil {
-		return fmt.Errorf("failed to extend room duration: %w", err)
-	}
-
-	return nil
-}
-
 func (uc *RoomUseCase) GetConfiguration() config.TurnStunConfig {
 	config := uc.config.TurnStun
 	if uc.config.IsProduction() {
@@ -276,45 +451,3 @@ func (uc *RoomUseCase) GetConfiguration() config.TurnStunConfig {
 
 	return config
 }
-
-func (uc *RoomUseCase) StartScreenShare(ctx context.Context, roomID, userID string) error {
-
-	currentSharer, err := uc.roomRepo.GetScreenSharer(ctx, roomID)
-	if err != nil {
-		return fmt.Errorf("failed to check screen sharer: %w", err)
-	}
-
-	if currentSharer != "" && currentSharer != userID {
-		return fmt.Errorf("screen share already in progress by another user")
-	}
-
-	if err := uc.roomRepo.SetScreenSharer(ctx, roomID, userID); err != nil {
-		return fmt.Errorf("failed to set screen sharer: %w", err)
-	}
-
-	log.Printf("[UseCase] User %s started screen share in room %s", userID, roomID)
-	return nil
-}
-
-func (uc *RoomUseCase) StopScreenShare(ctx context.Context, roomID, userID string) error {
-
-	currentSharer, err := uc.roomRepo.GetScreenSharer(ctx, roomID)
-	if err != nil {
-		return fmt.Errorf("failed to check screen sharer: %w", err)
-	}
-
-	if currentSharer != userID {
-		return fmt.Errorf("you are not the current screen sharer")
-	}
-
-	if err := uc.roomRepo.ClearScreenSharer(ctx, roomID); err != nil {
-		return fmt.Errorf("failed to clear screen sharer: %w", err)
-	}
-
-	log.Printf("[UseCase] User %s stopped screen share in room %s", userID, roomID)
-	return nil
-}
-
-func (uc *RoomUseCase) GetScreenSharer(ctx context.Context, roomID string) (string, error) {
-	return uc.roomRepo.GetScreenSharer(ctx, roomID)
-}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+var (
+	ErrNoShareRequest = errors.New("participant has no pending screen share request")
+	ErrNotPresenting  = errors.New("participant is not sharing their screen")
+)
+
+// StartScreenShare makes participantID a presenter as the room's screen
+// share policy allows. When a host has to approve first the request is
+// queued instead and pending is true.
+func (uc *RoomUseCase) StartScreenShare(ctx context.Context, roomID, participantID string) (pending bool, err error) {
+	participant, err := uc.participantRepo.GetParticipant(ctx, roomID, participantID)
+	if err != nil {
+		return false, ErrParticipantMissing
+	}
+
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	policy := room.Settings.ScreenShare
+
+	if err := authorize(room, participant.Role(), entity.CapabilityScreenShare); err != nil {
+		return false, err
+	}
+
+	// hosts never wait for approval
+	if policy.RequireApproval && !participant.IsHost {
+		presenting, err := uc.isPresenting(ctx, roomID, participantID)
+		if err != nil {
+			return false, err
+		}
+		if presenting {
+			return false, nil
+		}
+
+		if err := uc.roomRepo.AddShareRequest(ctx, roomID, participantID); err != nil {
+			return false, fmt.Errorf("failed to queue screen share request: %w", err)
+		}
+		log.Printf("[UseCase] Participant %s asked to share their screen in room %s", participantID, roomID)
+		return true, nil
+	}
+
+	if err := uc.addPresenter(ctx, roomID, participantID, policy); err != nil {
+		return false, err
+	}
+
+	log.Printf("[UseCase] Participant %s started screen share in room %s", participantID, roomID)
+	return false, nil
+}
+
+func (uc *RoomUseCase) StopScreenShare(ctx context.Context, roomID, participantID string) error {
+	removed, err := uc.roomRepo.RemovePresenter(ctx, roomID, participantID)
+	if err != nil {
+		return fmt.Errorf("failed to remove presenter: %w", err)
+	}
+	if !removed {
+		return ErrNotScreenSharer
+	}
+
+	log.Printf("[UseCase] Participant %s stopped screen share in room %s", participantID, roomID)
+	return nil
+}
+
+// CancelScreenShareRequest withdraws a pending request and reports whether
+// there was one.
+func (uc *RoomUseCase) CancelScreenShareRequest(ctx context.Context, roomID, participantID string) (bool, error) {
+	removed, err := uc.roomRepo.RemoveShareRequest(ctx, roomID, participantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove screen share request: %w", err)
+	}
+
+	return removed, nil
+}
+
+// GrantScreenShare lets a host turn a pending request into a presenter. The
+// request keeps its place in the queue if every presenter slot is taken.
+func (uc *RoomUseCase) GrantScreenShare(ctx context.Context, roomID, hostID, targetID string) error {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return err
+	}
+
+	requests, err := uc.GetShareRequests(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(requests, targetID) {
+		return ErrNoShareRequest
+	}
+
+	room, err := uc.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.addPresenter(ctx, roomID, targetID, room.Settings.ScreenShare); err != nil {
+		return err
+	}
+
+	if _, err := uc.roomRepo.RemoveShareRequest(ctx, roomID, targetID); err != nil {
+		return fmt.Errorf("failed to remove screen share request: %w", err)
+	}
+
+	log.Printf("[UseCase] Host %s let %s share their screen in room %s", hostID, targetID, roomID)
+	return nil
+}
+
+func (uc *RoomUseCase) DenyScreenShare(ctx context.Context, roomID, hostID, targetID string) error {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return err
+	}
+
+	removed, err := uc.CancelScreenShareRequest(ctx, roomID, targetID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNoShareRequest
+	}
+
+	log.Printf("[UseCase] Host %s denied screen share to %s in room %s", hostID, targetID, roomID)
+	return nil
+}
+
+// StopPresenter lets a host end another participant's screen share.
+func (uc *RoomUseCase) StopPresenter(ctx context.Context, roomID, hostID, targetID string) error {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return err
+	}
+
+	removed, err := uc.roomRepo.RemovePresenter(ctx, roomID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to remove presenter: %w", err)
+	}
+	if !removed {
+		return ErrNotPresenting
+	}
+
+	log.Printf("[UseCase] Host %s stopped screen share of %s in room %s", hostID, targetID, roomID)
+	return nil
+}
+
+// TakeOverScreenShare stops every other presenter and makes the host the
+// only one. It returns the presenters that were stopped.
+func (uc *RoomUseCase) TakeOverScreenShare(ctx context.Context, roomID, hostID string) ([]string, error) {
+	if _, err := uc.requireHost(ctx, roomID, hostID); err != nil {
+		return nil, err
+	}
+
+	presenters, err := uc.GetPresenters(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopped := make([]string, 0, len(presenters))
+	for _, presenter := range presenters {
+		if presenter == hostID {
+			continue
+		}
+		removed, err := uc.roomRepo.RemovePresenter(ctx, roomID, presenter)
+		if err != nil {
+			return stopped, fmt.Errorf("failed to remove presenter: %w", err)
+		}
+		if removed {
+			stopped = append(stopped, presenter)
+		}
+	}
+
+	// the host needs only one slot, and every other presenter just left it
+	if err := uc.addPresenter(ctx, roomID, hostID, entity.ScreenSharePolicy{}); err != nil {
+		return stopped, err
+	}
+
+	log.Printf("[UseCase] Host %s took over screen share in room %s", hostID, roomID)
+	return stopped, nil
+}
+
+func (uc *RoomUseCase) GetPresenters(ctx context.Context, roomID string) ([]string, error) {
+	presenters, err := uc.roomRepo.GetPresenters(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presenters: %w", err)
+	}
+
+	return presenters, nil
+}
+
+func (uc *RoomUseCase) GetShareRequests(ctx context.Context, roomID string) ([]string, error) {
+	requests, err := uc.roomRepo.GetShareRequests(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get screen share requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+func (uc *RoomUseCase) addPresenter(ctx context.Context, roomID, participantID string, policy entity.ScreenSharePolicy) error {
+	added, err := uc.roomRepo.AddPresenter(ctx, roomID, participantID, policy.Presenters())
+	if err != nil {
+		return fmt.Errorf("failed to add presenter: %w", err)
+	}
+	if !added {
+		return ErrScreenShareBusy
+	}
+
+	return nil
+}
+
+func (uc *RoomUseCase) isPresenting(ctx context.Context, roomID, participantID string) (bool, error) {
+	presenters, err := uc.GetPresenters(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(presenters, participantID), nil
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxParticipants = 100
+	maxTemplatesPerUser    = 50
+)
+
+var (
+	ErrInvalidTemplate  = errors.New("invalid room template")
+	ErrTooManyTemplates = fmt.Errorf("no more than %d room templates per user", maxTemplatesPerUser)
+	ErrBuiltInTemplate  = errors.New("built-in room templates cannot be changed")
+)
+
+// builtInTemplates are offered to everyone next to their own templates.
+// They are built on every call so nobody shares their settings' maps.
+func builtInTemplates() []*entity.RoomTemplate {
+	return []*entity.RoomTemplate{{
+		ID:   "standup",
+		Name: "Standup",
+		RoomDefaults: entity.RoomDefaults{
+			MaxParticipants: 25,
+			Settings: entity.RoomSettings{
+				AllowScreenShare: true,
+				AllowChat:        true,
+				MaxDuration:      15,
+			},
+		},
+	}, {
+		ID:   "webinar",
+		Name: "Webinar",
+		RoomDefaults: entity.RoomDefaults{
+			MaxParticipants: 500,
+			Settings: entity.RoomSettings{
+				AllowChat:        true,
+				AllowGuests:      true,
+				RecordingEnabled: true,
+				Roles: map[entity.Role]entity.RolePermissions{
+					entity.RoleGuest: {Chat: boolPtr(false)},
+				},
+			},
+		},
+	}, {
+		ID:   "interview",
+		Name: "Interview",
+		RoomDefaults: entity.RoomDefaults{
+			MaxParticipants: 5,
+			Settings: entity.RoomSettings{
+				AllowScreenShare: true,
+				AllowChat:        true,
+				WaitingRoom:      true,
+				AllowGuests:      true,
+				MaxDuration:      60,
+			},
+		},
+	}}
+}
+
+func builtInTemplate(templateID string) *entity.RoomTemplate {
+	for _, template := range builtInTemplates() {
+		if template.ID == templateID {
+			return template
+		}
+	}
+	return nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// TemplateUseCase decides what a new room starts with: a template, or else
+// its creator's defaults, or else the built-in defaults.
+type TemplateUseCase struct {
+	templateRepo repository.TemplateRepository
+}
+
+func NewTemplateUseCase(templateRepo repository.TemplateRepository) *TemplateUseCase {
+	return &TemplateUseCase{templateRepo: templateRepo}
+}
+
+type CreateTemplateInput struct {
+	UserID string
+	Name   string
+	entity.RoomDefaults
+}
+
+// CreateTemplate saves a template only its creator sees.
+func (uc *TemplateUseCase) CreateTemplate(ctx context.Context, input CreateTemplateInput) (*entity.RoomTemplate, error) {
+	if input.UserID == "" || input.UserID == AnonymousHostID {
+		return nil, ErrNotHost
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if utf8.RuneCountInString(name) > maxRoomNameLength {
+		return nil, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidTemplate, maxRoomNameLength)
+	}
+	if err := validateRoomDefaults(&input.RoomDefaults, ErrInvalidTemplate); err != nil {
+		return nil, err
+	}
+
+	existing, err := uc.templateRepo.ListTemplates(ctx, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	if len(existing) >= maxTemplatesPerUser {
+		return nil, ErrTooManyTemplates
+	}
+
+	template := &entity.RoomTemplate{
+		ID:           uuid.New().String(),
+		Name:         name,
+		OwnerID:      input.UserID,
+		CreatedAt:    time.Now(),
+		RoomDefaults: input.RoomDefaults,
+	}
+	if err := uc.templateRepo.SaveTemplate(ctx, template); err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+
+	log.Printf("[UseCase] User %s created room template %s", input.UserID, template.ID)
+	return template, nil
+}
+
+// ListTemplates returns the built-in templates followed by the user's own.
+func (uc *TemplateUseCase) ListTemplates(ctx context.Context, userID string) ([]*entity.RoomTemplate, error) {
+	templates := builtInTemplates()
+	if userID == "" || userID == AnonymousHostID {
+		return templates, nil
+	}
+
+	own, err := uc.templateRepo.ListTemplates(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	return append(templates, own...), nil
+}
+
+func (uc *TemplateUseCase) DeleteTemplate(ctx context.Context, userID, templateID string) error {
+	if builtInTemplate(templateID) != nil {
+		return ErrBuiltInTemplate
+	}
+	return uc.templateRepo.DeleteTemplate(ctx, userID, templateID)
+}
+
+// GetRoomDefaults returns what the user's rooms start with when no template
+// is picked.
+func (uc *TemplateUseCase) GetRoomDefaults(ctx context.Context, userID string) (entity.RoomDefaults, error) {
+	if userID != "" && userID != AnonymousHostID {
+		defaults, err := uc.templateRepo.GetRoomDefaults(ctx, userID)
+		if err != nil {
+			return entity.RoomDefaults{}, fmt.Errorf("failed to get room defaults: %w", err)
+		}
+		if defaults != nil {
+			return *defaults, nil
+		}
+	}
+
+	return entity.RoomDefaults{
+		MaxParticipants: defaultMaxParticipants,
+		Settings:        entity.DefaultRoomSettings(),
+	}, nil
+}
+
+func (uc *TemplateUseCase) SetRoomDefaults(ctx context.Context, userID string, defaults entity.RoomDefaults) error {
+	if userID == "" || userID == AnonymousHostID {
+		return ErrNotHost
+	}
+	if err := validateRoomDefaults(&defaults, ErrInvalidTemplate); err != nil {
+		return err
+	}
+	if err := uc.templateRepo.SetRoomDefaults(ctx, userID, &defaults); err != nil {
+		return fmt.Errorf("failed to save room defaults: %w", err)
+	}
+	return nil
+}
+
+// RoomDefaults returns what a room the user creates from templateID starts
+// with, or from their defaults when templateID is empty.
+func (uc *TemplateUseCase) RoomDefaults(ctx context.Context, userID, templateID string) (entity.RoomDefaults, error) {
+	if templateID == "" {
+		return uc.GetRoomDefaults(ctx, userID)
+	}
+
+	if template := builtInTemplate(templateID); template != nil {
+		return template.RoomDefaults, nil
+	}
+	if userID == "" || userID == AnonymousHostID {
+		return entity.RoomDefaults{}, repository.ErrTemplateNotFound
+	}
+
+	template, err := uc.templateRepo.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return entity.RoomDefaults{}, err
+	}
+	return template.RoomDefaults, nil
+}
+
+func validateRoomDefaults(d *entity.RoomDefaults, invalid error) error {
+	if d.MaxParticipants < 0 {
+		return fmt.Errorf("%w: maxParticipants must not be negative", invalid)
+	}
+	if d.MaxParticipants == 0 {
+		d.MaxParticipants = defaultMaxParticipants
+	}
+	return validateSettings(&d.Settings, invalid)
+}
@@ -47,6 +47,30 @@ var (
 			Help: "Total number of participants across all rooms",
 		},
 	)
+
+	sendQueueDepth = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "signaling_send_queue_depth",
+			Help:    "Messages waiting in a connection's send queue when another is queued",
+			Buckets: []float64{0, 1, 4, 16, 64, 128, 256, 1024},
+		},
+		[]string{"class"},
+	)
+
+	messagesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "signaling_messages_dropped_total",
+			Help: "Messages dropped because a connection's send queue was full",
+		},
+		[]string{"class"},
+	)
+
+	slowClientsEvicted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "signaling_slow_clients_evicted_total",
+			Help: "Connections closed for not keeping up with their messages",
+		},
+	)
 )
 
 func init() {
@@ -55,6 +79,9 @@ func init() {
 	prometheus.MustRegister(activeRooms)
 	prometheus.MustRegister(activeConnections)
 	prometheus.MustRegister(totalParticipants)
+	prometheus.MustRegister(sendQueueDepth)
+	prometheus.MustRegister(messagesDropped)
+	prometheus.MustRegister(slowClientsEvicted)
 }
 
 func MetricsMiddleware() fiber.Handler {
@@ -92,3 +119,15 @@ func UpdateActiveConnections(count float64) {
 func UpdateTotalParticipants(count float64) {
 	totalParticipants.Set(count)
 }
+
+func ObserveSendQueueDepth(class string, depth int) {
+	sendQueueDepth.WithLabelValues(class).Observe(float64(depth))
+}
+
+func IncMessagesDropped(class string) {
+	messagesDropped.WithLabelValues(class).Inc()
+}
+
+func IncSlowClientsEvicted() {
+	slowClientsEvicted.Inc()
+}
@@ -0,0 +1,153 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// Broker fans messages out between signaling nodes. Subscriptions are
+// dynamic so a node only receives traffic for rooms it has clients in.
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channels ...string) error
+	Unsubscribe(ctx context.Context, channels ...string) error
+	Messages() <-chan *Message
+	Close() error
+}
+
+// ============= REDIS BROKER =============
+
+type RedisBroker struct {
+	client   *redis.Client
+	pubsub   *redis.PubSub
+	messages chan *Message
+}
+
+func NewRedisBroker(ctx context.Context, client *redis.Client, channels ...string) (*RedisBroker, error) {
+	ps := client.Subscribe(ctx, channels...)
+
+	// wait for the subscription confirmation so we fail fast on a bad connection
+	if len(channels) > 0 {
+		if _, err := ps.Receive(ctx); err != nil {
+			ps.Close()
+			return nil, err
+		}
+	}
+
+	b := &RedisBroker{
+		client:   client,
+		pubsub:   ps,
+		messages: make(chan *Message, 1024),
+	}
+	go b.forward()
+
+	return b, nil
+}
+
+func (b *RedisBroker) forward() {
+	defer close(b.messages)
+
+	for msg := range b.pubsub.Channel(redis.WithChannelSize(1024)) {
+		b.messages <- &Message{
+			Channel: msg.Channel,
+			Payload: []byte(msg.Payload),
+		}
+	}
+	log.Println("[PubSub] Redis subscription closed")
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.client.Publish(ctx, channel, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, channels ...string) error {
+	return b.pubsub.Subscribe(ctx, channels...)
+}
+
+func (b *RedisBroker) Unsubscribe(ctx context.Context, channels ...string) error {
+	return b.pubsub.Unsubscribe(ctx, channels...)
+}
+
+func (b *RedisBroker) Messages() <-chan *Message {
+	return b.messages
+}
+
+func (b *RedisBroker) Close() error {
+	return b.pubsub.Close()
+}
+
+// ============= LOCAL BROKER =============
+
+// LocalBroker is an in-process Broker for single-node setups and tests.
+type LocalBroker struct {
+	mu       sync.RWMutex
+	channels map[string]bool
+	messages chan *Message
+	closed   bool
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{
+		channels: make(map[string]bool),
+		messages: make(chan *Message, 1024),
+	}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed || !b.channels[channel] {
+		return nil
+	}
+
+	select {
+	case b.messages <- &Message{Channel: channel, Payload: payload}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *LocalBroker) Subscribe(ctx context.Context, channels ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range channels {
+		b.channels[ch] = true
+	}
+	return nil
+}
+
+func (b *LocalBroker) Unsubscribe(ctx context.Context, channels ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range channels {
+		delete(b.channels, ch)
+	}
+	return nil
+}
+
+func (b *LocalBroker) Messages() <-chan *Message {
+	return b.messages
+}
+
+func (b *LocalBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.messages)
+	}
+	return nil
+}
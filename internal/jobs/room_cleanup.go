@@ -1,34 +1,92 @@
 package jobs
 
 import (
+	"bincang-visual/internal/domain/repository"
 	"bincang-visual/internal/domain/usecase"
+	"context"
 	"log"
 	"time"
 )
 
+const (
+	cleanupLock = "room-cleanup"
+
+	// rooms looked at per run, the rest wait for the next one
+	cleanupBatchSize = 500
+)
+
+// RoomCleanupJob periodically reaps idle rooms. Every replica runs it, but
+// a shared lock lets only one of them clean up at a time.
 type RoomCleanupJob struct {
-	roomUseCase *usecase.RoomUseCase
+	cleanupUseCase *usecase.CleanupUseCase
+	lockRepo       repository.LockRepository
+	nodeID         string
+	interval       time.Duration
+	idleTimeout    time.Duration
+	done           chan struct{}
 }
 
-func NewRoomCleanupJob(roomUseCase *usecase.RoomUseCase) *RoomCleanupJob {
-	return &RoomCleanupJob{roomUseCase: roomUseCase}
+func NewRoomCleanupJob(
+	cleanupUseCase *usecase.CleanupUseCase,
+	lockRepo repository.LockRepository,
+	nodeID string,
+	interval, idleTimeout time.Duration,
+) *RoomCleanupJob {
+	return &RoomCleanupJob{
+		cleanupUseCase: cleanupUseCase,
+		lockRepo:       lockRepo,
+		nodeID:         nodeID,
+		interval:       interval,
+		idleTimeout:    idleTimeout,
+		done:           make(chan struct{}),
+	}
 }
 
 func (j *RoomCleanupJob) Start() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(j.interval)
 	go func() {
-		for range ticker.C {
-			j.cleanup()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.cleanup()
+			case <-j.done:
+				return
+			}
 		}
 	}()
 }
 
+func (j *RoomCleanupJob) Stop() {
+	close(j.done)
+}
+
 func (j *RoomCleanupJob) cleanup() {
+	ctx := context.Background()
+
+	// the lock outlives a crashed owner by at most one interval
+	acquired, err := j.lockRepo.AcquireLock(ctx, cleanupLock, j.nodeID, j.interval)
+	if err != nil {
+		log.Printf("[Cleanup] Failed to acquire lock: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	defer func() {
+		if err := j.lockRepo.ReleaseLock(ctx, cleanupLock, j.nodeID); err != nil {
+			log.Printf("[Cleanup] Failed to release lock: %v", err)
+		}
+	}()
 
 	log.Println("[Cleanup] Running room cleanup job")
 
-	// TODO: Get all rooms then
-	// delete rooms older than 24 hours with no participants
+	report, err := j.cleanupUseCase.CleanupIdleRooms(ctx, j.idleTimeout, cleanupBatchSize)
+	if err != nil {
+		log.Printf("[Cleanup] Room cleanup failed: %v", err)
+		return
+	}
 
-	// ctx := context.Background()
+	log.Printf("[Cleanup] Checked %d rooms: %d closed, %d orphaned, %d stale participants removed",
+		report.Checked, report.Closed, report.Orphaned, report.StaleParticipants)
 }
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientIPMiddleware narrows the proxy header down to the one address c.IP()
+// may trust: the rightmost one that isn't a trusted proxy. Proxies append to
+// X-Forwarded-For, so anything left of it was written by the client and
+// could be anything. Register it before every handler that reads c.IP().
+func ClientIPMiddleware(proxyHeader string, trustedProxies []string) fiber.Handler {
+	trusted := parseProxies(trustedProxies)
+
+	return func(c *fiber.Ctx) error {
+		if proxyHeader == "" || !c.IsProxyTrusted() {
+			return c.Next()
+		}
+
+		entries := strings.Split(c.Get(proxyHeader), ",")
+		client := ""
+		for i := len(entries) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(entries[i]))
+			// nothing left of an entry no proxy wrote can be trusted
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !trusted.contains(ip) {
+				break
+			}
+		}
+
+		// without a usable entry c.IP() falls back to the peer address
+		if client == "" {
+			c.Request().Header.Del(proxyHeader)
+		} else {
+			c.Request().Header.Set(proxyHeader, client)
+		}
+		return c.Next()
+	}
+}
+
+type proxies []*net.IPNet
+
+// parseProxies reads trusted proxies the way Fiber does: IPs or CIDRs.
+func parseProxies(entries []string) proxies {
+	var nets proxies
+	for _, entry := range entries {
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return nets
+}
+
+func (p proxies) contains(ip net.IP) bool {
+	for _, ipNet := range p {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// GuestCookie keeps a guest's identity across connections, so a ban holds
+// for the person rather than for one socket. Clients that can't keep
+// cookies pass its value back in the `guest` query param instead.
+const GuestCookie = "bv_guest"
+
+const guestIdentityTTL = 30 * 24 * time.Hour
+
+// guestIdentity returns the guest ID signed into the request's cookie or
+// `guest` query param, or mints a new one. Either way the cookie is
+// (re)issued so the identity lives on.
+func guestIdentity(c *fiber.Ctx, secret string) string {
+	guestID, ok := verifyGuestToken(c.Cookies(GuestCookie), secret)
+	if !ok {
+		guestID, ok = verifyGuestToken(c.Query("guest"), secret)
+	}
+	if !ok {
+		guestID = "guest-" + uuid.New().String()
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     GuestCookie,
+		Value:    signGuestToken(guestID, secret),
+		Expires:  time.Now().Add(guestIdentityTTL),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: "Lax",
+	})
+	return guestID
+}
+
+func signGuestToken(guestID, secret string) string {
+	return guestID + "." + guestSignature(guestID, secret)
+}
+
+func verifyGuestToken(token, secret string) (string, bool) {
+	guestID, signature, found := strings.Cut(token, ".")
+	if !found || !strings.HasPrefix(guestID, "guest-") {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(guestSignature(guestID, secret))) {
+		return "", false
+	}
+	return guestID, true
+}
+
+func guestSignature(guestID, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("guest:" + guestID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
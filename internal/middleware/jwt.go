@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -32,20 +33,15 @@ func JWTMiddleware(secret string) fiber.Handler {
 			})
 		}
 
-		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseJWT(tokenString, secret)
+		if errors.Is(err, ErrInvalidClaims) {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
+				"error": "Invalid token claims",
 			})
 		}
-
-		claims, ok := token.Claims.(*JWTClaims)
-		if !ok {
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token claims",
+				"error": "Invalid token",
 			})
 		}
 
@@ -57,6 +53,29 @@ func JWTMiddleware(secret string) fiber.Handler {
 	}
 }
 
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrInvalidClaims = errors.New("invalid token claims")
+)
+
+// ParseJWT validates a token issued by GenerateJWT and returns its claims.
+func ParseJWT(tokenString, secret string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || claims.UserID == "" {
+		return nil, ErrInvalidClaims
+	}
+
+	return claims, nil
+}
+
 func GenerateJWT(userID, email, displayName, secret string, expiration int) (string, error) {
 	claims := JWTClaims{
 		UserID:      userID,
@@ -85,16 +104,10 @@ func OptionalJWTMiddleware(secret string) fiber.Handler {
 			return c.Next()
 		}
 
-		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(*JWTClaims); ok {
-				c.Locals("userID", claims.UserID)
-				c.Locals("email", claims.Email)
-				c.Locals("displayName", claims.DisplayName)
-			}
+		if claims, err := ParseJWT(tokenString, secret); err == nil {
+			c.Locals("userID", claims.UserID)
+			c.Locals("email", claims.Email)
+			c.Locals("displayName", claims.DisplayName)
 		}
 
 		return c.Next()
@@ -2,9 +2,14 @@ package redis
 
 import (
 	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,12 +17,71 @@ import (
 
 const (
 	roomPrefix        = "room:"
+	roomCodePrefix    = "room_code:"
 	participantPrefix = "room:%s:participants"
+	lobbyPrefix       = "room:%s:lobby"
+	banPrefix         = "room:%s:bans"
+	versionPrefix     = "room:%s:version"
+	presenterPrefix   = "room:%s:presenters"
+	shareReqPrefix    = "room:%s:share_requests"
+	noticePrefix      = "room:%s:notice:%s"
+	noticesPrefix     = "room:%s:notices"
+	passcodePrefix    = "room:%s:passcode"
+	roomFailPrefix    = "room:%s:passcode_failures"
+	ipFailPrefix      = "passcode_failures:"
+	invitesPrefix     = "room:%s:invites"
+	inviteUsesPrefix  = "room:%s:invite:%s:uses"
+	presencePrefix    = "room:%s:presence"
+	nodePrefix        = "node:"
+	sessionPrefix     = "session:"
+	sessionBufPrefix  = "session:%s:buffer"
 	chatPrefix        = "room:%s:chat"
 	recordingPrefix   = "recording:"
 	userPrefix        = "user:"
+	lockPrefix        = "lock:"
+	templatesPrefix   = "room_templates:"
+	roomDefaultPrefix = "room_defaults:"
+
+	// roomIndexKey scores every room by its last activity, so cleanup finds
+	// idle rooms without scanning the keyspace
+	roomIndexKey = "rooms:activity"
+
+	// hostRoomsPrefix scores a host's rooms by when they were created, and
+	// activeRoomsKey holds the rooms anybody is admitted to. Rooms that
+	// expire are dropped from both when a listing comes across them.
+	hostRoomsPrefix = "rooms:host:"
+	activeRoomsKey  = "rooms:active"
+
+	// usersByEmailKey maps emails to user IDs, and roomRecordingsPrefix a
+	// room to its recordings, which outlive the room by a week
+	usersByEmailKey      = "users:by_email"
+	roomRecordingsPrefix = "recordings:room:"
 )
 
+// touchRoom refreshes an indexed room. XX keeps a late write from putting a
+// deleted room back.
+func touchRoom(ctx context.Context, cmd redis.Cmdable, roomID string) *redis.IntCmd {
+	return cmd.ZAddXX(ctx, roomIndexKey, redis.Z{Score: float64(time.Now().Unix()), Member: roomID})
+}
+
+// deleteIfEqualScript deletes a key only while it still holds the value the
+// caller knows, so it never takes away what someone else set since.
+var deleteIfEqualScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// hdelIfEqualScript deletes a hash field only while it still holds the value
+// the caller knows.
+var hdelIfEqualScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
 // ============= ROOM REPOSITORY =============
 
 type RoomRepositoryImpl struct {
@@ -28,13 +92,116 @@ func NewRoomRepository(client *redis.Client) *RoomRepositoryImpl {
 	return &RoomRepositoryImpl{client: client}
 }
 
+// createRoomScript stores a room under its code unless the room exists or
+// another room holds the code already. A ttl of 0 keeps both forever. Rooms
+// without a score are left out of their host's index.
+//
+// KEYS: room, code, room index, host index
+// ARGV: room, room ID, ttl, code, now, created at
+var createRoomScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return -1
+end
+local ttl = tonumber(ARGV[3])
+if ARGV[4] ~= "" then
+	if ttl > 0 then
+		if not redis.call("SET", KEYS[2], ARGV[2], "NX", "PX", ttl) then
+			return 0
+		end
+	elseif not redis.call("SET", KEYS[2], ARGV[2], "NX") then
+		return 0
+	end
+end
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+redis.call("ZADD", KEYS[3], ARGV[5], ARGV[2])
+if ARGV[6] ~= "" then
+	redis.call("ZADD", KEYS[4], ARGV[6], ARGV[2])
+end
+return 1
+`)
+
+// hostRoomScore orders a host's rooms. Rooms created in the same millisecond
+// are told apart by their ID.
+func hostRoomScore(createdAt time.Time) int64 {
+	return createdAt.UnixMilli()
+}
+
 func (r *RoomRepositoryImpl) Create(ctx context.Context, room *entity.Room, ttl time.Duration) error {
-	key := roomPrefix + room.ID
 	data, err := json.Marshal(room)
 	if err != nil {
 		return fmt.Errorf("failed to marshal room: %w", err)
 	}
-	return r.client.Set(ctx, key, data, ttl).Err()
+
+	// rooms of anonymous hosts belong to nobody in particular
+	var score string
+	if room.HostID != "" && room.HostID != entity.AnonymousHostID {
+		score = strconv.FormatInt(hostRoomScore(room.CreatedAt), 10)
+	}
+
+	keys := []string{roomPrefix + room.ID, roomCodePrefix + room.Code, roomIndexKey, hostRoomsPrefix + room.HostID}
+	created, err := createRoomScript.Run(ctx, r.client, keys,
+		data, room.ID, ttl.Milliseconds(), room.Code, time.Now().Unix(), score).Int()
+	if err != nil {
+		return err
+	}
+
+	switch created {
+	case -1:
+		return repository.ErrRoomExists
+	case 0:
+		return repository.ErrCodeTaken
+	}
+	return nil
+}
+
+// claimCodeScript gives the room another code that expires with it.
+var claimCodeScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl == -2 then
+	return -1
+end
+local claimed
+if ttl > 0 then
+	claimed = redis.call("SET", KEYS[2], ARGV[1], "NX", "PX", ttl)
+else
+	claimed = redis.call("SET", KEYS[2], ARGV[1], "NX")
+end
+if not claimed then
+	return 0
+end
+return 1
+`)
+
+func (r *RoomRepositoryImpl) ClaimCode(ctx context.Context, code, roomID string) error {
+	keys := []string{roomPrefix + roomID, roomCodePrefix + code}
+	claimed, err := claimCodeScript.Run(ctx, r.client, keys, roomID).Int()
+	if err != nil {
+		return err
+	}
+
+	switch claimed {
+	case -1:
+		return repository.ErrRoomNotFound
+	case 0:
+		return repository.ErrCodeTaken
+	}
+	return nil
+}
+
+func (r *RoomRepositoryImpl) ReleaseCode(ctx context.Context, code, roomID string) error {
+	return deleteIfEqualScript.Run(ctx, r.client, []string{roomCodePrefix + code}, roomID).Err()
+}
+
+func (r *RoomRepositoryImpl) ResolveCode(ctx context.Context, code string) (string, error) {
+	roomID, err := r.client.Get(ctx, roomCodePrefix+code).Result()
+	if err == redis.Nil {
+		return "", repository.ErrRoomNotFound
+	}
+	return roomID, err
 }
 
 func (r *RoomRepositoryImpl) Get(ctx context.Context, roomID string) (*entity.Room, error) {
@@ -42,7 +209,7 @@ func (r *RoomRepositoryImpl) Get(ctx context.Context, roomID string) (*entity.Ro
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("room not found")
+			return nil, repository.ErrRoomNotFound
 		}
 		return nil, err
 	}
@@ -56,20 +223,121 @@ func (r *RoomRepositoryImpl) Get(ctx context.Context, roomID string) (*entity.Ro
 
 func (r *RoomRepositoryImpl) Update(ctx context.Context, room *entity.Room) error {
 	key := roomPrefix + room.ID
-	ttl, err := r.client.TTL(ctx, key).Result()
+
+	updated := *room
+	updated.Version++
+	data, err := json.Marshal(&updated)
 	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return repository.ErrRoomNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var current entity.Room
+		if err := json.Unmarshal(stored, &current); err != nil {
+			return fmt.Errorf("failed to unmarshal room: %w", err)
+		}
+		if current.Version != room.Version {
+			return repository.ErrRoomChanged
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+			touchRoom(ctx, pipe, room.ID)
+			return nil
+		})
 		return err
+	}, key)
+
+	if errors.Is(err, redis.TxFailedErr) {
+		return repository.ErrRoomChanged
 	}
-	data, err := json.Marshal(room)
 	if err != nil {
-		return fmt.Errorf("failed to marshal room: %w", err)
+		return err
 	}
-	return r.client.Set(ctx, key, data, ttl).Err()
+
+	room.Version = updated.Version
+	return nil
 }
 
 func (r *RoomRepositoryImpl) Delete(ctx context.Context, roomID string) error {
-	key := roomPrefix + roomID
-	return r.client.Del(ctx, key).Err()
+	keys := []string{
+		roomPrefix + roomID,
+		fmt.Sprintf(participantPrefix, roomID),
+		fmt.Sprintf(lobbyPrefix, roomID),
+		fmt.Sprintf(chatPrefix, roomID),
+		fmt.Sprintf(presenterPrefix, roomID),
+		fmt.Sprintf(shareReqPrefix, roomID),
+		fmt.Sprintf(presencePrefix, roomID),
+		fmt.Sprintf(versionPrefix, roomID),
+		fmt.Sprintf(banPrefix, roomID),
+		fmt.Sprintf(passcodePrefix, roomID),
+		fmt.Sprintf(roomFailPrefix, roomID),
+		fmt.Sprintf(invitesPrefix, roomID),
+		fmt.Sprintf(noticesPrefix, roomID),
+	}
+
+	// the code may have gone to another room since this one's key expired
+	var code, hostID string
+	if room, err := r.Get(ctx, roomID); err == nil {
+		code, hostID = room.Code, room.HostID
+	}
+
+	// per invite and per notice keys are only known by their IDs
+	inviteIDs, err := r.client.HKeys(ctx, fmt.Sprintf(invitesPrefix, roomID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, inviteID := range inviteIDs {
+		keys = append(keys, fmt.Sprintf(inviteUsesPrefix, roomID, inviteID))
+	}
+	notices, err := r.client.SMembers(ctx, fmt.Sprintf(noticesPrefix, roomID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, notice := range notices {
+		keys = append(keys, fmt.Sprintf(noticePrefix, roomID, notice))
+	}
+
+	// nobody sees the room half deleted
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.ZRem(ctx, roomIndexKey, roomID)
+	pipe.SRem(ctx, activeRoomsKey, roomID)
+	if code != "" {
+		deleteIfEqualScript.Eval(ctx, pipe, []string{roomCodePrefix + code}, roomID)
+	}
+	if hostID != "" {
+		pipe.ZRem(ctx, hostRoomsPrefix+hostID, roomID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ClearSession leaves the room, its code, passcode, invites and bans in
+// place.
+func (r *RoomRepositoryImpl) ClearSession(ctx context.Context, roomID string) error {
+	keys := []string{
+		fmt.Sprintf(participantPrefix, roomID),
+		fmt.Sprintf(lobbyPrefix, roomID),
+		fmt.Sprintf(chatPrefix, roomID),
+		fmt.Sprintf(presenterPrefix, roomID),
+		fmt.Sprintf(shareReqPrefix, roomID),
+		fmt.Sprintf(presencePrefix, roomID),
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.SRem(ctx, activeRoomsKey, roomID)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 func (r *RoomRepositoryImpl) Exists(ctx context.Context, roomID string) (bool, error) {
@@ -79,28 +347,473 @@ func (r *RoomRepositoryImpl) Exists(ctx context.Context, roomID string) (bool, e
 }
 
 func (r *RoomRepositoryImpl) ExtendTTL(ctx context.Context, roomID string, duration time.Duration) error {
-	key := roomPrefix + roomID
-	return r.client.Expire(ctx, key, duration).Err()
+	room, err := r.Get(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ExpireGT(ctx, roomPrefix+roomID, duration)
+	if room.Code != "" {
+		pipe.ExpireGT(ctx, roomCodePrefix+room.Code, duration)
+	}
+	pipe.ExpireGT(ctx, fmt.Sprintf(passcodePrefix, roomID), duration)
+	pipe.ExpireGT(ctx, fmt.Sprintf(invitesPrefix, roomID), duration)
+	pipe.ExpireGT(ctx, fmt.Sprintf(banPrefix, roomID), duration)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
-func (r *RoomRepositoryImpl) SetScreenSharer(ctx context.Context, roomID, userID string) error {
-	key := fmt.Sprintf("room:%s:screen_sharer", roomID)
+// addPresenterScript admits a presenter only while there is room, so two
+// participants can't both take the last slot.
+var addPresenterScript = redis.NewScript(`
+if redis.call("SISMEMBER", KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+if redis.call("SCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("SADD", KEYS[1], ARGV[1])
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+return 1
+`)
 
-	return r.client.Set(ctx, key, userID, 24*time.Hour).Err()
+func (r *RoomRepositoryImpl) AddPresenter(ctx context.Context, roomID, participantID string, max int) (bool, error) {
+	key := fmt.Sprintf(presenterPrefix, roomID)
+	ttl := int((24 * time.Hour).Seconds())
+
+	added, err := addPresenterScript.Run(ctx, r.client, []string{key}, participantID, max, ttl).Int()
+	if err != nil {
+		return false, err
+	}
+	return added > 0, nil
+}
+
+func (r *RoomRepositoryImpl) RemovePresenter(ctx context.Context, roomID, participantID string) (bool, error) {
+	key := fmt.Sprintf(presenterPrefix, roomID)
+	removed, err := r.client.SRem(ctx, key, participantID).Result()
+	return removed > 0, err
+}
+
+func (r *RoomRepositoryImpl) GetPresenters(ctx context.Context, roomID string) ([]string, error) {
+	key := fmt.Sprintf(presenterPrefix, roomID)
+	return r.client.SMembers(ctx, key).Result()
 }
 
-func (r *RoomRepositoryImpl) GetScreenSharer(ctx context.Context, roomID string) (string, error) {
-	key := fmt.Sprintf("room:%s:screen_sharer", roomID)
-	userID, err := r.client.Get(ctx, key).Result()
+func (r *RoomRepositoryImpl) AddShareRequest(ctx context.Context, roomID, participantID string) error {
+	key := fmt.Sprintf(shareReqPrefix, roomID)
+
+	// NX keeps the place of a participant who asks twice
+	pipe := r.client.TxPipeline()
+	pipe.ZAddNX(ctx, key, redis.Z{Score: float64(time.Now().UnixMilli()), Member: participantID})
+	pipe.Expire(ctx, key, 24*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RoomRepositoryImpl) RemoveShareRequest(ctx context.Context, roomID, participantID string) (bool, error) {
+	key := fmt.Sprintf(shareReqPrefix, roomID)
+	removed, err := r.client.ZRem(ctx, key, participantID).Result()
+	return removed > 0, err
+}
+
+func (r *RoomRepositoryImpl) GetShareRequests(ctx context.Context, roomID string) ([]string, error) {
+	key := fmt.Sprintf(shareReqPrefix, roomID)
+	return r.client.ZRange(ctx, key, 0, -1).Result()
+}
+
+// claimNoticeScript claims a notice and remembers it among the room's
+// notices, which live as long as the longest lived of them.
+//
+// KEYS: notice, room notices
+// ARGV: notice, ttl in ms
+var claimNoticeScript = redis.NewScript(`
+if not redis.call("SET", KEYS[1], 1, "NX", "PX", ARGV[2]) then
+	return 0
+end
+redis.call("SADD", KEYS[2], ARGV[1])
+if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+func (r *RoomRepositoryImpl) ClaimNotice(ctx context.Context, roomID, notice string, ttl time.Duration) (bool, error) {
+	keys := []string{fmt.Sprintf(noticePrefix, roomID, notice), fmt.Sprintf(noticesPrefix, roomID)}
+	claimed, err := claimNoticeScript.Run(ctx, r.client, keys, notice, ttl.Milliseconds()).Int()
+	return claimed == 1, err
+}
+
+func (r *RoomRepositoryImpl) SetPasscode(ctx context.Context, roomID, hash string) error {
+	ttl, err := r.client.TTL(ctx, roomPrefix+roomID).Result()
+	if err != nil {
+		return err
+	}
+	switch {
+	case ttl == -2:
+		return repository.ErrRoomNotFound
+	case ttl < 0:
+		ttl = 0 // the room doesn't expire either
+	}
+	return r.client.Set(ctx, fmt.Sprintf(passcodePrefix, roomID), hash, ttl).Err()
+}
+
+func (r *RoomRepositoryImpl) GetPasscode(ctx context.Context, roomID string) (string, error) {
+	hash, err := r.client.Get(ctx, fmt.Sprintf(passcodePrefix, roomID)).Result()
 	if err == redis.Nil {
-		return "", nil // No one sharing
+		return "", nil
 	}
-	return userID, err
+	return hash, err
 }
 
-func (r *RoomRepositoryImpl) ClearScreenSharer(ctx context.Context, roomID string) error {
-	key := fmt.Sprintf("room:%s:screen_sharer", roomID)
-	return r.client.Del(ctx, key).Err()
+// failureKeys are the passcode failure counters for ip and the room. Callers
+// without a known address are only counted against the room.
+func failureKeys(roomID, ip string) []string {
+	keys := []string{fmt.Sprintf(roomFailPrefix, roomID)}
+	if ip != "" {
+		keys = append(keys, ipFailPrefix+ip)
+	}
+	return keys
+}
+
+// reservePasscodeAttemptScript counts an attempt against every counter, or
+// against none when one of them is used up. A counter expires window after
+// its first attempt, so the attempts come back in bursts at most.
+//
+// KEYS: room counter, ip counter (optional)
+// ARGV: window, room limit, ip limit
+var reservePasscodeAttemptScript = redis.NewScript(`
+local limits = {tonumber(ARGV[2]), tonumber(ARGV[3])}
+for i, key in ipairs(KEYS) do
+	if tonumber(redis.call("GET", key) or "0") >= limits[i] then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call("INCR", key)
+	if redis.call("PTTL", key) == -1 then
+		redis.call("PEXPIRE", key, ARGV[1])
+	end
+end
+return 1
+`)
+
+// releasePasscodeAttemptScript takes an attempt back from every counter
+// still around.
+//
+// KEYS: room counter, ip counter (optional)
+var releasePasscodeAttemptScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if tonumber(redis.call("GET", key) or "0") > 0 then
+		redis.call("DECR", key)
+	end
+end
+return 1
+`)
+
+func (r *RoomRepositoryImpl) ReservePasscodeAttempt(ctx context.Context, roomID, ip string, maxPerIP, maxPerRoom int, window time.Duration) (bool, error) {
+	reserved, err := reservePasscodeAttemptScript.Run(ctx, r.client, failureKeys(roomID, ip),
+		window.Milliseconds(), maxPerRoom, maxPerIP).Int()
+	if err != nil {
+		return false, err
+	}
+	return reserved == 1, nil
+}
+
+func (r *RoomRepositoryImpl) ReleasePasscodeAttempt(ctx context.Context, roomID, ip string) error {
+	return releasePasscodeAttemptScript.Run(ctx, r.client, failureKeys(roomID, ip)).Err()
+}
+
+// CreateInvite stores the invite next to the room. Each invite's users are
+// kept until it expires, so a revoked or expired invite leaves nothing behind
+// for long.
+func (r *RoomRepositoryImpl) CreateInvite(ctx context.Context, invite *entity.Invite) error {
+	data, err := json.Marshal(invite)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := r.client.TTL(ctx, roomPrefix+invite.RoomID).Result()
+	if err != nil {
+		return err
+	}
+	if ttl == -2 {
+		return repository.ErrRoomNotFound
+	}
+
+	key := fmt.Sprintf(invitesPrefix, invite.RoomID)
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, invite.ID, data)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RoomRepositoryImpl) GetInvite(ctx context.Context, roomID, inviteID string) (*entity.Invite, error) {
+	pipe := r.client.Pipeline()
+	get := pipe.HGet(ctx, fmt.Sprintf(invitesPrefix, roomID), inviteID)
+	uses := pipe.SCard(ctx, fmt.Sprintf(inviteUsesPrefix, roomID, inviteID))
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		return nil, repository.ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var invite entity.Invite
+	if err := json.Unmarshal([]byte(get.Val()), &invite); err != nil {
+		return nil, err
+	}
+	invite.Uses = int(uses.Val())
+	return &invite, nil
+}
+
+func (r *RoomRepositoryImpl) ListInvites(ctx context.Context, roomID string) ([]*entity.Invite, error) {
+	key := fmt.Sprintf(invitesPrefix, roomID)
+	data, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invites := make([]*entity.Invite, 0, len(data))
+	var expired []string
+	for id, raw := range data {
+		var invite entity.Invite
+		if err := json.Unmarshal([]byte(raw), &invite); err != nil {
+			continue
+		}
+		if now.After(invite.ExpiresAt) {
+			expired = append(expired, id)
+			continue
+		}
+		invites = append(invites, &invite)
+	}
+
+	if len(expired) > 0 {
+		r.client.HDel(ctx, key, expired...)
+	}
+
+	pipe := r.client.Pipeline()
+	uses := make([]*redis.IntCmd, len(invites))
+	for i, invite := range invites {
+		uses[i] = pipe.SCard(ctx, fmt.Sprintf(inviteUsesPrefix, roomID, invite.ID))
+	}
+	if len(invites) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for i, invite := range invites {
+		invite.Uses = int(uses[i].Val())
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites, nil
+}
+
+func (r *RoomRepositoryImpl) RevokeInvite(ctx context.Context, roomID, inviteID string) error {
+	pipe := r.client.TxPipeline()
+	removed := pipe.HDel(ctx, fmt.Sprintf(invitesPrefix, roomID), inviteID)
+	pipe.Del(ctx, fmt.Sprintf(inviteUsesPrefix, roomID, inviteID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if removed.Val() == 0 {
+		return repository.ErrInviteNotFound
+	}
+	return nil
+}
+
+// redeemInviteScript counts a user of an invite against the invite as
+// stored, so a revoke or the last use can't race a join.
+var redeemInviteScript = redis.NewScript(`
+local data = redis.call("HGET", KEYS[1], ARGV[1])
+if not data then
+	return -1
+end
+if redis.call("SISMEMBER", KEYS[2], ARGV[2]) == 1 then
+	return 1
+end
+local max = tonumber(cjson.decode(data).maxUses) or 0
+if max > 0 and redis.call("SCARD", KEYS[2]) >= max then
+	return -2
+end
+redis.call("SADD", KEYS[2], ARGV[2])
+redis.call("PEXPIREAT", KEYS[2], ARGV[3])
+return 1
+`)
+
+func (r *RoomRepositoryImpl) RedeemInvite(ctx context.Context, invite *entity.Invite, userID string) error {
+	keys := []string{
+		fmt.Sprintf(invitesPrefix, invite.RoomID),
+		fmt.Sprintf(inviteUsesPrefix, invite.RoomID, invite.ID),
+	}
+	result, err := redeemInviteScript.Run(ctx, r.client, keys, invite.ID, userID, invite.ExpiresAt.UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+
+	switch result {
+	case -1:
+		return repository.ErrInviteNotFound
+	case -2:
+		return repository.ErrInviteUsedUp
+	}
+	return nil
+}
+
+// AddBan blocks userID from the room for as long as the room exists.
+func (r *RoomRepositoryImpl) AddBan(ctx context.Context, roomID, userID string) error {
+	key := fmt.Sprintf(banPrefix, roomID)
+
+	ttl, err := r.client.TTL(ctx, roomPrefix+roomID).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.SAdd(ctx, key, userID)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RoomRepositoryImpl) IsBanned(ctx context.Context, roomID, userID string) (bool, error) {
+	key := fmt.Sprintf(banPrefix, roomID)
+	return r.client.SIsMember(ctx, key, userID).Result()
+}
+
+func (r *RoomRepositoryImpl) NextVersion(ctx context.Context, roomID string) (int64, error) {
+	key := fmt.Sprintf(versionPrefix, roomID)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *RoomRepositoryImpl) GetVersion(ctx context.Context, roomID string) (int64, error) {
+	key := fmt.Sprintf(versionPrefix, roomID)
+	version, err := r.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (r *RoomRepositoryImpl) IdleRooms(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	return r.client.ZRangeByScore(ctx, roomIndexKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(before.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+}
+
+func (r *RoomRepositoryImpl) TouchRoom(ctx context.Context, roomID string) error {
+	return touchRoom(ctx, r.client, roomID).Err()
+}
+
+func (r *RoomRepositoryImpl) ListHostRooms(ctx context.Context, hostID string, after *repository.RoomCursor, limit int) ([]*entity.Room, error) {
+	key := hostRoomsPrefix + hostID
+
+	// equal scores come in reverse order of their IDs, so the rooms after
+	// the cursor's are the ones with a lower score or a lower ID
+	max := "+inf"
+	if after != nil {
+		max = strconv.FormatInt(hostRoomScore(after.CreatedAt), 10)
+	}
+	isAfter := func(z redis.Z) bool {
+		if after == nil {
+			return true
+		}
+		return int64(z.Score) < hostRoomScore(after.CreatedAt) || z.Member.(string) < after.RoomID
+	}
+
+	rooms := make([]*entity.Room, 0, limit)
+	var expired []string
+	for offset := int64(0); len(rooms) < limit; {
+		entries, err := r.client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    max,
+			Offset: offset,
+			Count:  int64(limit),
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(len(entries))
+
+		var roomKeys, roomIDs []string
+		for _, entry := range entries {
+			if isAfter(entry) {
+				roomID := entry.Member.(string)
+				roomIDs = append(roomIDs, roomID)
+				roomKeys = append(roomKeys, roomPrefix+roomID)
+			}
+		}
+
+		if len(roomKeys) > 0 {
+			data, err := r.client.MGet(ctx, roomKeys...).Result()
+			if err != nil {
+				return nil, err
+			}
+			for i, raw := range data {
+				s, ok := raw.(string)
+				if !ok {
+					expired = append(expired, roomIDs[i])
+					continue
+				}
+				var room entity.Room
+				if err := json.Unmarshal([]byte(s), &room); err != nil {
+					continue
+				}
+				if len(rooms) < limit {
+					rooms = append(rooms, &room)
+				}
+			}
+		}
+
+		if len(entries) < limit {
+			break
+		}
+	}
+
+	if len(expired) > 0 {
+		pipe := r.client.Pipeline()
+		pipe.ZRem(ctx, key, stringsToArgs(expired)...)
+		pipe.SRem(ctx, activeRoomsKey, stringsToArgs(expired)...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return rooms, nil
+}
+
+func (r *RoomRepositoryImpl) ActiveRooms(ctx context.Context, roomIDs []string) ([]bool, error) {
+	if len(roomIDs) == 0 {
+		return nil, nil
+	}
+	return r.client.SMIsMember(ctx, activeRoomsKey, stringsToArgs(roomIDs)...).Result()
+}
+
+func stringsToArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
 }
 
 // ============= PARTICIPANT REPOSITORY =============
@@ -113,18 +826,142 @@ func NewParticipantRepository(client *redis.Client) *ParticipantRepositoryImpl {
 	return &ParticipantRepositoryImpl{client: client}
 }
 
+// joinRoomScript checks and writes a join in one step, so concurrent joins
+// can't push a room past its limit. An admission goes through the same
+// checks and only moves the lobby entry into the room.
+//
+// KEYS: room, bans, participants, lobby, room index, active rooms
+// ARGV: room ID, participant ID, user ID, participant, max, mode, now, host
+var joinRoomScript = redis.NewScript(`
+local room = redis.call("GET", KEYS[1])
+if not room then
+	return -1
+end
+if ARGV[6] == "admit" and redis.call("HEXISTS", KEYS[4], ARGV[2]) == 0 then
+	return -5
+end
+if redis.call("SISMEMBER", KEYS[2], ARGV[3]) == 1 then
+	if ARGV[6] == "admit" then
+		redis.call("HDEL", KEYS[4], ARGV[2])
+	end
+	return -2
+end
+if ARGV[8] == "0" and cjson.decode(room).locked == true then
+	local present = false
+	for _, p in ipairs(redis.call("HVALS", KEYS[3])) do
+		if cjson.decode(p).userId == ARGV[3] then
+			present = true
+			break
+		end
+	end
+	if not present then
+		return -4
+	end
+end
+if redis.call("HEXISTS", KEYS[3], ARGV[2]) == 0 and redis.call("HLEN", KEYS[3]) >= tonumber(ARGV[5]) then
+	return -3
+end
+if ARGV[6] == "wait" then
+	redis.call("HSET", KEYS[4], ARGV[2], ARGV[4])
+	redis.call("EXPIRE", KEYS[4], 86400)
+else
+	if ARGV[6] == "admit" then
+		redis.call("HDEL", KEYS[4], ARGV[2])
+	end
+	redis.call("HSET", KEYS[3], ARGV[2], ARGV[4])
+	redis.call("SADD", KEYS[6], ARGV[1])
+end
+redis.call("ZADD", KEYS[5], "XX", ARGV[7], ARGV[1])
+return 1
+`)
+
+func (r *ParticipantRepositoryImpl) JoinRoom(ctx context.Context, participant *entity.Participant, max int) error {
+	mode := "join"
+	if participant.Status == entity.ParticipantStatusWaiting {
+		mode = "wait"
+	}
+	return r.runJoin(ctx, participant, max, mode)
+}
+
+// AdmitParticipant moves a waiting participant into the room through the
+// same checks as a join.
+func (r *ParticipantRepositoryImpl) AdmitParticipant(ctx context.Context, participant *entity.Participant, max int) error {
+	return r.runJoin(ctx, participant, max, "admit")
+}
+
+func (r *ParticipantRepositoryImpl) runJoin(ctx context.Context, participant *entity.Participant, max int, mode string) error {
+	roomID := participant.RoomID
+	data, err := json.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	host := "0"
+	if participant.IsHost {
+		host = "1"
+	}
+
+	keys := []string{
+		roomPrefix + roomID,
+		fmt.Sprintf(banPrefix, roomID),
+		fmt.Sprintf(participantPrefix, roomID),
+		fmt.Sprintf(lobbyPrefix, roomID),
+		roomIndexKey,
+		activeRoomsKey,
+	}
+	result, err := joinRoomScript.Run(ctx, r.client, keys,
+		roomID, participant.ID, participant.UserID, string(data), max, mode, time.Now().Unix(), host).Int()
+	if err != nil {
+		return err
+	}
+
+	switch result {
+	case -1:
+		return repository.ErrRoomNotFound
+	case -2:
+		return repository.ErrUserBanned
+	case -3:
+		return repository.ErrRoomFull
+	case -4:
+		return repository.ErrRoomLocked
+	case -5:
+		return repository.ErrNotWaiting
+	}
+	return nil
+}
+
 func (r *ParticipantRepositoryImpl) AddParticipant(ctx context.Context, participant *entity.Participant) error {
 	key := fmt.Sprintf(participantPrefix, participant.RoomID)
 	data, err := json.Marshal(participant)
 	if err != nil {
 		return err
 	}
-	return r.client.HSet(ctx, key, participant.UserID, data).Err()
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, participant.ID, data)
+	pipe.SAdd(ctx, activeRoomsKey, participant.RoomID)
+	touchRoom(ctx, pipe, participant.RoomID)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
-func (r *ParticipantRepositoryImpl) RemoveParticipant(ctx context.Context, roomID, userId string) error {
-	key := fmt.Sprintf(participantPrefix, roomID)
-	return r.client.HDel(ctx, key, userId).Err()
+// removeParticipantScript takes the room off the active rooms along with
+// its last participant.
+//
+// KEYS: participants, active rooms, room index
+// ARGV: participant ID, room ID, now
+var removeParticipantScript = redis.NewScript(`
+redis.call("HDEL", KEYS[1], ARGV[1])
+if redis.call("HLEN", KEYS[1]) == 0 then
+	redis.call("SREM", KEYS[2], ARGV[2])
+end
+redis.call("ZADD", KEYS[3], "XX", ARGV[3], ARGV[2])
+return 1
+`)
+
+func (r *ParticipantRepositoryImpl) RemoveParticipant(ctx context.Context, roomID, participantID string) error {
+	keys := []string{fmt.Sprintf(participantPrefix, roomID), activeRoomsKey, roomIndexKey}
+	return removeParticipantScript.Run(ctx, r.client, keys, participantID, roomID, time.Now().Unix()).Err()
 }
 
 func (r *ParticipantRepositoryImpl) GetParticipants(ctx context.Context, roomID string) ([]*entity.Participant, error) {
@@ -145,12 +982,12 @@ func (r *ParticipantRepositoryImpl) GetParticipants(ctx context.Context, roomID
 	return participants, nil
 }
 
-func (r *ParticipantRepositoryImpl) GetParticipant(ctx context.Context, roomID, userId string) (*entity.Participant, error) {
+func (r *ParticipantRepositoryImpl) GetParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
 	key := fmt.Sprintf(participantPrefix, roomID)
-	data, err := r.client.HGet(ctx, key, userId).Bytes()
+	data, err := r.client.HGet(ctx, key, participantID).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("participant not found")
+			return nil, repository.ErrParticipantNotFound
 		}
 		return nil, err
 	}
@@ -162,8 +999,34 @@ func (r *ParticipantRepositoryImpl) GetParticipant(ctx context.Context, roomID,
 	return &participant, nil
 }
 
+// updateParticipantScript overwrites a participant only while they are
+// still in the room.
+//
+// KEYS: participants
+// ARGV: participant ID, participant
+var updateParticipantScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
 func (r *ParticipantRepositoryImpl) UpdateParticipant(ctx context.Context, participant *entity.Participant) error {
-	return r.AddParticipant(ctx, participant)
+	data, err := json.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{fmt.Sprintf(participantPrefix, participant.RoomID)}
+	updated, err := updateParticipantScript.Run(ctx, r.client, keys, participant.ID, data).Int()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return repository.ErrParticipantNotFound
+	}
+	return nil
 }
 
 func (r *ParticipantRepositoryImpl) GetParticipantCount(ctx context.Context, roomID string) (int, error) {
@@ -172,6 +1035,350 @@ func (r *ParticipantRepositoryImpl) GetParticipantCount(ctx context.Context, roo
 	return int(count), err
 }
 
+func (r *ParticipantRepositoryImpl) CountParticipants(ctx context.Context, roomIDs []string) ([]int, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(roomIDs))
+	for i, roomID := range roomIDs {
+		cmds[i] = pipe.HLen(ctx, fmt.Sprintf(participantPrefix, roomID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	counts := make([]int, len(roomIDs))
+	for i, cmd := range cmds {
+		counts[i] = int(cmd.Val())
+	}
+	return counts, nil
+}
+
+func (r *ParticipantRepositoryImpl) RemoveFromLobby(ctx context.Context, roomID, participantID string) (bool, error) {
+	key := fmt.Sprintf(lobbyPrefix, roomID)
+	removed, err := r.client.HDel(ctx, key, participantID).Result()
+	return removed > 0, err
+}
+
+func (r *ParticipantRepositoryImpl) GetLobby(ctx context.Context, roomID string) ([]*entity.Participant, error) {
+	key := fmt.Sprintf(lobbyPrefix, roomID)
+	data, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make([]*entity.Participant, 0, len(data))
+	for _, v := range data {
+		var p entity.Participant
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			continue
+		}
+		participants = append(participants, &p)
+	}
+	return participants, nil
+}
+
+func (r *ParticipantRepositoryImpl) GetLobbyParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
+	key := fmt.Sprintf(lobbyPrefix, roomID)
+	data, err := r.client.HGet(ctx, key, participantID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("participant not in lobby")
+		}
+		return nil, err
+	}
+
+	var participant entity.Participant
+	if err := json.Unmarshal(data, &participant); err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// ============= PRESENCE REPOSITORY =============
+
+// removePresenceScript deletes a presence field only if it still belongs to
+// the given connection, so a stale node can't remove a newer session.
+var removePresenceScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+var replacePresenceScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+	return 1
+end
+return 0
+`)
+
+type PresenceRepositoryImpl struct {
+	client *redis.Client
+}
+
+func NewPresenceRepository(client *redis.Client) *PresenceRepositoryImpl {
+	return &PresenceRepositoryImpl{client: client}
+}
+
+func (r *PresenceRepositoryImpl) SetPresence(ctx context.Context, roomID, participantID string, presence entity.Presence) error {
+	key := fmt.Sprintf(presencePrefix, roomID)
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, participantID, data)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *PresenceRepositoryImpl) RemovePresence(ctx context.Context, roomID, participantID string, presence entity.Presence) (bool, error) {
+	key := fmt.Sprintf(presencePrefix, roomID)
+	data, err := json.Marshal(presence)
+	if err != nil {
+		return false, err
+	}
+
+	removed, err := removePresenceScript.Run(ctx, r.client, []string{key}, participantID, string(data)).Int()
+	if err != nil {
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+func (r *PresenceRepositoryImpl) ReplacePresence(ctx context.Context, roomID, participantID string, current, next entity.Presence) (bool, error) {
+	key := fmt.Sprintf(presencePrefix, roomID)
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	nextData, err := json.Marshal(next)
+	if err != nil {
+		return false, err
+	}
+
+	replaced, err := replacePresenceScript.Run(ctx, r.client, []string{key}, participantID, string(currentData), string(nextData)).Int()
+	if err != nil {
+		return false, err
+	}
+	return replaced > 0, nil
+}
+
+func (r *PresenceRepositoryImpl) GetPresence(ctx context.Context, roomID string) (map[string]entity.Presence, error) {
+	key := fmt.Sprintf(presencePrefix, roomID)
+	data, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	presence := make(map[string]entity.Presence, len(data))
+	for participantID, v := range data {
+		var p entity.Presence
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			continue
+		}
+		presence[participantID] = p
+	}
+	return presence, nil
+}
+
+func (r *PresenceRepositoryImpl) NodeHeartbeat(ctx context.Context, nodeID string, ttl time.Duration) error {
+	key := nodePrefix + nodeID
+	return r.client.Set(ctx, key, time.Now().Unix(), ttl).Err()
+}
+
+func (r *PresenceRepositoryImpl) RemoveNode(ctx context.Context, nodeID string) error {
+	key := nodePrefix + nodeID
+	return r.client.Del(ctx, key).Err()
+}
+
+func (r *PresenceRepositoryImpl) IsNodeAlive(ctx context.Context, nodeID string) (bool, error) {
+	key := nodePrefix + nodeID
+	count, err := r.client.Exists(ctx, key).Result()
+	return count > 0, err
+}
+
+// ============= LOCK REPOSITORY =============
+
+type LockRepositoryImpl struct {
+	client *redis.Client
+}
+
+func NewLockRepository(client *redis.Client) *LockRepositoryImpl {
+	return &LockRepositoryImpl{client: client}
+}
+
+func (r *LockRepositoryImpl) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	key := lockPrefix + name
+	return r.client.SetNX(ctx, key, owner, ttl).Result()
+}
+
+func (r *LockRepositoryImpl) ReleaseLock(ctx context.Context, name, owner string) error {
+	key := lockPrefix + name
+	return deleteIfEqualScript.Run(ctx, r.client, []string{key}, owner).Err()
+}
+
+// ============= TEMPLATE REPOSITORY =============
+
+type TemplateRepositoryImpl struct {
+	client *redis.Client
+}
+
+func NewTemplateRepository(client *redis.Client) *TemplateRepositoryImpl {
+	return &TemplateRepositoryImpl{client: client}
+}
+
+func (r *TemplateRepositoryImpl) SaveTemplate(ctx context.Context, template *entity.RoomTemplate) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, templatesPrefix+template.OwnerID, template.ID, data).Err()
+}
+
+func (r *TemplateRepositoryImpl) GetTemplate(ctx context.Context, ownerID, templateID string) (*entity.RoomTemplate, error) {
+	data, err := r.client.HGet(ctx, templatesPrefix+ownerID, templateID).Bytes()
+	if err == redis.Nil {
+		return nil, repository.ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var template entity.RoomTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *TemplateRepositoryImpl) ListTemplates(ctx context.Context, ownerID string) ([]*entity.RoomTemplate, error) {
+	data, err := r.client.HGetAll(ctx, templatesPrefix+ownerID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*entity.RoomTemplate, 0, len(data))
+	for _, raw := range data {
+		var template entity.RoomTemplate
+		if err := json.Unmarshal([]byte(raw), &template); err != nil {
+			continue
+		}
+		templates = append(templates, &template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].CreatedAt.Before(templates[j].CreatedAt)
+	})
+	return templates, nil
+}
+
+func (r *TemplateRepositoryImpl) DeleteTemplate(ctx context.Context, ownerID, templateID string) error {
+	removed, err := r.client.HDel(ctx, templatesPrefix+ownerID, templateID).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return repository.ErrTemplateNotFound
+	}
+	return nil
+}
+
+func (r *TemplateRepositoryImpl) GetRoomDefaults(ctx context.Context, ownerID string) (*entity.RoomDefaults, error) {
+	data, err := r.client.Get(ctx, roomDefaultPrefix+ownerID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults entity.RoomDefaults
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, err
+	}
+	return &defaults, nil
+}
+
+func (r *TemplateRepositoryImpl) SetRoomDefaults(ctx context.Context, ownerID string, defaults *entity.RoomDefaults) error {
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, roomDefaultPrefix+ownerID, data, 0).Err()
+}
+
+// ============= SESSION REPOSITORY =============
+
+type SessionRepositoryImpl struct {
+	client *redis.Client
+}
+
+func NewSessionRepository(client *redis.Client) *SessionRepositoryImpl {
+	return &SessionRepositoryImpl{client: client}
+}
+
+func (r *SessionRepositoryImpl) CreateSession(ctx context.Context, session *entity.Session) error {
+	key := sessionPrefix + session.Token
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, key, data, 24*time.Hour).Err()
+}
+
+func (r *SessionRepositoryImpl) GetSession(ctx context.Context, token string) (*entity.Session, error) {
+	key := sessionPrefix + token
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, err
+	}
+
+	var session entity.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepositoryImpl) DeleteSession(ctx context.Context, token string) error {
+	return r.client.Del(ctx, sessionPrefix+token, fmt.Sprintf(sessionBufPrefix, token)).Err()
+}
+
+func (r *SessionRepositoryImpl) BufferMessage(ctx context.Context, token string, message []byte, limit int, ttl time.Duration) error {
+	key := fmt.Sprintf(sessionBufPrefix, token)
+
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, message)
+	pipe.LTrim(ctx, key, -int64(limit), -1)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *SessionRepositoryImpl) DrainBuffer(ctx context.Context, token string) ([][]byte, error) {
+	key := fmt.Sprintf(sessionBufPrefix, token)
+
+	pipe := r.client.TxPipeline()
+	lrange := pipe.LRange(ctx, key, 0, -1)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	data := lrange.Val()
+	messages := make([][]byte, 0, len(data))
+	for _, v := range data {
+		messages = append(messages, []byte(v))
+	}
+	return messages, nil
+}
+
 // ============= CHAT REPOSITORY =============
 
 type ChatRepositoryImpl struct {
@@ -188,7 +1395,12 @@ func (r *ChatRepositoryImpl) SaveMessage(ctx context.Context, message *entity.Ch
 	if err != nil {
 		return err
 	}
-	return r.client.RPush(ctx, key, data).Err()
+
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	touchRoom(ctx, pipe, message.RoomID)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 func (r *ChatRepositoryImpl) GetMessages(ctx context.Context, roomID string, limit int) ([]*entity.ChatMessage, error) {
@@ -224,13 +1436,23 @@ func NewRecordingRepository(client *redis.Client) *RecordingRepositoryImpl {
 	return &RecordingRepositoryImpl{client: client}
 }
 
+const recordingTTL = 7 * 24 * time.Hour
+
 func (r *RecordingRepositoryImpl) Create(ctx context.Context, recording *entity.Recording) error {
 	key := recordingPrefix + recording.ID
 	data, err := json.Marshal(recording)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, key, data, 7*24*time.Hour).Err()
+
+	// the room's index expires with its newest recording
+	indexKey := roomRecordingsPrefix + recording.RoomID
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, key, data, recordingTTL)
+	pipe.SAdd(ctx, indexKey, recording.ID)
+	pipe.Expire(ctx, indexKey, recordingTTL)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 func (r *RecordingRepositoryImpl) Get(ctx context.Context, recordingID string) (*entity.Recording, error) {
@@ -260,14 +1482,70 @@ func (r *RecordingRepositoryImpl) Update(ctx context.Context, recording *entity.
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, key, data, ttl).Err()
+
+	indexKey := roomRecordingsPrefix + recording.RoomID
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, key, data, ttl)
+	pipe.SAdd(ctx, indexKey, recording.ID)
+	pipe.ExpireNX(ctx, indexKey, recordingTTL)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
+// GetByRoomID returns the room's recordings, oldest first. Recordings that
+// expired are dropped from the room's index on the way.
 func (r *RecordingRepositoryImpl) GetByRoomID(ctx context.Context, roomID string) ([]*entity.Recording, error) {
-	pattern := recordingPrefix + "*"
+	indexKey := roomRecordingsPrefix + roomID
+	ids, err := r.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = recordingPrefix + id
+	}
+	data, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
 	var recordings []*entity.Recording
+	var expired []string
+	for i, raw := range data {
+		s, ok := raw.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		var rec entity.Recording
+		if err := json.Unmarshal([]byte(s), &rec); err != nil {
+			continue
+		}
+		recordings = append(recordings, &rec)
+	}
+
+	if len(expired) > 0 {
+		if err := r.client.SRem(ctx, indexKey, stringsToArgs(expired)...).Err(); err != nil {
+			return nil, err
+		}
+	}
 
-	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartTime.Before(recordings[j].StartTime)
+	})
+	return recordings, nil
+}
+
+// RebuildRoomIndex indexes recordings stored before rooms had an index of
+// their recordings, and returns how many it found. It scans the keyspace,
+// so it is meant to run once, see cmd/migrate-indexes.
+func (r *RecordingRepositoryImpl) RebuildRoomIndex(ctx context.Context) (int, error) {
+	indexed := 0
+	iter := r.client.Scan(ctx, 0, recordingPrefix+"*", 0).Iterator()
 	for iter.Next(ctx) {
 		data, err := r.client.Get(ctx, iter.Val()).Bytes()
 		if err != nil {
@@ -275,15 +1553,20 @@ func (r *RecordingRepositoryImpl) GetByRoomID(ctx context.Context, roomID string
 		}
 
 		var rec entity.Recording
-		if err := json.Unmarshal(data, &rec); err != nil {
+		if err := json.Unmarshal(data, &rec); err != nil || rec.RoomID == "" {
 			continue
 		}
 
-		if rec.RoomID == roomID {
-			recordings = append(recordings, &rec)
+		indexKey := roomRecordingsPrefix + rec.RoomID
+		pipe := r.client.TxPipeline()
+		pipe.SAdd(ctx, indexKey, rec.ID)
+		pipe.ExpireNX(ctx, indexKey, recordingTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return indexed, err
 		}
+		indexed++
 	}
-	return recordings, iter.Err()
+	return indexed, iter.Err()
 }
 
 func (r *RecordingRepositoryImpl) AddChunk(ctx context.Context, recordingID, chunkURL string) error {
@@ -305,13 +1588,58 @@ func NewUserRepository(client *redis.Client) *UserRepositoryImpl {
 	return &UserRepositoryImpl{client: client}
 }
 
+// emailKey is how users are indexed by email, which is case-insensitive.
+func emailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// maxUserSaveAttempts bounds the retries of a user save that raced another
+// save of the same user.
+const maxUserSaveAttempts = 3
+
+// Create stores the user and indexes it by email in one transaction. A
+// changed email gives up the old one's index entry.
 func (r *UserRepositoryImpl) Create(ctx context.Context, user *entity.User) error {
 	key := userPrefix + user.ID
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, key, data, 0).Err()
+
+	save := func(tx *redis.Tx) error {
+		var previous string
+		if stored, err := tx.Get(ctx, key).Bytes(); err == nil {
+			var current entity.User
+			if err := json.Unmarshal(stored, &current); err == nil {
+				previous = emailKey(current.Email)
+			}
+		} else if err != redis.Nil {
+			return err
+		}
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			if previous != "" && previous != emailKey(user.Email) {
+				hdelIfEqualScript.Eval(ctx, pipe, []string{usersByEmailKey}, previous, user.ID)
+			}
+			if user.Email != "" {
+				pipe.HSet(ctx, usersByEmailKey, emailKey(user.Email), user.ID)
+			}
+			return nil
+		})
+		return err
+	}
+
+	// the other save changed the user, so the old email is read again
+	for attempt := 1; ; attempt++ {
+		err = r.client.Watch(ctx, save, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+		if attempt == maxUserSaveAttempts {
+			return fmt.Errorf("user %s changed while saving it: %w", user.ID, err)
+		}
+	}
 }
 
 func (r *UserRepositoryImpl) Get(ctx context.Context, userID string) (*entity.User, error) {
@@ -332,9 +1660,35 @@ func (r *UserRepositoryImpl) Get(ctx context.Context, userID string) (*entity.Us
 }
 
 func (r *UserRepositoryImpl) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	pattern := userPrefix + "*"
+	userID, err := r.client.HGet(ctx, usersByEmailKey, emailKey(email)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// an entry left behind by a user who changed their email
+	if emailKey(user.Email) != emailKey(email) {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
 
-	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+func (r *UserRepositoryImpl) Update(ctx context.Context, user *entity.User) error {
+	return r.Create(ctx, user)
+}
+
+// RebuildEmailIndex indexes users stored before users were indexed by
+// email, and returns how many it found. It scans the keyspace, so it is
+// meant to run once, see cmd/migrate-indexes.
+func (r *UserRepositoryImpl) RebuildEmailIndex(ctx context.Context) (int, error) {
+	indexed := 0
+	iter := r.client.Scan(ctx, 0, userPrefix+"*", 0).Iterator()
 	for iter.Next(ctx) {
 		data, err := r.client.Get(ctx, iter.Val()).Bytes()
 		if err != nil {
@@ -342,17 +1696,15 @@ func (r *UserRepositoryImpl) GetByEmail(ctx context.Context, email string) (*ent
 		}
 
 		var user entity.User
-		if err := json.Unmarshal(data, &user); err != nil {
+		if err := json.Unmarshal(data, &user); err != nil || user.ID == "" || user.Email == "" {
 			continue
 		}
 
-		if user.Email == email {
-			return &user, nil
+		// users who signed in since the index exists are already in it
+		if err := r.client.HSetNX(ctx, usersByEmailKey, emailKey(user.Email), user.ID).Err(); err != nil {
+			return indexed, err
 		}
+		indexed++
 	}
-	return nil, fmt.Errorf("user not found")
-}
-
-func (r *UserRepositoryImpl) Update(ctx context.Context, user *entity.User) error {
-	return r.Create(ctx, user)
+	return indexed, iter.Err()
 }
@@ -3,7 +3,11 @@ package main
 import (
 	"bincang-visual/internal/config"
 	"bincang-visual/internal/delivery/http"
+	"bincang-visual/internal/delivery/websocket/protocol"
 	"bincang-visual/internal/domain/usecase"
+	"bincang-visual/internal/infrastructure/metrics"
+	"bincang-visual/internal/infrastructure/pubsub"
+	"bincang-visual/internal/jobs"
 	"bincang-visual/internal/middleware"
 	"context"
 	"fmt"
@@ -67,6 +71,10 @@ func main() {
 	chatRepo := redisRepo.NewChatRepository(redisClient)
 	recordingRepo := redisRepo.NewRecordingRepository(redisClient)
 	userRepo := redisRepo.NewUserRepository(redisClient)
+	presenceRepo := redisRepo.NewPresenceRepository(redisClient)
+	sessionRepo := redisRepo.NewSessionRepository(redisClient)
+	lockRepo := redisRepo.NewLockRepository(redisClient)
+	templateRepo := redisRepo.NewTemplateRepository(redisClient)
 	calendarRepository := calendarRepo.NewGoogleCalendarRepository(googleOAuthConfig)
 
 	roomUseCase := usecase.NewRoomUseCase(
@@ -76,21 +84,31 @@ func main() {
 		recordingRepo,
 		*cfg,
 	)
+	templateUseCase := usecase.NewTemplateUseCase(templateRepo)
 
-	signalingHub := wsHandler.NewSignalingHub(roomUseCase)
+	broker, err := pubsub.NewRedisBroker(ctx, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to start pub/sub broker: %v", err)
+	}
+
+	signalingHub := wsHandler.NewSignalingHub(roomUseCase, presenceRepo, sessionRepo, broker, cfg.Signaling)
+	roomUseCase.SetRoomEvents(signalingHub)
 	go signalingHub.Run()
-	log.Println("Signaling hub started")
-
-	app := fiber.New(fiber.Config{
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-		BodyLimit:    50 * 1024 * 1024, // 50MB for recordings
-		ServerHeader: "Bincang-Visual",
-		AppName:      "Bincang Visual v1.0.0",
-	})
+	log.Printf("Signaling hub started (node %s)", cfg.Signaling.NodeID)
+
+	cleanupJob := jobs.NewRoomCleanupJob(
+		usecase.NewCleanupUseCase(roomUseCase, presenceRepo),
+		lockRepo,
+		cfg.Signaling.NodeID,
+		time.Duration(cfg.Rooms.CleanupInterval)*time.Second,
+		time.Duration(cfg.Rooms.IdleTimeout)*time.Second,
+	)
+	cleanupJob.Start()
+
+	app := fiber.New(http.AppConfig(cfg.Server))
 
 	app.Use(recover.New())
+	app.Use(middleware.ClientIPMiddleware(cfg.Server.ProxyHeader, cfg.Server.TrustedProxies))
 	app.Use(logger.New(logger.Config{
 		Format:     "[${time}] ${status} - ${method} ${path} (${latency})\n",
 		TimeFormat: "2006-01-02 15:04:05",
@@ -100,13 +118,13 @@ func main() {
 	if cfg.IsProduction() {
 		app.Use(cors.New(cors.Config{
 			AllowOrigins:     "https://bincang-visual.cloud",
-			AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+			AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
 			AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
 			AllowCredentials: true,
 		}))
 	}
 
-	roomHandler := http.NewRoomHandler(roomUseCase, cfg.Server.BaseURL)
+	roomHandler := http.NewRoomHandler(roomUseCase, templateUseCase, cfg.Server.BaseURL)
 	authHandler := http.NewAuthHandler(
 		userRepo,
 		googleOAuthConfig,
@@ -114,7 +132,8 @@ func main() {
 		cfg.JWT.Expiration,
 		redisClient,
 	)
-	calendarHandler := http.NewCalendarHandler(calendarRepository, roomUseCase)
+	calendarHandler := http.NewCalendarHandler(calendarRepository, roomUseCase, templateUseCase)
+	templateHandler := http.NewTemplateHandler(templateUseCase)
 	analyticsHandler := http.NewAnalyticsHandler(roomUseCase)
 	uploadHandler := http.NewUploadHandler(cfg.Storage.LocalPath)
 
@@ -129,9 +148,10 @@ func main() {
 	auth.Post("/google/signin", authHandler.GoogleTokenSignIn)
 
 	// ice servers (public or with optional auth)
-	api.Post("/rooms", roomHandler.CreateRoom)
+	optionalAuth := middleware.OptionalJWTMiddleware(cfg.JWT.Secret)
+	api.Post("/rooms", optionalAuth, roomHandler.CreateRoom)
 	api.Get("/rooms/:roomId", roomHandler.GetRoom)
-	api.Get("/rooms/:roomId/validate", roomHandler.ValidateRoom)
+	api.Get("/rooms/:roomId/validate", optionalAuth, roomHandler.ValidateRoom)
 	api.Get("/ice-servers", roomHandler.GetICEServers)
 
 	// protected routes (require JWT)
@@ -140,11 +160,28 @@ func main() {
 	// auth - authenticated
 	protected.Post("/auth/refresh", authHandler.RefreshToken)
 	protected.Get("/auth/me", authHandler.GetCurrentUser)
+	protected.Post("/auth/ws-ticket", authHandler.IssueWebSocketTicket)
 
 	// room management
+	protected.Get("/rooms", roomHandler.ListRooms)
 	protected.Get("/rooms/:roomId/participants", roomHandler.GetParticipants)
 	protected.Get("/rooms/:roomId/chat", roomHandler.GetChatHistory)
+	protected.Patch("/rooms/:roomId", roomHandler.UpdateRoom)
+	protected.Post("/rooms/:roomId/extend", roomHandler.ExtendRoom)
+	protected.Post("/rooms/:roomId/lock", roomHandler.LockRoom)
+	protected.Post("/rooms/:roomId/invites", roomHandler.CreateInvite)
+	protected.Get("/rooms/:roomId/invites", roomHandler.ListInvites)
+	protected.Delete("/rooms/:roomId/invites/:inviteId", roomHandler.RevokeInvite)
 	protected.Delete("/rooms/:roomId", roomHandler.DeleteRoom)
+	protected.Get("/me/room", roomHandler.GetPersonalRoom)
+	protected.Put("/me/room/code", roomHandler.SetPersonalRoomCode)
+
+	// room templates
+	protected.Get("/templates", templateHandler.ListTemplates)
+	protected.Post("/templates", templateHandler.CreateTemplate)
+	protected.Delete("/templates/:templateId", templateHandler.DeleteTemplate)
+	protected.Get("/me/room-defaults", templateHandler.GetRoomDefaults)
+	protected.Put("/me/room-defaults", templateHandler.SetRoomDefaults)
 
 	// recording
 	protected.Post("/recordings/start", roomHandler.StartRecording)
@@ -172,27 +209,57 @@ func main() {
 			return c.Next()
 		}
 		return fiber.ErrUpgradeRequired
-	})
+	}, middleware.WebSocketAuthMiddleware(cfg.JWT.Secret, redisClient))
 	app.Get("/ws/room/:roomId", websocket.New(func(c *websocket.Conn) {
-		// get user info from query params or headers
-		// use validate JWT token when auth has been implemented
+		// identity comes from the JWT/ticket or guest cookie checked by WebSocketAuthMiddleware
 		roomID := c.Params("roomId")
-		userID := c.Query("userId")
-		displayName := c.Query("displayName")
-
-		if userID == "" {
-			userID = "anon-" + uuid.New().String()
-		}
-		if displayName == "" {
-			displayName = "Guest"
+		userID, _ := c.Locals("userID").(string)
+		displayName, _ := c.Locals("displayName").(string)
+		email, _ := c.Locals("email").(string)
+		isGuest, _ := c.Locals("isGuest").(bool)
+
+		if isGuest {
+			displayName = c.Query("displayName")
+			if displayName == "" {
+				displayName = "Guest"
+			}
 		}
 		clientID := uuid.New().String()
 
 		log.Printf("WebSocket connection: roomID=%s, userID=%s, clientID=%s", roomID, userID, clientID)
 
-		signalingHub.HandleWebSocket(c, roomID, userID, clientID, displayName)
+		signalingHub.HandleWebSocket(c, wsHandler.ConnectParams{
+			RoomID:      roomID,
+			UserID:      userID,
+			Email:       email,
+			ClientID:    clientID,
+			DisplayName: displayName,
+			IsGuest:     isGuest,
+			Device:      c.Query("device"),
+			ResumeToken: c.Query("resume"),
+			Passcode:    c.Query("passcode"),
+			InviteToken: c.Query("invite"),
+			ClientIP:    c.IP(),
+			Protocol:    c.Query("v"),
+		})
+	}, websocket.Config{
+		// the encoding subprotocols win over the auth one when a client offers both;
+		// browsers accept the handshake as long as one offered entry is echoed
+		Subprotocols: []string{protocol.SubprotocolMsgpack, protocol.SubprotocolJSON, middleware.WebSocketAuthProtocol},
 	}))
 
+	// prometheus scrape endpoint, on its own address so it isn't public
+	var metricsApp *fiber.App
+	if cfg.Server.MetricsAddr != "" {
+		metricsApp = fiber.New(fiber.Config{DisableStartupMessage: true})
+		metricsApp.Get("/metrics", metrics.MetricsHandler())
+		go func() {
+			if err := metricsApp.Listen(cfg.Server.MetricsAddr); err != nil {
+				log.Printf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	// setup graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -201,6 +268,7 @@ func main() {
 		<-quit
 		log.Println("Shutting down server...")
 
+		cleanupJob.Stop()
 		signalingHub.Shutdown()
 
 		if err := redisClient.Close(); err != nil {
@@ -210,6 +278,11 @@ func main() {
 		if err := app.Shutdown(); err != nil {
 			log.Printf("Error shutting down server: %v", err)
 		}
+		if metricsApp != nil {
+			if err := metricsApp.Shutdown(); err != nil {
+				log.Printf("Error shutting down metrics server: %v", err)
+			}
+		}
 
 		log.Println("Server stopped")
 		os.Exit(0)
@@ -0,0 +1,160 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPresenceRepository struct {
+	mock.Mock
+}
+
+func (m *MockPresenceRepository) SetPresence(ctx context.Context, roomID, participantID string, presence entity.Presence) error {
+	args := m.Called(ctx, roomID, participantID, presence)
+	return args.Error(0)
+}
+
+func (m *MockPresenceRepository) RemovePresence(ctx context.Context, roomID, participantID string, presence entity.Presence) (bool, error) {
+	args := m.Called(ctx, roomID, participantID, presence)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPresenceRepository) ReplacePresence(ctx context.Context, roomID, participantID string, current, next entity.Presence) (bool, error) {
+	args := m.Called(ctx, roomID, participantID, current, next)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPresenceRepository) GetPresence(ctx context.Context, roomID string) (map[string]entity.Presence, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]entity.Presence), args.Error(1)
+}
+
+func (m *MockPresenceRepository) NodeHeartbeat(ctx context.Context, nodeID string, ttl time.Duration) error {
+	args := m.Called(ctx, nodeID, ttl)
+	return args.Error(0)
+}
+
+func (m *MockPresenceRepository) RemoveNode(ctx context.Context, nodeID string) error {
+	args := m.Called(ctx, nodeID)
+	return args.Error(0)
+}
+
+func (m *MockPresenceRepository) IsNodeAlive(ctx context.Context, nodeID string) (bool, error) {
+	args := m.Called(ctx, nodeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestCleanupIdleRooms(t *testing.T) {
+	newCleanup := func(cfg config.Config) (*usecase.CleanupUseCase, *MockRoomRepository, *MockParticipantRepository, *MockPresenceRepository, *recordedEvents) {
+		mockRoomRepo := new(MockRoomRepository)
+		mockParticipantRepo := new(MockParticipantRepository)
+		mockPresenceRepo := new(MockPresenceRepository)
+
+		rooms := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, new(MockChatRepository), new(MockRecordingRepository), cfg)
+		events := &recordedEvents{}
+		rooms.SetRoomEvents(events)
+
+		return usecase.NewCleanupUseCase(rooms, mockPresenceRepo), mockRoomRepo, mockParticipantRepo, mockPresenceRepo, events
+	}
+
+	t.Run("reaps the keys of an expired room", func(t *testing.T) {
+		cleanup, mockRoomRepo, _, _, events := newCleanup(config.Config{})
+
+		mockRoomRepo.On("IdleRooms", mock.Anything, mock.Anything, 100).Return([]string{"room123"}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(nil, repository.ErrRoomNotFound).Once()
+		mockRoomRepo.On("Delete", mock.Anything, "room123").Return(nil).Once()
+
+		report, err := cleanup.CleanupIdleRooms(context.Background(), 10*time.Minute, 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Orphaned)
+		assert.Equal(t, usecase.MeetingEndedExpired, events.ended["room123"])
+		mockRoomRepo.AssertExpectations(t)
+	})
+
+	t.Run("removes participants of a dead node and closes the empty room", func(t *testing.T) {
+		cleanup, mockRoomRepo, mockParticipantRepo, mockPresenceRepo, events := newCleanup(config.Config{})
+
+		room := &entity.Room{ID: "room123", HostID: "host123", CreatedAt: time.Now().Add(-time.Hour)}
+		crashed := entity.Presence{NodeID: "node-dead", ClientID: "client-1"}
+
+		mockRoomRepo.On("IdleRooms", mock.Anything, mock.Anything, 100).Return([]string{"room123"}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return([]*entity.Participant{
+			{ID: "conn-1", RoomID: "room123"},
+			{ID: "conn-2", RoomID: "room123"},
+		}, nil).Once()
+		mockPresenceRepo.On("GetPresence", mock.Anything, "room123").Return(map[string]entity.Presence{"conn-1": crashed}, nil).Once()
+		mockPresenceRepo.On("IsNodeAlive", mock.Anything, "node-dead").Return(false, nil).Once()
+		mockPresenceRepo.On("RemovePresence", mock.Anything, "room123", "conn-1", crashed).Return(true, nil).Once()
+		for _, id := range []string{"conn-1", "conn-2"} {
+			mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room123", id).Return(nil).Once()
+			mockRoomRepo.On("RemovePresenter", mock.Anything, "room123", id).Return(false, nil).Once()
+			mockRoomRepo.On("RemoveShareRequest", mock.Anything, "room123", id).Return(false, nil).Once()
+		}
+		mockRoomRepo.On("Delete", mock.Anything, "room123").Return(nil).Once()
+
+		report, err := cleanup.CleanupIdleRooms(context.Background(), 10*time.Minute, 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.StaleParticipants)
+		assert.Equal(t, 1, report.Closed)
+		assert.Equal(t, usecase.MeetingEndedEmpty, events.ended["room123"])
+		mockRoomRepo.AssertExpectations(t)
+		mockParticipantRepo.AssertExpectations(t)
+		mockPresenceRepo.AssertExpectations(t)
+	})
+
+	t.Run("keeps a room with live participants", func(t *testing.T) {
+		cleanup, mockRoomRepo, mockParticipantRepo, mockPresenceRepo, events := newCleanup(config.Config{})
+
+		room := &entity.Room{ID: "room123", HostID: "host123", CreatedAt: time.Now().Add(-time.Hour)}
+
+		mockRoomRepo.On("IdleRooms", mock.Anything, mock.Anything, 100).Return([]string{"room123"}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return([]*entity.Participant{
+			{ID: "conn-1", RoomID: "room123"},
+		}, nil).Once()
+		mockPresenceRepo.On("GetPresence", mock.Anything, "room123").Return(map[string]entity.Presence{
+			"conn-1": {NodeID: "node-1", ClientID: "client-1"},
+		}, nil).Once()
+		mockPresenceRepo.On("IsNodeAlive", mock.Anything, "node-1").Return(true, nil).Once()
+		mockRoomRepo.On("TouchRoom", mock.Anything, "room123").Return(nil).Once()
+
+		report, err := cleanup.CleanupIdleRooms(context.Background(), 10*time.Minute, 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usecase.CleanupReport{Checked: 1}, report)
+		assert.Empty(t, events.ended)
+		mockRoomRepo.AssertNotCalled(t, "Delete", mock.Anything, "room123")
+		mockParticipantRepo.AssertNotCalled(t, "RemoveParticipant", mock.Anything, "room123", "conn-1")
+	})
+
+	t.Run("leaves a room nobody joined yet", func(t *testing.T) {
+		cleanup, mockRoomRepo, mockParticipantRepo, _, _ := newCleanup(config.Config{})
+
+		room := &entity.Room{ID: "room123", HostID: "host123", CreatedAt: time.Now().Add(-time.Hour)}
+
+		mockRoomRepo.On("IdleRooms", mock.Anything, mock.Anything, 100).Return([]string{"room123"}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return([]*entity.Participant{}, nil).Once()
+		mockRoomRepo.On("TouchRoom", mock.Anything, "room123").Return(nil).Once()
+
+		_, err := cleanup.CleanupIdleRooms(context.Background(), 10*time.Minute, 100)
+
+		assert.NoError(t, err)
+		mockRoomRepo.AssertNotCalled(t, "Delete", mock.Anything, "room123")
+	})
+}
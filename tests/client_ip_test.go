@@ -0,0 +1,89 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"bincang-visual/internal/config"
+	httpDelivery "bincang-visual/internal/delivery/http"
+	"bincang-visual/internal/domain/usecase"
+	"bincang-visual/internal/middleware"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasscodeThrottleByClientIP(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+	handler := httpDelivery.NewRoomHandler(uc, nil, "http://localhost:8080")
+
+	newApp := func(trusted ...string) *fiber.App {
+		app := fiber.New(httpDelivery.AppConfig(config.ServerConfig{
+			ProxyHeader:    "X-Forwarded-For",
+			TrustedProxies: trusted,
+		}))
+		app.Use(middleware.ClientIPMiddleware("X-Forwarded-For", trusted))
+		app.Get("/api/rooms/:roomId/validate", handler.ValidateRoom)
+		return app
+	}
+	// requests made by app.Test come from 0.0.0.0; 10.0.0.0/8 is the balancer
+	app := newApp("0.0.0.0", "10.0.0.0/8")
+
+	validate := func(app *fiber.App, roomID, forwardedFor string) int {
+		req := httptest.NewRequest("GET", "/api/rooms/"+roomID+"/validate?passcode=0000", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	newRoom := func() string {
+		room, err := uc.CreateRoom(context.Background(), usecase.CreateRoomInput{Name: "Board", HostID: "host123", Passcode: "4321"})
+		require.NoError(t, err)
+		return room.ID
+	}
+
+	t.Run("clients behind the proxy are throttled separately", func(t *testing.T) {
+		roomID := newRoom()
+		for i := 0; i < 10; i++ {
+			require.Equal(t, fiber.StatusForbidden, validate(app, roomID, "203.0.113.7, 10.0.0.2"))
+		}
+
+		assert.Equal(t, fiber.StatusTooManyRequests, validate(app, roomID, "203.0.113.7, 10.0.0.2"))
+		assert.Equal(t, fiber.StatusForbidden, validate(app, roomID, "198.51.100.9, 10.0.0.2"))
+	})
+
+	t.Run("addresses the client prepended are ignored", func(t *testing.T) {
+		roomID := newRoom()
+		for i := 0; i < 10; i++ {
+			require.Equal(t, fiber.StatusForbidden, validate(app, roomID, "192.0.2.1, 10.0.0.2"))
+		}
+
+		assert.Equal(t, fiber.StatusTooManyRequests, validate(app, roomID, "198.51.100.77, 192.0.2.1, 10.0.0.2"))
+		assert.Equal(t, fiber.StatusTooManyRequests, validate(app, roomID, "bogus, 192.0.2.1"))
+	})
+
+	t.Run("the header is ignored from untrusted peers", func(t *testing.T) {
+		direct := newApp()
+		roomID := newRoom()
+		for i := 0; i < 10; i++ {
+			require.Equal(t, fiber.StatusForbidden, validate(direct, roomID, "203.0.113.7"))
+		}
+
+		assert.Equal(t, fiber.StatusTooManyRequests, validate(direct, roomID, "198.51.100.9"))
+	})
+}
@@ -0,0 +1,94 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMultiDevice(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	laptop := &entity.Participant{ID: "conn-laptop", UserID: "user456", RoomID: "room123", Device: "Laptop"}
+	phone := &entity.Participant{ID: "conn-phone", UserID: "user456", RoomID: "room123", Device: "Phone"}
+	other := &entity.Participant{ID: "conn-other", UserID: "user789", RoomID: "room123"}
+	everyone := []*entity.Participant{laptop, phone, other}
+
+	t.Run("second device joins as its own participant", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", HostID: "host123", MaxParticipants: 100}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.MatchedBy(func(p *entity.Participant) bool {
+			return p.ID == "conn-phone" && p.UserID == "user456"
+		}), 100).Return(nil).Once()
+
+		participant, err := uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID:        "room123",
+			ParticipantID: "conn-phone",
+			UserID:        "user456",
+			DisplayName:   "Test User",
+			Device:        "Phone",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "conn-phone", participant.ID)
+		assert.Equal(t, "Phone", participant.Device)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("devices of the same user are listed", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-laptop").Return(laptop, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return(everyone, nil).Once()
+
+		devices, err := uc.GetDevices(context.Background(), "room123", "conn-laptop")
+
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []*entity.Participant{laptop, phone}, devices)
+	})
+
+	t.Run("cannot disconnect someone else", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-laptop").Return(laptop, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return(everyone, nil).Once()
+
+		_, err := uc.DisconnectDevice(context.Background(), "room123", "conn-laptop", "conn-other")
+
+		assert.True(t, errors.Is(err, usecase.ErrNotOwnDevice))
+		mockParticipantRepo.AssertNotCalled(t, "RemoveParticipant", mock.Anything, "room123", "conn-other")
+	})
+
+	t.Run("disconnects own device", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-laptop").Return(laptop, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return(everyone, nil).Once()
+		mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room123", "conn-phone").Return(nil).Once()
+
+		device, err := uc.DisconnectDevice(context.Background(), "room123", "conn-laptop", "conn-phone")
+
+		assert.NoError(t, err)
+		assert.Equal(t, phone, device)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("transfer keeps only this device", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-phone").Return(phone, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return(everyone, nil).Once()
+		mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room123", "conn-laptop").Return(nil).Once()
+
+		moved, err := uc.TransferToDevice(context.Background(), "room123", "conn-phone")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*entity.Participant{laptop}, moved)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+}
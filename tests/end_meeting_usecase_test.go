@@ -0,0 +1,71 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEndMeeting(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+	events := &recordedEvents{}
+	uc.SetRoomEvents(events)
+
+	t.Run("only the host ends the meeting", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(&entity.Room{ID: "room123", HostID: "host123"}, nil).Once()
+
+		err := uc.EndMeeting(context.Background(), "room123", "user456")
+
+		assert.True(t, errors.Is(err, usecase.ErrNotHost))
+		mockRoomRepo.AssertNotCalled(t, "Delete", mock.Anything, "room123")
+		assert.Empty(t, events.ended)
+	})
+
+	t.Run("host ends the meeting for everyone", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", HostID: "host123", IsRecording: true, RecordingID: "rec-1"}
+		recording := &entity.Recording{ID: "rec-1", RoomID: "room123", StartTime: time.Now().Add(-5 * time.Minute)}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRecordingRepo.On("Get", mock.Anything, "rec-1").Return(recording, nil).Once()
+		mockRecordingRepo.On("Update", mock.Anything, mock.MatchedBy(func(r *entity.Recording) bool {
+			return r.Status == "processing"
+		})).Return(nil).Once()
+		mockRoomRepo.On("Delete", mock.Anything, "room123").Return(nil).Once()
+
+		err := uc.EndMeeting(context.Background(), "room123", "host123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, usecase.MeetingEndedByHost, events.ended["room123"])
+		mockRoomRepo.AssertExpectations(t)
+		mockRecordingRepo.AssertExpectations(t)
+	})
+
+	t.Run("empty room stays open when configured", func(t *testing.T) {
+		keep := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{
+			Rooms: config.RoomsConfig{KeepEmpty: true},
+		})
+
+		mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room789", "participant123").Return(nil).Once()
+		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room789").Return(0, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room789").Return(&entity.Room{ID: "room789", HostID: "host123"}, nil).Once()
+
+		err := keep.LeaveRoom(context.Background(), "room789", "participant123")
+
+		assert.NoError(t, err)
+		mockRoomRepo.AssertNotCalled(t, "Delete", mock.Anything, "room789")
+		mockRoomRepo.AssertNotCalled(t, "ClearSession", mock.Anything, "room789")
+	})
+}
@@ -0,0 +1,129 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMeetingExpiry(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+	events := &recordedEvents{}
+	uc.SetRoomEvents(events)
+
+	t.Run("host extends the meeting", func(t *testing.T) {
+		room := &entity.Room{
+			ID:        "room123",
+			HostID:    "host123",
+			CreatedAt: time.Now().Add(-55 * time.Minute),
+			Settings:  entity.RoomSettings{MaxDuration: 60},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("Update", mock.Anything, mock.MatchedBy(func(r *entity.Room) bool {
+			return r.ExtendedMinutes == 15
+		})).Return(nil).Once()
+		mockRoomRepo.On("ExtendTTL", mock.Anything, "room123", mock.AnythingOfType("time.Duration")).Return(nil).Once()
+
+		extended, err := uc.ExtendRoomDuration(context.Background(), "room123", "host123", 15*time.Minute)
+
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(20*time.Minute), extended.EndsAt(), time.Second)
+		mockRoomRepo.AssertExpectations(t)
+	})
+
+	t.Run("nothing to extend without a time limit", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", HostID: "host123", CreatedAt: time.Now()}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		_, err := uc.ExtendRoomDuration(context.Background(), "room123", "host123", 15*time.Minute)
+
+		assert.True(t, errors.Is(err, usecase.ErrNoTimeLimit))
+	})
+
+	t.Run("meeting still running is not ended", func(t *testing.T) {
+		room := &entity.Room{
+			ID:        "room123",
+			CreatedAt: time.Now().Add(-30 * time.Minute),
+			Settings:  entity.RoomSettings{MaxDuration: 60},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		ended, err := uc.EndExpiredMeeting(context.Background(), "room123")
+
+		assert.NoError(t, err)
+		assert.False(t, ended)
+	})
+
+	t.Run("expired meeting stops recording and closes the room", func(t *testing.T) {
+		room := &entity.Room{
+			ID:          "room123",
+			CreatedAt:   time.Now().Add(-61 * time.Minute),
+			IsRecording: true,
+			RecordingID: "rec-1",
+			Settings:    entity.RoomSettings{MaxDuration: 60},
+		}
+		recording := &entity.Recording{ID: "rec-1", RoomID: "room123", StartTime: time.Now().Add(-10 * time.Minute)}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("ClaimNotice", mock.Anything, "room123", mock.Anything, mock.Anything).Return(true, nil).Once()
+		mockRecordingRepo.On("Get", mock.Anything, "rec-1").Return(recording, nil).Once()
+		mockRecordingRepo.On("Update", mock.Anything, mock.MatchedBy(func(r *entity.Recording) bool {
+			return r.Status == "processing"
+		})).Return(nil).Once()
+		mockRoomRepo.On("Delete", mock.Anything, "room123").Return(nil).Once()
+
+		ended, err := uc.EndExpiredMeeting(context.Background(), "room123")
+
+		assert.NoError(t, err)
+		assert.True(t, ended)
+		assert.Equal(t, usecase.MeetingEndedTimeLimit, events.ended["room123"])
+		mockRoomRepo.AssertExpectations(t)
+		mockRecordingRepo.AssertExpectations(t)
+	})
+
+	t.Run("another node already ended it", func(t *testing.T) {
+		room := &entity.Room{
+			ID:        "room123",
+			CreatedAt: time.Now().Add(-61 * time.Minute),
+			Settings:  entity.RoomSettings{MaxDuration: 60},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("ClaimNotice", mock.Anything, "room123", mock.Anything, mock.Anything).Return(false, nil).Once()
+
+		ended, err := uc.EndExpiredMeeting(context.Background(), "room123")
+
+		assert.NoError(t, err)
+		assert.False(t, ended)
+	})
+
+	t.Run("a long overdue meeting's notice still expires", func(t *testing.T) {
+		endsAt := time.Now().Add(-3 * time.Hour)
+
+		mockRoomRepo.On("ClaimNotice", mock.Anything, "room123", mock.Anything, mock.MatchedBy(func(ttl time.Duration) bool {
+			return ttl > 0
+		})).Return(false, nil).Once()
+
+		claimed, err := uc.ClaimExpiryNotice(context.Background(), "room123", endsAt, "ended")
+
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		mockRoomRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,99 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	"bincang-visual/internal/middleware"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBannedGuestStaysBanned(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	app := fiber.New()
+	app.Get("/ws/room/:roomId", middleware.WebSocketAuthMiddleware("test-secret", client), func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals("userID").(string))
+	})
+
+	// connect returns the guest's user ID and the cookie to come back with
+	connect := func(cookie *http.Cookie, query string) (string, *http.Cookie) {
+		req := httptest.NewRequest("GET", "/ws/room/room123"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		for _, c := range resp.Cookies() {
+			if c.Name == middleware.GuestCookie {
+				return string(body), c
+			}
+		}
+		t.Fatal("no guest cookie issued")
+		return "", nil
+	}
+
+	t.Run("a guest keeps their identity", func(t *testing.T) {
+		guestID, cookie := connect(nil, "")
+		again, _ := connect(cookie, "")
+		byQuery, _ := connect(nil, "?guest="+cookie.Value)
+
+		assert.Equal(t, guestID, again)
+		assert.Equal(t, guestID, byQuery)
+		other, _ := connect(nil, "")
+		assert.NotEqual(t, guestID, other)
+	})
+
+	t.Run("a forged cookie gets a new identity", func(t *testing.T) {
+		guestID, cookie := connect(nil, "")
+
+		forged, _ := connect(&http.Cookie{Name: middleware.GuestCookie, Value: "guest-someone-else." + cookie.Value[len(guestID)+1:]}, "")
+
+		assert.NotEqual(t, "guest-someone-else", forged)
+	})
+
+	t.Run("a banned guest who reconnects is refused", func(t *testing.T) {
+		ctx := context.Background()
+		settings := entity.DefaultRoomSettings()
+		settings.AllowGuests = true
+		room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Open house", HostID: "host123", Settings: settings})
+		require.NoError(t, err)
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: "host123", ParticipantID: "conn-host"})
+		require.NoError(t, err)
+
+		guestID, cookie := connect(nil, "")
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: guestID, ParticipantID: "conn-1", IsGuest: true})
+		require.NoError(t, err)
+		_, err = uc.BanParticipant(ctx, room.ID, "conn-host", "conn-1")
+		require.NoError(t, err)
+
+		reconnected, _ := connect(cookie, "")
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: reconnected, ParticipantID: "conn-2", IsGuest: true})
+		assert.ErrorIs(t, err, usecase.ErrBanned)
+	})
+}
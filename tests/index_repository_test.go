@@ -0,0 +1,180 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/domain/entity"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecondaryIndexes(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	users := redisRepo.NewUserRepository(client)
+	recordings := redisRepo.NewRecordingRepository(client)
+	ctx := context.Background()
+
+	t.Run("users by email", func(t *testing.T) {
+		require.NoError(t, users.Create(ctx, &entity.User{ID: "user123", Email: "Ana@Example.com"}))
+
+		user, err := users.GetByEmail(ctx, "ana@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", user.ID)
+
+		require.NoError(t, users.Update(ctx, &entity.User{ID: "user123", Email: "ana@work.example"}))
+		_, err = users.GetByEmail(ctx, "ana@example.com")
+		assert.Error(t, err, "the old email is given up")
+		user, err = users.GetByEmail(ctx, "ana@work.example")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", user.ID)
+
+		_, err = users.GetByEmail(ctx, "nobody@example.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("recordings by room", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, recordings.Create(ctx, &entity.Recording{ID: "rec-2", RoomID: "room123", StartTime: start.Add(time.Minute)}))
+		require.NoError(t, recordings.Create(ctx, &entity.Recording{ID: "rec-1", RoomID: "room123", StartTime: start}))
+		require.NoError(t, recordings.Create(ctx, &entity.Recording{ID: "rec-3", RoomID: "room456", StartTime: start}))
+
+		found, err := recordings.GetByRoomID(ctx, "room123")
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "rec-1", found[0].ID)
+		assert.Equal(t, "rec-2", found[1].ID)
+
+		server.FastForward(8 * 24 * time.Hour)
+		found, err = recordings.GetByRoomID(ctx, "room123")
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("migration indexes existing data", func(t *testing.T) {
+		// stored the way it was before there were indexes
+		for key, value := range map[string]interface{}{
+			"user:user789":     entity.User{ID: "user789", Email: "bob@example.com"},
+			"recording:rec-9":  entity.Recording{ID: "rec-9", RoomID: "room789"},
+			"recording:rec-10": entity.Recording{ID: "rec-10", RoomID: "room789"},
+		} {
+			data, err := json.Marshal(value)
+			require.NoError(t, err)
+			require.NoError(t, client.Set(ctx, key, data, 0).Err())
+		}
+
+		_, err := users.GetByEmail(ctx, "bob@example.com")
+		require.Error(t, err)
+
+		indexed, err := users.RebuildEmailIndex(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, indexed)
+		indexed, err = recordings.RebuildRoomIndex(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, indexed)
+
+		user, err := users.GetByEmail(ctx, "bob@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "user789", user.ID)
+		found, err := recordings.GetByRoomID(ctx, "room789")
+		require.NoError(t, err)
+		assert.Len(t, found, 2)
+
+		// users who signed in since keep their entry
+		user, err = users.GetByEmail(ctx, "ana@work.example")
+		require.NoError(t, err)
+		assert.Equal(t, "user123", user.ID)
+	})
+
+	t.Run("concurrent saves of a user", func(t *testing.T) {
+		// every save that loses the race retries after the winner, so
+		// three writers need at most three attempts
+		emails := []string{"cy@example.com", "cy@work.example", "cy@home.example"}
+		errs := make(chan error, len(emails))
+		for _, email := range emails {
+			go func(email string) {
+				errs <- users.Update(ctx, &entity.User{ID: "user321", Email: email})
+			}(email)
+		}
+		for range emails {
+			assert.NoError(t, <-errs)
+		}
+
+		user, err := users.Get(ctx, "user321")
+		require.NoError(t, err)
+		found, err := users.GetByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		assert.Equal(t, "user321", found.ID)
+		for _, email := range emails {
+			if email != user.Email {
+				_, err := users.GetByEmail(ctx, email)
+				assert.Error(t, err, "%s was given up", email)
+			}
+		}
+	})
+}
+
+// The lookups take as long with many users or recordings as with a few.
+//
+//	go test ./tests -run '^$' -bench 'ByEmail|ByRoom'
+func BenchmarkGetUserByEmail(b *testing.B) {
+	for _, count := range []int{100, 1000, 10000} {
+		server := miniredis.RunT(b)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		users := redisRepo.NewUserRepository(client)
+		ctx := context.Background()
+
+		for i := 0; i < count; i++ {
+			user := &entity.User{ID: fmt.Sprintf("user-%d", i), Email: fmt.Sprintf("user-%d@example.com", i)}
+			if err := users.Create(ctx, user); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.Run(fmt.Sprintf("users=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := users.GetByEmail(ctx, fmt.Sprintf("user-%d@example.com", i%count)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		client.Close()
+	}
+}
+
+func BenchmarkGetRecordingsByRoom(b *testing.B) {
+	for _, count := range []int{100, 1000, 10000} {
+		server := miniredis.RunT(b)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		recordings := redisRepo.NewRecordingRepository(client)
+		ctx := context.Background()
+
+		// a few recordings for each room
+		rooms := count / 4
+		for i := 0; i < count; i++ {
+			recording := &entity.Recording{ID: fmt.Sprintf("rec-%d", i), RoomID: fmt.Sprintf("room-%d", i%rooms)}
+			if err := recordings.Create(ctx, recording); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.Run(fmt.Sprintf("recordings=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := recordings.GetByRoomID(ctx, fmt.Sprintf("room-%d", i%rooms)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		client.Close()
+	}
+}
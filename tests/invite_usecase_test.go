@@ -0,0 +1,233 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomInvites(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{JWT: config.JWTConfig{Secret: "test-secret"}},
+	)
+
+	ctx := context.Background()
+	settings := entity.DefaultRoomSettings()
+	settings.WaitingRoom = true
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{
+		Name:     "Incident review",
+		HostID:   "host123",
+		Settings: settings,
+		Passcode: "4321",
+	})
+	require.NoError(t, err)
+
+	invite := func(input usecase.CreateInviteInput) string {
+		input.RoomID = room.ID
+		input.UserID = "host123"
+		_, token, err := uc.CreateInvite(ctx, input)
+		require.NoError(t, err)
+		return token
+	}
+	join := func(input usecase.JoinRoomInput) (*entity.Participant, error) {
+		input.RoomID = room.ID
+		input.ParticipantID = "conn-" + input.UserID + "-" + input.Device
+		return uc.JoinRoom(ctx, input)
+	}
+
+	t.Run("only the host invites", func(t *testing.T) {
+		_, _, err := uc.CreateInvite(ctx, usecase.CreateInviteInput{RoomID: room.ID, UserID: "user456"})
+		assert.ErrorIs(t, err, usecase.ErrNotHost)
+	})
+
+	t.Run("invalid invites", func(t *testing.T) {
+		for name, input := range map[string]usecase.CreateInviteInput{
+			"unknown role":  {Role: entity.RoleHost},
+			"bad email":     {Email: "not an email"},
+			"negative uses": {MaxUses: -1},
+			"too long":      {ExpiresIn: 60 * 24 * time.Hour},
+		} {
+			input.RoomID, input.UserID = room.ID, "host123"
+			_, _, err := uc.CreateInvite(ctx, input)
+			assert.ErrorIs(t, err, usecase.ErrInvalidInviteRequest, name)
+		}
+	})
+
+	t.Run("skips the passcode and waiting room", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{})
+
+		participant, err := join(usecase.JoinRoomInput{UserID: "user456", InviteToken: token})
+
+		require.NoError(t, err)
+		assert.Equal(t, entity.ParticipantStatusActive, participant.Status)
+		assert.Equal(t, entity.RoleParticipant, participant.Role())
+	})
+
+	t.Run("lets guests in", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{})
+
+		_, err := join(usecase.JoinRoomInput{UserID: "guest-1", IsGuest: true, InviteToken: token})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("runs out of uses", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{MaxUses: 1})
+
+		_, err := join(usecase.JoinRoomInput{UserID: "user500", Device: "laptop", InviteToken: token})
+		require.NoError(t, err)
+		_, err = join(usecase.JoinRoomInput{UserID: "user500", Device: "phone", InviteToken: token})
+		assert.NoError(t, err, "the same user may connect again")
+
+		_, err = join(usecase.JoinRoomInput{UserID: "user501", InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInviteUsedUp)
+	})
+
+	t.Run("kept when the room is full", func(t *testing.T) {
+		small, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Small", HostID: "host123", MaxParticipants: 2})
+		require.NoError(t, err)
+		for _, userID := range []string{"host123", "user550"} {
+			_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: small.ID, UserID: userID})
+			require.NoError(t, err)
+		}
+		created, token, err := uc.CreateInvite(ctx, usecase.CreateInviteInput{RoomID: small.ID, UserID: "host123", MaxUses: 1})
+		require.NoError(t, err)
+		uses := func() int {
+			invites, err := uc.ListInvites(ctx, small.ID, "host123")
+			require.NoError(t, err)
+			require.Len(t, invites, 1)
+			require.Equal(t, created.ID, invites[0].ID)
+			return invites[0].Uses
+		}
+
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: small.ID, UserID: "user551", InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrRoomFull)
+		assert.Equal(t, 0, uses())
+
+		require.NoError(t, uc.LeaveRoom(ctx, small.ID, "user550"))
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: small.ID, UserID: "user551", InviteToken: token})
+		require.NoError(t, err)
+		assert.Equal(t, 1, uses())
+	})
+
+	t.Run("bound to an email", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{Email: "Ana@Example.com"})
+
+		_, err := join(usecase.JoinRoomInput{UserID: "guest-2", IsGuest: true, InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInviteSignInRequired)
+
+		_, err = join(usecase.JoinRoomInput{UserID: "user600", Email: "bob@example.com", InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInviteNotForYou)
+
+		_, err = join(usecase.JoinRoomInput{UserID: "user601", Email: "ana@example.com", InviteToken: token})
+		assert.NoError(t, err)
+	})
+
+	t.Run("co-host", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{Role: entity.RoleCoHost})
+
+		_, err := join(usecase.JoinRoomInput{UserID: "guest-3", IsGuest: true, InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInviteSignInRequired)
+
+		participant, err := join(usecase.JoinRoomInput{UserID: "user700", InviteToken: token})
+		require.NoError(t, err)
+		assert.True(t, participant.IsHost)
+		assert.True(t, participant.IsCoHost)
+	})
+
+	t.Run("viewer never presents", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{Role: entity.RoleViewer})
+
+		participant, err := join(usecase.JoinRoomInput{UserID: "user800", InviteToken: token})
+		require.NoError(t, err)
+
+		assert.Equal(t, entity.RoleViewer, participant.Role())
+		assert.ErrorIs(t, uc.Authorize(ctx, room.ID, participant.ID, entity.CapabilityScreenShare), usecase.ErrNotPermitted)
+		assert.NoError(t, uc.Authorize(ctx, room.ID, participant.ID, entity.CapabilityChat))
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		created, token, err := uc.CreateInvite(ctx, usecase.CreateInviteInput{RoomID: room.ID, UserID: "host123"})
+		require.NoError(t, err)
+		_, err = join(usecase.JoinRoomInput{UserID: "user900", InviteToken: token})
+		require.NoError(t, err)
+
+		invites, err := uc.ListInvites(ctx, room.ID, "host123")
+		require.NoError(t, err)
+		require.NotEmpty(t, invites)
+		last := invites[len(invites)-1]
+		assert.Equal(t, created.ID, last.ID)
+		assert.Equal(t, 1, last.Uses)
+
+		require.NoError(t, uc.RevokeInvite(ctx, room.ID, created.ID, "host123"))
+		assert.ErrorIs(t, uc.RevokeInvite(ctx, room.ID, created.ID, "host123"), repository.ErrInviteNotFound)
+
+		_, err = join(usecase.JoinRoomInput{UserID: "user901", InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInvalidInvite)
+	})
+
+	t.Run("forged or for another room", func(t *testing.T) {
+		token := invite(usecase.CreateInviteInput{})
+
+		other, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Other", HostID: "host123"})
+		require.NoError(t, err)
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: other.ID, UserID: "user950", InviteToken: token})
+		assert.ErrorIs(t, err, usecase.ErrInvalidInvite)
+
+		_, err = join(usecase.JoinRoomInput{UserID: "user951", InviteToken: token + "x"})
+		assert.ErrorIs(t, err, usecase.ErrInvalidInvite)
+	})
+}
+
+func TestDeletedRoomLeavesNoKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	roomRepo := redisRepo.NewRoomRepository(client)
+	uc := usecase.NewRoomUseCase(
+		roomRepo,
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{JWT: config.JWTConfig{Secret: "test-secret"}},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Retro", HostID: "host123"})
+	require.NoError(t, err)
+	_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: "host123"})
+	require.NoError(t, err)
+	_, token, err := uc.CreateInvite(ctx, usecase.CreateInviteInput{RoomID: room.ID, UserID: "host123"})
+	require.NoError(t, err)
+	_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: "user456", InviteToken: token})
+	require.NoError(t, err)
+	claimed, err := uc.ClaimExpiryNotice(ctx, room.ID, time.Now().Add(time.Hour), "warning")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	require.NoError(t, roomRepo.Delete(ctx, room.ID))
+
+	for _, key := range server.Keys() {
+		assert.NotContains(t, key, room.ID)
+	}
+}
@@ -0,0 +1,154 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentJoinsRespectCapacity(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 64})
+	defer client.Close()
+
+	participantRepo := redisRepo.NewParticipantRepository(client)
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		participantRepo,
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Town hall", HostID: "host123", MaxParticipants: 25})
+	require.NoError(t, err)
+
+	const joiners = 300
+	var joined, full atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < joiners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{
+				RoomID:      room.ID,
+				UserID:      fmt.Sprintf("user-%d", i),
+				DisplayName: "Attendee",
+			})
+			switch {
+			case err == nil:
+				joined.Add(1)
+			case errors.Is(err, usecase.ErrRoomFull):
+				full.Add(1)
+			default:
+				t.Errorf("unexpected join error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	count, err := participantRepo.GetParticipantCount(ctx, room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 25, count)
+	assert.Equal(t, int32(25), joined.Load())
+	assert.Equal(t, int32(joiners-25), full.Load())
+}
+
+func TestConcurrentAdmitsAndJoinsRespectCapacity(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 64})
+	defer client.Close()
+
+	participantRepo := redisRepo.NewParticipantRepository(client)
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		participantRepo,
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Town hall", HostID: "host123", MaxParticipants: 25})
+	require.NoError(t, err)
+	_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: "host123", DisplayName: "Host"})
+	require.NoError(t, err)
+
+	const waiting = 100
+	for i := 0; i < waiting; i++ {
+		userID := fmt.Sprintf("waiting-%d", i)
+		require.NoError(t, participantRepo.JoinRoom(ctx, &entity.Participant{
+			ID:     userID,
+			UserID: userID,
+			RoomID: room.ID,
+			Status: entity.ParticipantStatusWaiting,
+		}, room.MaxParticipants))
+	}
+
+	const joiners = 100
+	var entered, full atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < joiners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{
+				RoomID:      room.ID,
+				UserID:      fmt.Sprintf("user-%d", i),
+				DisplayName: "Attendee",
+			})
+			switch {
+			case err == nil:
+				entered.Add(1)
+			case errors.Is(err, usecase.ErrRoomFull):
+				full.Add(1)
+			default:
+				t.Errorf("unexpected join error: %v", err)
+			}
+		}(i)
+	}
+	// every waiting participant is admitted twice, as by two hosts at once
+	for i := 0; i < 2*waiting; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			admitted, err := uc.AdmitParticipants(ctx, room.ID, "host123", []string{fmt.Sprintf("waiting-%d", i%waiting)})
+			entered.Add(int32(len(admitted)))
+			if err != nil && !errors.Is(err, usecase.ErrRoomFull) {
+				t.Errorf("unexpected admit error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	count, err := participantRepo.GetParticipantCount(ctx, room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 25, count)
+	assert.Equal(t, int32(24), entered.Load())
+
+	lobby, err := participantRepo.GetLobby(ctx, room.ID)
+	require.NoError(t, err)
+	participants, err := participantRepo.GetParticipants(ctx, room.ID)
+	require.NoError(t, err)
+	inRoom := make(map[string]bool, len(participants))
+	for _, p := range participants {
+		inRoom[p.ID] = true
+	}
+	for _, p := range lobby {
+		assert.False(t, inRoom[p.ID], "%s is both waiting and admitted", p.ID)
+	}
+}
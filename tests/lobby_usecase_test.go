@@ -0,0 +1,163 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWaitingRoom(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	room := &entity.Room{
+		ID:              "room123",
+		HostID:          "host123",
+		MaxParticipants: 2,
+		Settings:        entity.RoomSettings{WaitingRoom: true},
+	}
+
+	t.Run("participant is parked in the lobby", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.MatchedBy(func(p *entity.Participant) bool {
+			return p.Status == entity.ParticipantStatusWaiting
+		}), 2).Return(nil).Once()
+
+		participant, err := uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID:      "room123",
+			UserID:      "user456",
+			DisplayName: "Test User",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.ParticipantStatusWaiting, participant.Status)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("host skips the lobby", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.MatchedBy(func(p *entity.Participant) bool {
+			return p.Status == entity.ParticipantStatusActive
+		}), 2).Return(nil).Once()
+
+		participant, err := uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID:      "room123",
+			UserID:      "host123",
+			DisplayName: "Host",
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, participant.IsHost)
+		assert.Equal(t, entity.ParticipantStatusActive, participant.Status)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("non-host cannot admit", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "user789").
+			Return(&entity.Participant{UserID: "user789", IsHost: false}, nil).Once()
+
+		admitted, err := uc.AdmitParticipants(context.Background(), "room123", "user789", []string{"user456"})
+
+		assert.ErrorIs(t, err, usecase.ErrNotHost)
+		assert.Empty(t, admitted)
+	})
+
+	t.Run("host admits a waiting participant", func(t *testing.T) {
+		waiting := &entity.Participant{ID: "user456", UserID: "user456", RoomID: "room123", Status: entity.ParticipantStatusWaiting}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").
+			Return(&entity.Participant{UserID: "host123", IsHost: true}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetLobbyParticipant", mock.Anything, "room123", "user456").Return(waiting, nil).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, waiting, 2).Return(nil).Once()
+
+		admitted, err := uc.AdmitParticipants(context.Background(), "room123", "host123", []string{"user456"})
+
+		assert.NoError(t, err)
+		assert.Len(t, admitted, 1)
+		assert.Equal(t, entity.ParticipantStatusActive, admitted[0].Status)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("admit all stops when room is full", func(t *testing.T) {
+		lobby := []*entity.Participant{
+			{ID: "a", UserID: "a", RoomID: "room123"},
+			{ID: "b", UserID: "b", RoomID: "room123"},
+		}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").
+			Return(&entity.Participant{UserID: "host123", IsHost: true}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetLobby", mock.Anything, "room123").Return(lobby, nil).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, lobby[0], 2).Return(nil).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, lobby[1], 2).Return(repository.ErrRoomFull).Once()
+
+		admitted, err := uc.AdmitParticipants(context.Background(), "room123", "host123", nil)
+
+		assert.True(t, errors.Is(err, usecase.ErrRoomFull))
+		assert.Len(t, admitted, 1)
+		assert.Equal(t, "a", admitted[0].UserID)
+	})
+
+	t.Run("admit skips participants decided elsewhere or banned", func(t *testing.T) {
+		lobby := []*entity.Participant{
+			{ID: "a", UserID: "a", RoomID: "room123"},
+			{ID: "b", UserID: "b", RoomID: "room123"},
+			{ID: "c", UserID: "c", RoomID: "room123"},
+		}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").
+			Return(&entity.Participant{UserID: "host123", IsHost: true}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetLobby", mock.Anything, "room123").Return(lobby, nil).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, lobby[0], 2).Return(repository.ErrNotWaiting).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, lobby[1], 2).Return(repository.ErrUserBanned).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, lobby[2], 2).Return(nil).Once()
+
+		admitted, err := uc.AdmitParticipants(context.Background(), "room123", "host123", nil)
+
+		assert.NoError(t, err)
+		assert.Len(t, admitted, 1)
+		assert.Equal(t, "c", admitted[0].UserID)
+	})
+
+	t.Run("admit stops when the room is locked", func(t *testing.T) {
+		waiting := &entity.Participant{ID: "d", UserID: "d", RoomID: "room123"}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").
+			Return(&entity.Participant{UserID: "host123", IsHost: true}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetLobbyParticipant", mock.Anything, "room123", "d").Return(waiting, nil).Once()
+		mockParticipantRepo.On("AdmitParticipant", mock.Anything, waiting, 2).Return(repository.ErrRoomLocked).Once()
+
+		admitted, err := uc.AdmitParticipants(context.Background(), "room123", "host123", []string{"d"})
+
+		assert.ErrorIs(t, err, usecase.ErrRoomLocked)
+		assert.Empty(t, admitted)
+	})
+
+	t.Run("participant already denied by another host is skipped", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").
+			Return(&entity.Participant{UserID: "host123", IsHost: true}, nil).Once()
+		mockParticipantRepo.On("GetLobbyParticipant", mock.Anything, "room123", "user456").
+			Return(&entity.Participant{ID: "user456", UserID: "user456", RoomID: "room123"}, nil).Once()
+		mockParticipantRepo.On("RemoveFromLobby", mock.Anything, "room123", "user456").Return(false, nil).Once()
+
+		denied, err := uc.DenyParticipants(context.Background(), "room123", "host123", []string{"user456"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, denied)
+	})
+}
@@ -0,0 +1,133 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModeration(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	host := &entity.Participant{UserID: "host123", RoomID: "room123", IsHost: true}
+
+	t.Run("non-host cannot mute", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "user456").
+			Return(&entity.Participant{UserID: "user456", RoomID: "room123"}, nil).Once()
+
+		_, err := uc.MuteParticipant(context.Background(), "room123", "user456", "user789")
+
+		assert.ErrorIs(t, err, usecase.ErrNotHost)
+	})
+
+	t.Run("host cannot be moderated", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").Return(host, nil).Once()
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "cohost").
+			Return(&entity.Participant{UserID: "cohost", RoomID: "room123", IsHost: true}, nil).Once()
+
+		err := uc.RemoveParticipant(context.Background(), "room123", "host123", "cohost")
+
+		assert.ErrorIs(t, err, usecase.ErrCannotModerateHost)
+	})
+
+	t.Run("host mutes a participant", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "host123").Return(host, nil).Once()
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "user456").
+			Return(&entity.Participant{UserID: "user456", RoomID: "room123"}, nil).Once()
+		mockParticipantRepo.On("UpdateParticipant", mock.Anything, mock.AnythingOfType("*entity.Participant")).Return(nil).Once()
+
+		target, err := uc.MuteParticipant(context.Background(), "room123", "host123", "user456")
+
+		assert.NoError(t, err)
+		assert.True(t, target.IsMuted)
+	})
+
+	t.Run("banned user cannot rejoin", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").
+			Return(&entity.Room{ID: "room123", HostID: "host123", MaxParticipants: 10}, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.MatchedBy(func(p *entity.Participant) bool {
+			return p.UserID == "user456"
+		}), 10).Return(repository.ErrUserBanned).Once()
+
+		_, err := uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID:      "room123",
+			UserID:      "user456",
+			DisplayName: "Test User",
+		})
+
+		assert.ErrorIs(t, err, usecase.ErrBanned)
+	})
+}
+
+func TestParticipantUpdateAfterLeaving(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Standup", HostID: "host123", MaxParticipants: 10})
+	require.NoError(t, err)
+	participant, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, UserID: "user456"})
+	require.NoError(t, err)
+
+	// a mute read the participant just before they left
+	require.NoError(t, uc.LeaveRoom(ctx, room.ID, participant.ID))
+	participant.IsMuted = true
+
+	assert.ErrorIs(t, uc.UpdateParticipantState(ctx, participant), usecase.ErrParticipantMissing)
+
+	participants, err := uc.GetParticipants(ctx, room.ID)
+	require.NoError(t, err)
+	assert.Empty(t, participants, "the late update doesn't put them back")
+}
+
+func TestBanLastsAsLongAsTheRoom(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	rooms := redisRepo.NewRoomRepository(client)
+	ctx := context.Background()
+
+	require.NoError(t, rooms.Create(ctx, &entity.Room{ID: "room123", HostID: "host123"}, time.Hour))
+	require.NoError(t, rooms.AddBan(ctx, "room123", "user456"))
+
+	// the meeting was extended past its original end
+	require.NoError(t, rooms.ExtendTTL(ctx, "room123", 3*time.Hour))
+	server.FastForward(2 * time.Hour)
+
+	banned, err := rooms.IsBanned(ctx, "room123", "user456")
+	require.NoError(t, err)
+	assert.True(t, banned)
+
+	// a personal room's next session still keeps them out
+	require.NoError(t, rooms.ClearSession(ctx, "room123"))
+	banned, err = rooms.IsBanned(ctx, "room123", "user456")
+	require.NoError(t, err)
+	assert.True(t, banned)
+}
@@ -0,0 +1,211 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRoomPasscode(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("4321"), bcrypt.MinCost)
+	require.NoError(t, err)
+	room := &entity.Room{ID: "room123", HostID: "host123", MaxParticipants: 10, HasPasscode: true}
+
+	join := func(userID, passcode string) (*entity.Participant, error) {
+		return uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID:   "room123",
+			UserID:   userID,
+			Passcode: passcode,
+			ClientIP: "203.0.113.7",
+		})
+	}
+
+	t.Run("only the hash is stored", func(t *testing.T) {
+		mockRoomRepo.On("Create", mock.Anything, mock.MatchedBy(func(r *entity.Room) bool {
+			return r.HasPasscode
+		}), mock.Anything).Return(nil).Once()
+		mockRoomRepo.On("SetPasscode", mock.Anything, mock.Anything, mock.MatchedBy(func(h string) bool {
+			return h != "4321" && bcrypt.CompareHashAndPassword([]byte(h), []byte("4321")) == nil
+		})).Return(nil).Once()
+
+		created, err := uc.CreateRoom(context.Background(), usecase.CreateRoomInput{Name: "Board", HostID: "host123", Passcode: "4321"})
+
+		assert.NoError(t, err)
+		assert.True(t, created.HasPasscode)
+		mockRoomRepo.AssertExpectations(t)
+	})
+
+	t.Run("passcode too short", func(t *testing.T) {
+		_, err := uc.CreateRoom(context.Background(), usecase.CreateRoomInput{Name: "Board", HostID: "host123", Passcode: "12"})
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidPasscode)
+	})
+
+	t.Run("joining without the passcode", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		_, err := join("user456", "")
+
+		assert.ErrorIs(t, err, usecase.ErrPasscodeRequired)
+		mockRoomRepo.AssertNotCalled(t, "ReservePasscodeAttempt", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong passcode is counted", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("ReservePasscodeAttempt", mock.Anything, "room123", "203.0.113.7", 10, 50, mock.Anything).Return(true, nil).Once()
+		mockRoomRepo.On("GetPasscode", mock.Anything, "room123").Return(string(hash), nil).Once()
+
+		_, err := join("user456", "1234")
+
+		assert.ErrorIs(t, err, usecase.ErrWrongPasscode)
+		mockRoomRepo.AssertExpectations(t)
+		mockRoomRepo.AssertNotCalled(t, "ReleasePasscodeAttempt", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("right passcode joins", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("ReservePasscodeAttempt", mock.Anything, "room123", "203.0.113.7", 10, 50, mock.Anything).Return(true, nil).Once()
+		mockRoomRepo.On("GetPasscode", mock.Anything, "room123").Return(string(hash), nil).Once()
+		mockRoomRepo.On("ReleasePasscodeAttempt", mock.Anything, "room123", "203.0.113.7").Return(nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.AnythingOfType("*entity.Participant"), 10).Return(nil).Once()
+
+		participant, err := join("user456", "4321")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user456", participant.UserID)
+		mockRoomRepo.AssertExpectations(t)
+		mockParticipantRepo.AssertExpectations(t)
+	})
+
+	t.Run("throttled after too many wrong passcodes", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("ReservePasscodeAttempt", mock.Anything, "room123", "203.0.113.7", 10, 50, mock.Anything).Return(false, nil).Once()
+
+		_, err := join("user456", "4321")
+
+		assert.ErrorIs(t, err, usecase.ErrTooManyAttempts)
+	})
+
+	t.Run("host needs no passcode", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.MatchedBy(func(p *entity.Participant) bool {
+			return p.IsHost
+		}), 10).Return(nil).Once()
+
+		participant, err := join("host123", "")
+
+		assert.NoError(t, err)
+		assert.True(t, participant.IsHost)
+	})
+}
+
+func TestPasscodeAttemptsInParallel(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Board", HostID: "host123", Passcode: "4321"})
+	require.NoError(t, err)
+
+	// guesses sent at once are counted before any of them is compared
+	errs := make(chan error, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := uc.ValidateRoomAccess(ctx, usecase.RoomAccessInput{RoomID: room.ID, Passcode: "1234", ClientIP: "203.0.113.7"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var wrong, throttled int
+	for err := range errs {
+		switch {
+		case errors.Is(err, usecase.ErrWrongPasscode):
+			wrong++
+		case errors.Is(err, usecase.ErrTooManyAttempts):
+			throttled++
+		}
+	}
+	assert.Equal(t, 10, wrong)
+	assert.Equal(t, 10, throttled)
+
+	// a right passcode from elsewhere doesn't count against anyone
+	for i := 0; i < 3; i++ {
+		_, err := uc.ValidateRoomAccess(ctx, usecase.RoomAccessInput{RoomID: room.ID, Passcode: "4321", ClientIP: "198.51.100.9"})
+		require.NoError(t, err)
+	}
+	_, err = uc.ValidateRoomAccess(ctx, usecase.RoomAccessInput{RoomID: room.ID, Passcode: "4321", ClientIP: "203.0.113.7"})
+	assert.ErrorIs(t, err, usecase.ErrTooManyAttempts)
+}
+
+func TestRoomLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+
+	ctx := context.Background()
+	room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Standup", HostID: "host123", MaxParticipants: 10})
+	require.NoError(t, err)
+
+	join := func(participantID, userID string) error {
+		_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: participantID, UserID: userID})
+		return err
+	}
+	require.NoError(t, join("conn-laptop", "user456"))
+
+	_, err = uc.SetRoomLocked(ctx, room.ID, "user456", true)
+	assert.ErrorIs(t, err, usecase.ErrNotHost)
+
+	locked, err := uc.SetRoomLocked(ctx, room.ID, "host123", true)
+	require.NoError(t, err)
+	assert.True(t, locked.Locked)
+
+	assert.True(t, errors.Is(join("conn-new", "user789"), usecase.ErrRoomLocked), "newcomers are kept out")
+	assert.NoError(t, join("conn-phone", "user456"), "someone already in can connect again")
+	assert.NoError(t, join("conn-host", "host123"), "the host always gets in")
+
+	_, err = uc.SetRoomLocked(ctx, room.ID, "host123", false)
+	require.NoError(t, err)
+	assert.NoError(t, join("conn-new", "user789"))
+}
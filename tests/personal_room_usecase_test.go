@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalRoom(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+	events := &recordedEvents{}
+	uc.SetRoomEvents(events)
+	ctx := context.Background()
+
+	room, err := uc.GetPersonalRoom(ctx, "user456", "Ana")
+	require.NoError(t, err)
+
+	t.Run("created once and kept for good", func(t *testing.T) {
+		assert.True(t, room.Personal)
+		assert.Equal(t, "Ana's room", room.Name)
+		assert.True(t, room.Settings.WaitingRoom, "only the host lets people in")
+
+		again, err := uc.GetPersonalRoom(ctx, "user456", "Ana")
+		require.NoError(t, err)
+		assert.Equal(t, room.ID, again.ID)
+		assert.Equal(t, room.Code, again.Code)
+
+		server.FastForward(48 * time.Hour)
+		_, err = uc.GetRoom(ctx, room.Code)
+		assert.NoError(t, err)
+	})
+
+	t.Run("vanity code", func(t *testing.T) {
+		old := room.Code
+
+		updated, err := uc.SetPersonalRoomCode(ctx, "user456", "ana-room")
+		require.NoError(t, err)
+		assert.Equal(t, "ana-room", updated.Code)
+
+		found, err := uc.GetRoom(ctx, "ana-room")
+		require.NoError(t, err)
+		assert.Equal(t, room.ID, found.ID)
+		_, err = uc.GetRoom(ctx, old)
+		assert.Error(t, err, "the old code is given up")
+
+		_, err = uc.GetPersonalRoom(ctx, "user789", "Bob")
+		require.NoError(t, err)
+		_, err = uc.SetPersonalRoomCode(ctx, "user789", "ana-room")
+		assert.ErrorIs(t, err, usecase.ErrCodeTaken)
+	})
+
+	t.Run("session resets, configuration stays", func(t *testing.T) {
+		current, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		settings := current.Settings
+		settings.MaxDuration = 30
+		_, err = uc.UpdateRoom(ctx, usecase.UpdateRoomInput{
+			RoomID:   room.ID,
+			UserID:   "user456",
+			Version:  current.Version,
+			Settings: &settings,
+		})
+		require.NoError(t, err)
+
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: "conn-1", UserID: "user456"})
+		require.NoError(t, err)
+		require.NoError(t, uc.SendChatMessage(ctx, &entity.ChatMessage{RoomID: room.ID, UserID: "user456", Message: "hi"}))
+
+		started, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		assert.False(t, started.StartedAt.IsZero())
+		assert.False(t, started.EndsAt().IsZero(), "the time limit runs from the start of the session")
+
+		require.NoError(t, uc.LeaveRoom(ctx, room.ID, "conn-1"))
+
+		after, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		assert.True(t, after.StartedAt.IsZero())
+		assert.True(t, after.EndsAt().IsZero())
+		assert.Equal(t, 30, after.Settings.MaxDuration)
+		assert.Equal(t, "ana-room", after.Code)
+		assert.Equal(t, usecase.MeetingEndedEmpty, events.ended[room.ID])
+
+		participants, err := uc.GetParticipants(ctx, room.ID)
+		require.NoError(t, err)
+		assert.Empty(t, participants)
+		chat, err := uc.GetChatHistory(ctx, room.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, chat)
+	})
+
+	t.Run("ending the meeting keeps the room", func(t *testing.T) {
+		_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: "conn-2", UserID: "user456"})
+		require.NoError(t, err)
+
+		require.NoError(t, uc.EndMeeting(ctx, room.ID, "user456"))
+
+		_, err = uc.GetRoom(ctx, "ana-room")
+		assert.NoError(t, err)
+		participants, err := uc.GetParticipants(ctx, room.ID)
+		require.NoError(t, err)
+		assert.Empty(t, participants)
+	})
+
+	t.Run("waiting in the lobby doesn't start the session", func(t *testing.T) {
+		waiting, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: "conn-3", UserID: "user900"})
+		require.NoError(t, err)
+		require.Equal(t, entity.ParticipantStatusWaiting, waiting.Status)
+
+		current, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		assert.True(t, current.StartedAt.IsZero())
+
+		_, err = uc.LeaveLobby(ctx, room.ID, "conn-3")
+		require.NoError(t, err)
+	})
+
+	t.Run("the session resets once the lobby empties", func(t *testing.T) {
+		_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: "conn-4", UserID: "user456"})
+		require.NoError(t, err)
+		_, err = uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: room.ID, ParticipantID: "conn-5", UserID: "user900"})
+		require.NoError(t, err)
+		// the host vanished without the room noticing, e.g. their node died
+		require.NoError(t, redisRepo.NewParticipantRepository(client).RemoveParticipant(ctx, room.ID, "conn-4"))
+
+		_, err = uc.LeaveLobby(ctx, room.ID, "conn-5")
+		require.NoError(t, err)
+
+		current, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		assert.True(t, current.StartedAt.IsZero())
+	})
+}
@@ -0,0 +1,100 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRoomSettingsAllows(t *testing.T) {
+	no := false
+	yes := true
+
+	tests := []struct {
+		name       string
+		settings   entity.RoomSettings
+		role       entity.Role
+		capability entity.Capability
+		want       bool
+	}{
+		{"chat follows the room switch", entity.RoomSettings{AllowChat: true}, entity.RoleGuest, entity.CapabilityChat, true},
+		{"chat off for participants", entity.RoomSettings{}, entity.RoleParticipant, entity.CapabilityChat, false},
+		{"host chats when chat is off", entity.RoomSettings{}, entity.RoleHost, entity.CapabilityChat, true},
+		{"guest override mutes chat", entity.RoomSettings{
+			AllowChat: true,
+			Roles:     map[entity.Role]entity.RolePermissions{entity.RoleGuest: {Chat: &no}},
+		}, entity.RoleGuest, entity.CapabilityChat, false},
+		{"override leaves other roles alone", entity.RoomSettings{
+			AllowChat: true,
+			Roles:     map[entity.Role]entity.RolePermissions{entity.RoleGuest: {Chat: &no}},
+		}, entity.RoleParticipant, entity.CapabilityChat, true},
+		{"only hosts record by default", entity.RoomSettings{RecordingEnabled: true}, entity.RoleParticipant, entity.CapabilityRecord, false},
+		{"participants record when allowed", entity.RoomSettings{
+			RecordingEnabled: true,
+			Roles:            map[entity.Role]entity.RolePermissions{entity.RoleParticipant: {Record: &yes}},
+		}, entity.RoleParticipant, entity.CapabilityRecord, true},
+		{"nobody records when recording is disabled", entity.RoomSettings{
+			Roles: map[entity.Role]entity.RolePermissions{entity.RoleHost: {Record: &yes}},
+		}, entity.RoleHost, entity.CapabilityRecord, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.settings.Allows(tt.role, tt.capability))
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	t.Run("refusal names the setting", func(t *testing.T) {
+		guest := &entity.Participant{ID: "conn-guest", UserID: "guest-1", RoomID: "room123", IsGuest: true}
+		room := &entity.Room{ID: "room123", HostID: "host123"}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-guest").Return(guest, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		err := uc.Authorize(context.Background(), "room123", "conn-guest", entity.CapabilityChat)
+
+		var policyErr *usecase.PolicyError
+		assert.True(t, errors.As(err, &policyErr))
+		assert.Equal(t, entity.CapabilityChat, policyErr.Capability)
+		assert.Equal(t, entity.RoleGuest, policyErr.Role)
+		assert.True(t, errors.Is(err, usecase.ErrNotPermitted))
+	})
+
+	t.Run("cannot join once the meeting ran out of time", func(t *testing.T) {
+		room := &entity.Room{
+			ID:              "room123",
+			HostID:          "host123",
+			CreatedAt:       time.Now().Add(-2 * time.Hour),
+			MaxParticipants: 10,
+			Settings:        entity.RoomSettings{MaxDuration: 60},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		participant, err := uc.JoinRoom(context.Background(), usecase.JoinRoomInput{
+			RoomID: "room123",
+			UserID: "user456",
+		})
+
+		assert.Nil(t, participant)
+		assert.True(t, errors.Is(err, usecase.ErrMeetingOver))
+		mockParticipantRepo.AssertNotCalled(t, "JoinRoom", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,115 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"bincang-visual/internal/delivery/websocket/protocol"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestDecodeSignalMessage(t *testing.T) {
+	t.Run("typed payload", func(t *testing.T) {
+		req, err := protocol.Decode([]byte(`{"id":"1","type":"offer","to":"user456","data":{"sdp":"v=0","type":"offer"}}`))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "user456", req.To)
+		assert.Equal(t, "v=0", req.Payload.(*protocol.SessionDescription).SDP)
+	})
+
+	t.Run("unknown type rejected", func(t *testing.T) {
+		_, err := protocol.Decode([]byte(`{"id":"2","type":"self-destruct"}`))
+
+		assert.Equal(t, protocol.CodeUnknownType, err.Code)
+		assert.Equal(t, "2", err.CorrelationID)
+	})
+
+	t.Run("wrong field type rejected", func(t *testing.T) {
+		_, err := protocol.Decode([]byte(`{"id":"3","type":"screen-share","data":{"isSharing":"yes"}}`))
+
+		assert.Equal(t, protocol.CodeInvalidPayload, err.Code)
+		assert.Equal(t, "3", err.CorrelationID)
+	})
+
+	t.Run("missing required field rejected", func(t *testing.T) {
+		_, err := protocol.Decode([]byte(`{"type":"screen-share","data":{}}`))
+
+		assert.Equal(t, protocol.CodeInvalidPayload, err.Code)
+	})
+
+	t.Run("unknown field rejected", func(t *testing.T) {
+		_, err := protocol.Decode([]byte(`{"type":"chat","data":{"message":"hi","html":"<b>hi</b>"}}`))
+
+		assert.Equal(t, protocol.CodeInvalidPayload, err.Code)
+	})
+
+	t.Run("invalid json rejected", func(t *testing.T) {
+		_, err := protocol.Decode([]byte(`{"type":`))
+
+		assert.Equal(t, protocol.CodeInvalidMessage, err.Code)
+	})
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	version, err := protocol.Negotiate("")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	version, err = protocol.Negotiate("3, 1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	_, err = protocol.Negotiate("3")
+	assert.Error(t, err)
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	t.Run("decodes into the same payloads as JSON", func(t *testing.T) {
+		raw, _ := msgpack.Marshal(map[string]interface{}{
+			"id":   "1",
+			"type": "ice",
+			"to":   "user456",
+			"data": map[string]interface{}{
+				"candidate":     "candidate:1 1 udp 2122260223 10.0.0.1 54321 typ host",
+				"sdpMLineIndex": 0,
+			},
+		})
+
+		req, err := protocol.Msgpack.Decode(raw)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "user456", req.To)
+		assert.Equal(t, 0, *req.Payload.(*protocol.ICECandidate).SDPMLineIndex)
+	})
+
+	t.Run("unknown field rejected", func(t *testing.T) {
+		raw, _ := msgpack.Marshal(map[string]interface{}{
+			"type": "chat",
+			"data": map[string]interface{}{"message": "hi", "html": "<b>hi</b>"},
+		})
+
+		_, err := protocol.Msgpack.Decode(raw)
+
+		assert.Equal(t, protocol.CodeInvalidPayload, err.Code)
+	})
+
+	t.Run("re-encodes outbound JSON keeping integers", func(t *testing.T) {
+		frame, err := protocol.Msgpack.FromJSON([]byte(`{"type":"peer-reconnecting","data":{"gracePeriod":20}}`))
+		assert.NoError(t, err)
+
+		var decoded map[string]interface{}
+		assert.NoError(t, msgpack.Unmarshal(frame, &decoded))
+		assert.Equal(t, "peer-reconnecting", decoded["type"])
+		assert.EqualValues(t, 20, decoded["data"].(map[string]interface{})["gracePeriod"])
+	})
+}
+
+func TestMessagePriority(t *testing.T) {
+	for _, msgType := range []string{"offer", "answer", "ice", "media-state", "removed", "error"} {
+		assert.False(t, protocol.IsBulk(msgType), msgType)
+	}
+	for _, msgType := range []string{"chat", "pong"} {
+		assert.True(t, protocol.IsBulk(msgType), msgType)
+	}
+}
@@ -0,0 +1,85 @@
+package usecase_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomCodes(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	roomRepo := redisRepo.NewRoomRepository(client)
+	uc := usecase.NewRoomUseCase(
+		roomRepo,
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+	ctx := context.Background()
+
+	t.Run("every room gets a code", func(t *testing.T) {
+		room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Standup", HostID: "host123"})
+		require.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^[a-z]{3}-[a-z]{4}-[a-z]{3}$`), room.Code)
+
+		for _, typed := range []string{
+			room.Code,
+			strings.ToUpper(room.Code),
+			strings.ReplaceAll(room.Code, "-", ""),
+			strings.ReplaceAll(room.Code, "-", " "),
+		} {
+			found, err := uc.GetRoom(ctx, typed)
+			if assert.NoError(t, err, typed) {
+				assert.Equal(t, room.ID, found.ID)
+			}
+		}
+
+		found, err := uc.GetRoom(ctx, room.ID)
+		require.NoError(t, err)
+		assert.Equal(t, room.Code, found.Code)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		_, err := uc.GetRoom(ctx, "zzz-zzzz-zzz")
+		assert.ErrorIs(t, err, repository.ErrRoomNotFound)
+	})
+
+	t.Run("vanity codes are unique", func(t *testing.T) {
+		room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Ana", HostID: "host123", Code: "Ana-Desk"})
+		require.NoError(t, err)
+		assert.Equal(t, "ana-desk", room.Code)
+
+		_, err = uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Other", HostID: "host456", Code: "ana-desk"})
+		assert.ErrorIs(t, err, usecase.ErrCodeTaken)
+
+		// the code is free again once the room is gone
+		require.NoError(t, roomRepo.Delete(ctx, room.ID))
+		_, err = uc.GetRoom(ctx, "ana-desk")
+		assert.ErrorIs(t, err, repository.ErrRoomNotFound)
+
+		_, err = uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Other", HostID: "host456", Code: "ana-desk"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid vanity codes", func(t *testing.T) {
+		for _, code := range []string{"ab", "-ana", "ana desk", "ana_desk", strings.Repeat("a", 33)} {
+			_, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Ana", HostID: "host123", Code: code})
+			assert.ErrorIs(t, err, usecase.ErrInvalidCode, code)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRooms(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewRoomUseCase(
+		redisRepo.NewRoomRepository(client),
+		redisRepo.NewParticipantRepository(client),
+		redisRepo.NewChatRepository(client),
+		redisRepo.NewRecordingRepository(client),
+		config.Config{},
+	)
+	ctx := context.Background()
+
+	// created in quick succession, so some share a millisecond
+	var created []string
+	for i := 0; i < 7; i++ {
+		room, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: fmt.Sprintf("Sync %d", i), HostID: "host123"})
+		require.NoError(t, err)
+		created = append([]string{room.ID}, created...)
+	}
+	_, err := uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Sync of someone else", HostID: "host456"})
+	require.NoError(t, err)
+	_, err = uc.CreateRoom(ctx, usecase.CreateRoomInput{Name: "Anonymous", HostID: usecase.AnonymousHostID})
+	require.NoError(t, err)
+
+	t.Run("pages through the host's rooms", func(t *testing.T) {
+		var listed []string
+		var cursor string
+		for pages := 0; pages < 10; pages++ {
+			page, err := uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", Cursor: cursor, Limit: 3})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Rooms), 3)
+			for _, room := range page.Rooms {
+				assert.Equal(t, "host123", room.HostID)
+				listed = append(listed, room.ID)
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		assert.ElementsMatch(t, created, listed)
+		assert.Len(t, listed, len(created), "no room twice")
+		assert.False(t, server.Exists("rooms:host:"+usecase.AnonymousHostID), "anonymous rooms belong to nobody")
+	})
+
+	t.Run("search", func(t *testing.T) {
+		page, err := uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", Query: "sync 3"})
+		require.NoError(t, err)
+		require.Len(t, page.Rooms, 1)
+		assert.Equal(t, "Sync 3", page.Rooms[0].Name)
+
+		page, err = uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", Query: page.Rooms[0].Code})
+		require.NoError(t, err)
+		require.Len(t, page.Rooms, 1)
+		assert.Equal(t, "Sync 3", page.Rooms[0].Name)
+	})
+
+	t.Run("active rooms with live counts", func(t *testing.T) {
+		for _, id := range []string{"conn-1", "conn-2"} {
+			_, err := uc.JoinRoom(ctx, usecase.JoinRoomInput{RoomID: created[2], ParticipantID: id, UserID: "user-" + id})
+			require.NoError(t, err)
+		}
+
+		page, err := uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", ActiveOnly: true})
+		require.NoError(t, err)
+		require.Len(t, page.Rooms, 1)
+		assert.Equal(t, created[2], page.Rooms[0].ID)
+		assert.True(t, page.Rooms[0].Active)
+		assert.Equal(t, 2, page.Rooms[0].ParticipantCount)
+
+		require.NoError(t, uc.LeaveRoom(ctx, created[2], "conn-1"))
+		page, err = uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", ActiveOnly: true})
+		require.NoError(t, err)
+		require.Len(t, page.Rooms, 1)
+		assert.Equal(t, 1, page.Rooms[0].ParticipantCount)
+
+		require.NoError(t, uc.LeaveRoom(ctx, created[2], "conn-2"))
+		page, err = uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", ActiveOnly: true})
+		require.NoError(t, err)
+		assert.Empty(t, page.Rooms)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123", Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, usecase.ErrInvalidCursor)
+	})
+
+	t.Run("expired rooms drop out", func(t *testing.T) {
+		server.FastForward(25 * time.Hour)
+
+		page, err := uc.ListRooms(ctx, usecase.ListRoomsInput{UserID: "host123"})
+		require.NoError(t, err)
+		assert.Empty(t, page.Rooms)
+		assert.False(t, server.Exists("rooms:host:host123"), "the index is pruned")
+	})
+}
@@ -0,0 +1,120 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type recordedEvents struct {
+	updated []*entity.Room
+	ended   map[string]string
+}
+
+func (e *recordedEvents) RoomUpdated(room *entity.Room) {
+	e.updated = append(e.updated, room)
+}
+
+func (e *recordedEvents) MeetingEnded(roomID, reason string) {
+	if e.ended == nil {
+		e.ended = make(map[string]string)
+	}
+	e.ended[roomID] = reason
+}
+
+func TestUpdateRoom(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+	events := &recordedEvents{}
+	uc.SetRoomEvents(events)
+
+	newRoom := func() *entity.Room {
+		return &entity.Room{ID: "room123", Name: "Standup", HostID: "host123", MaxParticipants: 10, Version: 3}
+	}
+
+	t.Run("host updates and everyone hears about it", func(t *testing.T) {
+		name := "Planning"
+		max := 5
+		settings := entity.RoomSettings{AllowChat: true}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(newRoom(), nil).Once()
+		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(4, nil).Once()
+		mockRoomRepo.On("Update", mock.Anything, mock.MatchedBy(func(r *entity.Room) bool {
+			return r.Name == "Planning" && r.MaxParticipants == 5 && r.Settings.AllowChat && r.Metadata["topic"] == "q3"
+		})).Return(nil).Once()
+
+		room, err := uc.UpdateRoom(context.Background(), usecase.UpdateRoomInput{
+			RoomID:          "room123",
+			UserID:          "host123",
+			Version:         3,
+			Name:            &name,
+			MaxParticipants: &max,
+			Settings:        &settings,
+			Metadata:        map[string]interface{}{"topic": "q3"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Planning", room.Name)
+		assert.Len(t, events.updated, 1)
+		mockRoomRepo.AssertExpectations(t)
+	})
+
+	t.Run("stale version is refused", func(t *testing.T) {
+		name := "Planning"
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(newRoom(), nil).Once()
+
+		_, err := uc.UpdateRoom(context.Background(), usecase.UpdateRoomInput{
+			RoomID:  "room123",
+			UserID:  "host123",
+			Version: 2,
+			Name:    &name,
+		})
+
+		assert.True(t, errors.Is(err, repository.ErrRoomChanged))
+	})
+
+	t.Run("only the host may update", func(t *testing.T) {
+		name := "Planning"
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(newRoom(), nil).Once()
+
+		_, err := uc.UpdateRoom(context.Background(), usecase.UpdateRoomInput{
+			RoomID:  "room123",
+			UserID:  "user456",
+			Version: 3,
+			Name:    &name,
+		})
+
+		assert.True(t, errors.Is(err, usecase.ErrNotHost))
+	})
+
+	t.Run("cannot shrink below the participants in the room", func(t *testing.T) {
+		max := 2
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(newRoom(), nil).Once()
+		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(4, nil).Once()
+
+		_, err := uc.UpdateRoom(context.Background(), usecase.UpdateRoomInput{
+			RoomID:          "room123",
+			UserID:          "host123",
+			Version:         3,
+			MaxParticipants: &max,
+		})
+
+		assert.True(t, errors.Is(err, usecase.ErrBelowParticipantCount))
+		assert.Len(t, events.updated, 1)
+	})
+}
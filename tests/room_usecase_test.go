@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"bincang-visual/internal/config"
 	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
 	"bincang-visual/internal/domain/usecase"
 
 	"github.com/stretchr/testify/assert"
@@ -51,6 +53,167 @@ func (m *MockRoomRepository) ExtendTTL(ctx context.Context, roomID string, durat
 	return args.Error(0)
 }
 
+func (m *MockRoomRepository) AddPresenter(ctx context.Context, roomID, participantID string, max int) (bool, error) {
+	args := m.Called(ctx, roomID, participantID, max)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) RemovePresenter(ctx context.Context, roomID, participantID string) (bool, error) {
+	args := m.Called(ctx, roomID, participantID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) GetPresenters(ctx context.Context, roomID string) ([]string, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoomRepository) AddShareRequest(ctx context.Context, roomID, participantID string) error {
+	args := m.Called(ctx, roomID, participantID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) RemoveShareRequest(ctx context.Context, roomID, participantID string) (bool, error) {
+	args := m.Called(ctx, roomID, participantID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) GetShareRequests(ctx context.Context, roomID string) ([]string, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoomRepository) ClaimNotice(ctx context.Context, roomID, notice string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, roomID, notice, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) AddBan(ctx context.Context, roomID, userID string) error {
+	args := m.Called(ctx, roomID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) IsBanned(ctx context.Context, roomID, userID string) (bool, error) {
+	args := m.Called(ctx, roomID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) NextVersion(ctx context.Context, roomID string) (int64, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoomRepository) GetVersion(ctx context.Context, roomID string) (int64, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoomRepository) SetPasscode(ctx context.Context, roomID, hash string) error {
+	args := m.Called(ctx, roomID, hash)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) GetPasscode(ctx context.Context, roomID string) (string, error) {
+	args := m.Called(ctx, roomID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) ReservePasscodeAttempt(ctx context.Context, roomID, ip string, maxPerIP, maxPerRoom int, window time.Duration) (bool, error) {
+	args := m.Called(ctx, roomID, ip, maxPerIP, maxPerRoom, window)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) ReleasePasscodeAttempt(ctx context.Context, roomID, ip string) error {
+	args := m.Called(ctx, roomID, ip)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) CreateInvite(ctx context.Context, invite *entity.Invite) error {
+	args := m.Called(ctx, invite)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) GetInvite(ctx context.Context, roomID, inviteID string) (*entity.Invite, error) {
+	args := m.Called(ctx, roomID, inviteID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Invite), args.Error(1)
+}
+
+func (m *MockRoomRepository) ListInvites(ctx context.Context, roomID string) ([]*entity.Invite, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.Invite), args.Error(1)
+}
+
+func (m *MockRoomRepository) RevokeInvite(ctx context.Context, roomID, inviteID string) error {
+	args := m.Called(ctx, roomID, inviteID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) RedeemInvite(ctx context.Context, invite *entity.Invite, userID string) error {
+	args := m.Called(ctx, invite, userID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ResolveCode(ctx context.Context, code string) (string, error) {
+	args := m.Called(ctx, code)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRoomRepository) ClaimCode(ctx context.Context, code, roomID string) error {
+	args := m.Called(ctx, code, roomID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ReleaseCode(ctx context.Context, code, roomID string) error {
+	args := m.Called(ctx, code, roomID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ClearSession(ctx context.Context, roomID string) error {
+	args := m.Called(ctx, roomID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) IdleRooms(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoomRepository) TouchRoom(ctx context.Context, roomID string) error {
+	args := m.Called(ctx, roomID)
+	return args.Error(0)
+}
+
+func (m *MockRoomRepository) ListHostRooms(ctx context.Context, hostID string, after *repository.RoomCursor, limit int) ([]*entity.Room, error) {
+	args := m.Called(ctx, hostID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.Room), args.Error(1)
+}
+
+func (m *MockRoomRepository) ActiveRooms(ctx context.Context, roomIDs []string) ([]bool, error) {
+	args := m.Called(ctx, roomIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]bool), args.Error(1)
+}
+
 type MockParticipantRepository struct {
 	mock.Mock
 }
@@ -60,8 +223,8 @@ func (m *MockParticipantRepository) AddParticipant(ctx context.Context, particip
 	return args.Error(0)
 }
 
-func (m *MockParticipantRepository) RemoveParticipant(ctx context.Context, roomID, userId string) error {
-	args := m.Called(ctx, roomID, userId)
+func (m *MockParticipantRepository) RemoveParticipant(ctx context.Context, roomID, participantID string) error {
+	args := m.Called(ctx, roomID, participantID)
 	return args.Error(0)
 }
 
@@ -73,8 +236,8 @@ func (m *MockParticipantRepository) GetParticipants(ctx context.Context, roomID
 	return args.Get(0).([]*entity.Participant), args.Error(1)
 }
 
-func (m *MockParticipantRepository) GetParticipant(ctx context.Context, roomID, userId string) (*entity.Participant, error) {
-	args := m.Called(ctx, roomID, userId)
+func (m *MockParticipantRepository) GetParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
+	args := m.Called(ctx, roomID, participantID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -91,6 +254,45 @@ func (m *MockParticipantRepository) GetParticipantCount(ctx context.Context, roo
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockParticipantRepository) CountParticipants(ctx context.Context, roomIDs []string) ([]int, error) {
+	args := m.Called(ctx, roomIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockParticipantRepository) JoinRoom(ctx context.Context, participant *entity.Participant, max int) error {
+	args := m.Called(ctx, participant, max)
+	return args.Error(0)
+}
+
+func (m *MockParticipantRepository) AdmitParticipant(ctx context.Context, participant *entity.Participant, max int) error {
+	args := m.Called(ctx, participant, max)
+	return args.Error(0)
+}
+
+func (m *MockParticipantRepository) RemoveFromLobby(ctx context.Context, roomID, participantID string) (bool, error) {
+	args := m.Called(ctx, roomID, participantID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockParticipantRepository) GetLobby(ctx context.Context, roomID string) ([]*entity.Participant, error) {
+	args := m.Called(ctx, roomID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.Participant), args.Error(1)
+}
+
+func (m *MockParticipantRepository) GetLobbyParticipant(ctx context.Context, roomID, participantID string) (*entity.Participant, error) {
+	args := m.Called(ctx, roomID, participantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Participant), args.Error(1)
+}
+
 type MockChatRepository struct {
 	mock.Mock
 }
@@ -155,7 +357,7 @@ func TestCreateRoom(t *testing.T) {
 	mockChatRepo := new(MockChatRepository)
 	mockRecordingRepo := new(MockRecordingRepository)
 
-	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo)
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
 
 	t.Run("successful room creation", func(t *testing.T) {
 		mockRoomRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Room"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
@@ -194,6 +396,21 @@ func TestCreateRoom(t *testing.T) {
 		assert.Equal(t, 100, room.MaxParticipants)
 		mockRoomRepo.AssertExpectations(t)
 	})
+
+	t.Run("invalid settings are refused", func(t *testing.T) {
+		for name, input := range map[string]usecase.CreateRoomInput{
+			"negative max participants": {MaxParticipants: -1},
+			"negative max duration":     {Settings: entity.RoomSettings{MaxDuration: -5}},
+			"negative max presenters":   {Settings: entity.RoomSettings{ScreenShare: entity.ScreenSharePolicy{MaxPresenters: -1}}},
+			"unknown role":              {Settings: entity.RoomSettings{Roles: map[entity.Role]entity.RolePermissions{"owner": {}}}},
+		} {
+			input.Name, input.HostID = "Test Room", "user123"
+
+			_, err := uc.CreateRoom(context.Background(), input)
+
+			assert.ErrorIs(t, err, usecase.ErrInvalidRoom, name)
+		}
+	})
 }
 
 func TestJoinRoom(t *testing.T) {
@@ -202,7 +419,7 @@ func TestJoinRoom(t *testing.T) {
 	mockChatRepo := new(MockChatRepository)
 	mockRecordingRepo := new(MockRecordingRepository)
 
-	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo)
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
 
 	t.Run("successful join", func(t *testing.T) {
 		room := &entity.Room{
@@ -212,8 +429,7 @@ func TestJoinRoom(t *testing.T) {
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
-		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(5, nil).Once()
-		mockParticipantRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("*entity.Participant")).Return(nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.AnythingOfType("*entity.Participant"), 100).Return(nil).Once()
 
 		input := usecase.JoinRoomInput{
 			RoomID:      "room123",
@@ -240,8 +456,7 @@ func TestJoinRoom(t *testing.T) {
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
-		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(0, nil).Once()
-		mockParticipantRepo.On("AddParticipant", mock.Anything, mock.AnythingOfType("*entity.Participant")).Return(nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.AnythingOfType("*entity.Participant"), 100).Return(nil).Once()
 
 		input := usecase.JoinRoomInput{
 			RoomID:      "room123",
@@ -262,7 +477,7 @@ func TestJoinRoom(t *testing.T) {
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
-		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(10, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.AnythingOfType("*entity.Participant"), 10).Return(repository.ErrRoomFull).Once()
 
 		input := usecase.JoinRoomInput{
 			RoomID:      "room123",
@@ -277,6 +492,53 @@ func TestJoinRoom(t *testing.T) {
 		assert.Contains(t, err.Error(), "room is full")
 	})
 
+	t.Run("guest rejected when room requires sign in", func(t *testing.T) {
+		room := &entity.Room{
+			ID:              "room123",
+			HostID:          "host123",
+			MaxParticipants: 100,
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		input := usecase.JoinRoomInput{
+			RoomID:      "room123",
+			UserID:      "guest-1",
+			DisplayName: "Guest",
+			IsGuest:     true,
+		}
+
+		participant, err := uc.JoinRoom(context.Background(), input)
+
+		assert.ErrorIs(t, err, usecase.ErrGuestsNotAllowed)
+		assert.Nil(t, participant)
+	})
+
+	t.Run("guest never becomes host", func(t *testing.T) {
+		room := &entity.Room{
+			ID:              "room123",
+			HostID:          "host123",
+			MaxParticipants: 100,
+			Settings:        entity.RoomSettings{AllowGuests: true},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("JoinRoom", mock.Anything, mock.AnythingOfType("*entity.Participant"), 100).Return(nil).Once()
+
+		input := usecase.JoinRoomInput{
+			RoomID:      "room123",
+			UserID:      "host123",
+			DisplayName: "Guest",
+			IsGuest:     true,
+		}
+
+		participant, err := uc.JoinRoom(context.Background(), input)
+
+		assert.NoError(t, err)
+		assert.True(t, participant.IsGuest)
+		assert.False(t, participant.IsHost)
+	})
+
 	t.Run("room not found", func(t *testing.T) {
 		mockRoomRepo.On("Get", mock.Anything, "nonexistent").Return(nil, errors.New("room not found")).Once()
 
@@ -293,19 +555,62 @@ func TestJoinRoom(t *testing.T) {
 	})
 }
 
+func TestStopRecording(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	allowed := true
+	room := &entity.Room{
+		ID:          "room123",
+		HostID:      "host123",
+		IsRecording: true,
+		RecordingID: "rec-own",
+		Settings: entity.RoomSettings{
+			RecordingEnabled: true,
+			Roles:            map[entity.Role]entity.RolePermissions{entity.RoleParticipant: {Record: &allowed}},
+		},
+	}
+
+	t.Run("another room's recording is left alone", func(t *testing.T) {
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").
+			Return([]*entity.Participant{{ID: "conn-1", UserID: "user456"}}, nil).Once()
+
+		err := uc.StopRecording(context.Background(), "room123", "rec-elsewhere", "user456")
+
+		assert.ErrorIs(t, err, usecase.ErrNotRoomRecording)
+		mockRecordingRepo.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+		mockRoomRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("nothing to stop", func(t *testing.T) {
+		idle := &entity.Room{ID: "room123", HostID: "host123"}
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(idle, nil).Once()
+
+		err := uc.StopRecording(context.Background(), "room123", "", "host123")
+
+		assert.ErrorIs(t, err, usecase.ErrNotRoomRecording)
+	})
+}
+
 func TestStartRecording(t *testing.T) {
 	mockRoomRepo := new(MockRoomRepository)
 	mockParticipantRepo := new(MockParticipantRepository)
 	mockChatRepo := new(MockChatRepository)
 	mockRecordingRepo := new(MockRecordingRepository)
 
-	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo)
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
 
 	t.Run("successful recording start", func(t *testing.T) {
 		room := &entity.Room{
 			ID:          "room123",
 			HostID:      "host123",
 			IsRecording: false,
+			Settings:    entity.RoomSettings{RecordingEnabled: true},
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
@@ -327,15 +632,54 @@ func TestStartRecording(t *testing.T) {
 			ID:          "room123",
 			HostID:      "host123",
 			IsRecording: false,
+			Settings:    entity.RoomSettings{RecordingEnabled: true},
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").
+			Return([]*entity.Participant{{ID: "conn-1", UserID: "user456"}}, nil).Once()
 
 		recording, err := uc.StartRecording(context.Background(), "room123", "user456")
 
 		assert.Error(t, err)
 		assert.Nil(t, recording)
-		assert.Contains(t, err.Error(), "only host can start recording")
+		assert.True(t, errors.Is(err, usecase.ErrNotPermitted))
+	})
+
+	t.Run("outsider cannot start recording", func(t *testing.T) {
+		allowed := true
+		room := &entity.Room{
+			ID:     "room123",
+			HostID: "host123",
+			Settings: entity.RoomSettings{
+				RecordingEnabled: true,
+				Roles:            map[entity.Role]entity.RolePermissions{entity.RoleParticipant: {Record: &allowed}},
+			},
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").
+			Return([]*entity.Participant{{ID: "conn-1", UserID: "user456"}}, nil).Once()
+
+		recording, err := uc.StartRecording(context.Background(), "room123", "user789")
+
+		assert.Nil(t, recording)
+		assert.ErrorIs(t, err, usecase.ErrNotMember)
+	})
+
+	t.Run("recording disabled for the room", func(t *testing.T) {
+		room := &entity.Room{
+			ID:     "room123",
+			HostID: "host123",
+		}
+
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		recording, err := uc.StartRecording(context.Background(), "room123", "host123")
+
+		assert.Nil(t, recording)
+		assert.True(t, errors.Is(err, usecase.ErrNotPermitted))
+		mockRecordingRepo.AssertNumberOfCalls(t, "Create", 1)
 	})
 
 	t.Run("recording already in progress", func(t *testing.T) {
@@ -343,6 +687,7 @@ func TestStartRecording(t *testing.T) {
 			ID:          "room123",
 			HostID:      "host123",
 			IsRecording: true,
+			Settings:    entity.RoomSettings{RecordingEnabled: true},
 		}
 
 		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
@@ -361,7 +706,7 @@ func TestLeaveRoom(t *testing.T) {
 	mockChatRepo := new(MockChatRepository)
 	mockRecordingRepo := new(MockRecordingRepository)
 
-	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo)
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
 
 	t.Run("leave room with remaining participants", func(t *testing.T) {
 		mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room123", "participant123").Return(nil).Once()
@@ -376,6 +721,7 @@ func TestLeaveRoom(t *testing.T) {
 	t.Run("last participant leaves - room deleted", func(t *testing.T) {
 		mockParticipantRepo.On("RemoveParticipant", mock.Anything, "room123", "participant123").Return(nil).Once()
 		mockParticipantRepo.On("GetParticipantCount", mock.Anything, "room123").Return(0, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(&entity.Room{ID: "room123"}, nil).Once()
 		mockRoomRepo.On("Delete", mock.Anything, "room123").Return(nil).Once()
 		mockChatRepo.On("DeleteMessages", mock.Anything, "room123").Return(nil).Maybe()
 
@@ -386,3 +732,33 @@ func TestLeaveRoom(t *testing.T) {
 		mockRoomRepo.AssertExpectations(t)
 	})
 }
+
+func TestGetRoomState(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	room := &entity.Room{ID: "room123", HostID: "host123", IsRecording: true}
+	participants := []*entity.Participant{{ID: "conn-1", UserID: "host123", RoomID: "room123", IsHost: true}}
+	chat := []*entity.ChatMessage{{ID: "msg-1", RoomID: "room123", Message: "hello"}}
+
+	mockRoomRepo.On("GetVersion", mock.Anything, "room123").Return(int64(7), nil).Once()
+	mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+	mockParticipantRepo.On("GetParticipants", mock.Anything, "room123").Return(participants, nil).Once()
+	mockRoomRepo.On("GetPresenters", mock.Anything, "room123").Return([]string{"conn-1"}, nil).Once()
+	mockRoomRepo.On("GetShareRequests", mock.Anything, "room123").Return([]string{}, nil).Once()
+	mockChatRepo.On("GetMessages", mock.Anything, "room123", mock.AnythingOfType("int")).Return(chat, nil).Once()
+
+	state, err := uc.GetRoomState(context.Background(), "room123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), state.Version)
+	assert.Equal(t, room, state.Room)
+	assert.Equal(t, participants, state.Participants)
+	assert.Equal(t, []string{"conn-1"}, state.Presenters)
+	assert.True(t, state.IsRecording)
+	assert.Equal(t, chat, state.Chat)
+}
@@ -0,0 +1,122 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bincang-visual/internal/config"
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScreenShare(t *testing.T) {
+	mockRoomRepo := new(MockRoomRepository)
+	mockParticipantRepo := new(MockParticipantRepository)
+	mockChatRepo := new(MockChatRepository)
+	mockRecordingRepo := new(MockRecordingRepository)
+
+	uc := usecase.NewRoomUseCase(mockRoomRepo, mockParticipantRepo, mockChatRepo, mockRecordingRepo, config.Config{})
+
+	host := &entity.Participant{ID: "conn-host", UserID: "host123", RoomID: "room123", IsHost: true}
+	guest := &entity.Participant{ID: "conn-guest", UserID: "user456", RoomID: "room123"}
+
+	t.Run("disabled for participants", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", Settings: entity.RoomSettings{AllowScreenShare: false}}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-guest").Return(guest, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+
+		_, err := uc.StartScreenShare(context.Background(), "room123", "conn-guest")
+
+		assert.True(t, errors.Is(err, usecase.ErrNotPermitted))
+		mockRoomRepo.AssertNotCalled(t, "AddPresenter", mock.Anything, "room123", "conn-guest", mock.Anything)
+	})
+
+	t.Run("host presents even when disabled", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", Settings: entity.RoomSettings{AllowScreenShare: false}}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-host").Return(host, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("AddPresenter", mock.Anything, "room123", "conn-host", 1).Return(true, nil).Once()
+
+		pending, err := uc.StartScreenShare(context.Background(), "room123", "conn-host")
+
+		assert.NoError(t, err)
+		assert.False(t, pending)
+	})
+
+	t.Run("approval queues the request", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", Settings: entity.RoomSettings{
+			AllowScreenShare: true,
+			ScreenShare:      entity.ScreenSharePolicy{RequireApproval: true},
+		}}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-guest").Return(guest, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("GetPresenters", mock.Anything, "room123").Return([]string{}, nil).Once()
+		mockRoomRepo.On("AddShareRequest", mock.Anything, "room123", "conn-guest").Return(nil).Once()
+
+		pending, err := uc.StartScreenShare(context.Background(), "room123", "conn-guest")
+
+		assert.NoError(t, err)
+		assert.True(t, pending)
+		mockRoomRepo.AssertNotCalled(t, "AddPresenter", mock.Anything, "room123", "conn-guest", mock.Anything)
+	})
+
+	t.Run("busy when every presenter slot is taken", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", Settings: entity.RoomSettings{
+			AllowScreenShare: true,
+			ScreenShare:      entity.ScreenSharePolicy{MaxPresenters: 2},
+		}}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-guest").Return(guest, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("AddPresenter", mock.Anything, "room123", "conn-guest", 2).Return(false, nil).Once()
+
+		_, err := uc.StartScreenShare(context.Background(), "room123", "conn-guest")
+
+		assert.True(t, errors.Is(err, usecase.ErrScreenShareBusy))
+	})
+
+	t.Run("host grants a pending request", func(t *testing.T) {
+		room := &entity.Room{ID: "room123", Settings: entity.RoomSettings{
+			AllowScreenShare: true,
+			ScreenShare:      entity.ScreenSharePolicy{RequireApproval: true},
+		}}
+
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-host").Return(host, nil).Once()
+		mockRoomRepo.On("GetShareRequests", mock.Anything, "room123").Return([]string{"conn-guest"}, nil).Once()
+		mockRoomRepo.On("Get", mock.Anything, "room123").Return(room, nil).Once()
+		mockRoomRepo.On("AddPresenter", mock.Anything, "room123", "conn-guest", 1).Return(true, nil).Once()
+		mockRoomRepo.On("RemoveShareRequest", mock.Anything, "room123", "conn-guest").Return(true, nil).Once()
+
+		err := uc.GrantScreenShare(context.Background(), "room123", "conn-host", "conn-guest")
+
+		assert.NoError(t, err)
+		mockRoomRepo.AssertExpectations(t)
+	})
+
+	t.Run("participant cannot grant", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-guest").Return(guest, nil).Once()
+
+		err := uc.GrantScreenShare(context.Background(), "room123", "conn-guest", "conn-host")
+
+		assert.True(t, errors.Is(err, usecase.ErrNotHost))
+	})
+
+	t.Run("host takes over", func(t *testing.T) {
+		mockParticipantRepo.On("GetParticipant", mock.Anything, "room123", "conn-host").Return(host, nil).Once()
+		mockRoomRepo.On("GetPresenters", mock.Anything, "room123").Return([]string{"conn-guest"}, nil).Once()
+		mockRoomRepo.On("RemovePresenter", mock.Anything, "room123", "conn-guest").Return(true, nil).Once()
+		mockRoomRepo.On("AddPresenter", mock.Anything, "room123", "conn-host", 1).Return(true, nil).Once()
+
+		stopped, err := uc.TakeOverScreenShare(context.Background(), "room123", "conn-host")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"conn-guest"}, stopped)
+	})
+}
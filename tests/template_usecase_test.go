@@ -0,0 +1,104 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"bincang-visual/internal/domain/entity"
+	"bincang-visual/internal/domain/repository"
+	"bincang-visual/internal/domain/usecase"
+	redisRepo "bincang-visual/internal/repository/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomTemplates(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	uc := usecase.NewTemplateUseCase(redisRepo.NewTemplateRepository(client))
+	ctx := context.Background()
+
+	t.Run("built-in defaults", func(t *testing.T) {
+		defaults, err := uc.RoomDefaults(ctx, "user123", "")
+		require.NoError(t, err)
+		assert.Equal(t, 100, defaults.MaxParticipants)
+		assert.Equal(t, entity.DefaultRoomSettings(), defaults.Settings)
+
+		templates, err := uc.ListTemplates(ctx, "user123")
+		require.NoError(t, err)
+		var ids []string
+		for _, template := range templates {
+			ids = append(ids, template.ID)
+		}
+		assert.Equal(t, []string{"standup", "webinar", "interview"}, ids)
+
+		interview, err := uc.RoomDefaults(ctx, usecase.AnonymousHostID, "interview")
+		require.NoError(t, err)
+		assert.True(t, interview.Settings.WaitingRoom)
+	})
+
+	t.Run("user defaults", func(t *testing.T) {
+		settings := entity.DefaultRoomSettings()
+		settings.RecordingEnabled = true
+		require.NoError(t, uc.SetRoomDefaults(ctx, "user123", entity.RoomDefaults{MaxParticipants: 20, Settings: settings}))
+
+		defaults, err := uc.RoomDefaults(ctx, "user123", "")
+		require.NoError(t, err)
+		assert.Equal(t, 20, defaults.MaxParticipants)
+		assert.True(t, defaults.Settings.RecordingEnabled)
+
+		others, err := uc.RoomDefaults(ctx, "user456", "")
+		require.NoError(t, err)
+		assert.False(t, others.Settings.RecordingEnabled)
+
+		err = uc.SetRoomDefaults(ctx, "user123", entity.RoomDefaults{MaxParticipants: -1})
+		assert.ErrorIs(t, err, usecase.ErrInvalidTemplate)
+	})
+
+	t.Run("own templates", func(t *testing.T) {
+		template, err := uc.CreateTemplate(ctx, usecase.CreateTemplateInput{
+			UserID: "user123",
+			Name:   " Retro ",
+			RoomDefaults: entity.RoomDefaults{
+				MaxParticipants: 12,
+				Settings:        entity.RoomSettings{AllowChat: true, MaxDuration: 90},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Retro", template.Name)
+
+		defaults, err := uc.RoomDefaults(ctx, "user123", template.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 12, defaults.MaxParticipants)
+		assert.Equal(t, 90, defaults.Settings.MaxDuration)
+
+		_, err = uc.RoomDefaults(ctx, "user456", template.ID)
+		assert.ErrorIs(t, err, repository.ErrTemplateNotFound, "templates are private")
+
+		templates, err := uc.ListTemplates(ctx, "user123")
+		require.NoError(t, err)
+		assert.Equal(t, template.ID, templates[len(templates)-1].ID)
+
+		require.NoError(t, uc.DeleteTemplate(ctx, "user123", template.ID))
+		_, err = uc.RoomDefaults(ctx, "user123", template.ID)
+		assert.ErrorIs(t, err, repository.ErrTemplateNotFound)
+		assert.ErrorIs(t, uc.DeleteTemplate(ctx, "user123", "standup"), usecase.ErrBuiltInTemplate)
+	})
+
+	t.Run("invalid templates", func(t *testing.T) {
+		for name, input := range map[string]usecase.CreateTemplateInput{
+			"no name":      {Name: " "},
+			"unknown role": {Name: "x", RoomDefaults: entity.RoomDefaults{Settings: entity.RoomSettings{Roles: map[entity.Role]entity.RolePermissions{"owner": {}}}}},
+			"negative max": {Name: "x", RoomDefaults: entity.RoomDefaults{MaxParticipants: -5}},
+		} {
+			input.UserID = "user123"
+			_, err := uc.CreateTemplate(ctx, input)
+			assert.ErrorIs(t, err, usecase.ErrInvalidTemplate, name)
+		}
+	})
+}
@@ -2,20 +2,32 @@ package utils
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
-	"math/rand"
-	"time"
+	"math/big"
 
 	"github.com/redis/go-redis/v9"
 )
 
 func GenerateRandomString(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	return randomString(length, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+}
+
+// GenerateMeetingCode returns a random abc-defg-hij style code, short enough
+// to read out over the phone.
+func GenerateMeetingCode() string {
+	code := randomString(10, "abcdefghijklmnopqrstuvwxyz")
+	return fmt.Sprintf("%s-%s-%s", code[:3], code[3:7], code[7:])
+}
+
+func randomString(length int, charset string) string {
+	max := big.NewInt(int64(len(charset)))
 
 	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[rand.Intn(len(charset))]
+		// the system's random source doesn't fail on any platform we run on
+		n, _ := rand.Int(rand.Reader, max)
+		result[i] = charset[n.Int64()]
 	}
 	return string(result)
 }